@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/shirolimit/wallet-service/pkg/fx"
+)
+
+// fx_stub serves exchange rates from a file over HTTP, it stands in for
+// a real rates service in local development
+var (
+	fs        = flag.NewFlagSet("fx_stub", flag.ExitOnError)
+	httpAddr  = fs.String("http-address", ":8081", "HTTP address to listen")
+	ratesFile = fs.String("rates-file", "rates.json", "JSON file with exchange rates")
+)
+
+func main() {
+	logger := log.NewLogfmtLogger(os.Stderr)
+	logger = log.With(logger, "caller", log.DefaultCaller)
+	logger = log.With(logger, "timestamp", log.DefaultTimestampUTC)
+
+	fs.Parse(os.Args[1:])
+
+	provider, err := fx.NewFileProvider(*ratesFile)
+	if err != nil {
+		logger.Log("rates", *ratesFile, "error", err)
+		os.Exit(1)
+	}
+
+	logger.Log("transport", "HTTP", "address", *httpAddr)
+	if err = http.ListenAndServe(*httpAddr, fx.NewStubHandler(provider)); err != nil {
+		logger.Log("transport", "HTTP", "error", err)
+		os.Exit(1)
+	}
+}
@@ -2,42 +2,189 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"flag"
+	"fmt"
+	"math"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/shirolimit/wallet-service/pkg/auth"
 	"github.com/shirolimit/wallet-service/pkg/db"
+	"github.com/shirolimit/wallet-service/pkg/entities"
+	"github.com/shirolimit/wallet-service/pkg/fx"
+	"github.com/shirolimit/wallet-service/pkg/health"
+	"github.com/shirolimit/wallet-service/pkg/pb"
 	"github.com/shirolimit/wallet-service/pkg/service"
 
+	kitendpoint "github.com/go-kit/kit/endpoint"
 	log "github.com/go-kit/kit/log"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	httptransport "github.com/go-kit/kit/transport/http"
 	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/shirolimit/wallet-service/pkg/endpoint"
 	"github.com/shirolimit/wallet-service/pkg/transport"
+	"google.golang.org/grpc"
 )
 
 var (
 	fs       = flag.NewFlagSet("wallet", flag.ExitOnError)
 	httpAddr = fs.String("http-address", ":8080", "HTTP address to listen")
+	grpcAddr = fs.String("grpc-address", "", "gRPC address to listen, gRPC transport is disabled if empty")
 	connStr  = fs.String("connection-string", "", "Postgres connection string")
+	storType = fs.String("storage", "postgres", "Storage type: postgres or memory")
+	autoMig  = fs.Bool("auto-migrate", false, "Apply pending schema migrations on startup")
+	fxFile   = fs.String("fx-rates-file", "", "JSON file with exchange rates, enables payments with currency exchange")
+	fxURL    = fs.String("fx-url", "", "URL of exchange rates service, enables payments with currency exchange")
+	currList = fs.String("currencies", "", "Comma separated ISO 4217 codes of enabled currencies, all are enabled by default")
+	traceExp = fs.String("tracing-exporter", "none", "Exporter of traces: none, stdout or otlp")
+	otlpAddr = fs.String("otlp-endpoint", "localhost:4317", "Address of OTLP gRPC collector used by otlp tracing exporter")
+	rateLim  = fs.Float64("rate-limit", 0, "Requests per second allowed to each endpoint, unlimited if zero")
+	accLim   = fs.Float64("account-rate-limit", 0, "Requests per second allowed to each endpoint for a single account, unlimited if zero")
+	cbThresh = fs.Uint("breaker-threshold", 5, "Consecutive database connection errors that open circuit breaker")
+	cbTime   = fs.Duration("breaker-timeout", 10*time.Second, "Time circuit breaker stays open before probing database")
+	keysFile = fs.String("api-keys-file", "", "JSON file with SHA-256 hashes of API keys, enables authentication")
+	jwksFile = fs.String("jwks-file", "", "JWKS file with keys verifying HS256 and RS256 bearer tokens, enables authentication")
+	noAuth   = fs.Bool("insecure-no-auth", false, "Serve API without authentication if neither API keys nor JWKS file is specified, for local development only")
+	noInitBl = fs.Bool("forbid-initial-balance", false, "Reject accounts created with non-zero balance, accounts are funded by deposits only")
+	stopWait = fs.Duration("shutdown-delay", 0, "Time between failing readiness checks and draining connections on shutdown")
 )
 
-func main() {
-	fs.Parse(os.Args[1:])
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
 
+func main() {
 	logger := log.NewLogfmtLogger(os.Stderr)
 	logger = log.With(logger, "caller", log.DefaultCaller)
 	logger = log.With(logger, "timestamp", log.DefaultTimestampUTC)
 
-	storage := db.NewPgStorage(*connStr)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:], logger))
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		os.Exit(runReconcile(os.Args[2:], logger))
+	}
+
+	fs.Parse(os.Args[1:])
+
+	tracerProvider, shutdownTracing, err := newTracerProvider(*traceExp, *otlpAddr)
+	if err != nil {
+		logger.Log("tracing", *traceExp, "error", err)
+		os.Exit(1)
+	}
+	tracer := tracerProvider.Tracer(tracerName)
+
+	checker := health.NewChecker()
+
+	var storage db.Storage
+	var storageCheck health.Check
+	switch *storType {
+	case "postgres":
+		handle, err := sql.Open("postgres", *connStr)
+		if err != nil {
+			logger.Log("storage", *storType, "error", err)
+			os.Exit(1)
+		}
+
+		migrator := db.NewMigrator(handle)
+		if *autoMig {
+			if err = migrator.Up(context.Background()); err != nil {
+				logger.Log("migrations", "up", "error", err)
+				os.Exit(1)
+			}
+		}
+		breaker := db.NewCircuitBreakerStorage(
+			db.PgStorageFromHandle(handle, db.WithTracer(tracer)),
+			uint32(*cbThresh),
+			*cbTime,
+		)
+		checker.Add("migrations", migrationsCheck(migrator))
+		storage = breaker
+		storageCheck = breakerCheck(breaker)
+	case "memory":
+		storage = db.NewMemoryStorage()
+		storageCheck = storage.Ping
+	default:
+		logger.Log("storage", *storType, "error", "unknown storage type")
+		os.Exit(1)
+	}
+
+	var options []service.Option
+	switch {
+	case *fxFile != "" && *fxURL != "":
+		logger.Log("fx", "rates", "error", "only one of exchange rates file and URL can be specified")
+		os.Exit(1)
+	case *fxFile != "":
+		rates, err := fx.NewFileProvider(*fxFile)
+		if err != nil {
+			logger.Log("fx", *fxFile, "error", err)
+			os.Exit(1)
+		}
+		options = append(options, service.WithFXRateProvider(rates))
+	case *fxURL != "":
+		options = append(options, service.WithFXRateProvider(fx.NewHTTPProvider(*fxURL, nil)))
+	}
+
+	if *currList != "" {
+		currencies, err := entities.DefaultCurrencyRegistry.EnabledOnly(strings.Split(*currList, ",")...)
+		if err != nil {
+			logger.Log("currencies", *currList, "error", err)
+			os.Exit(1)
+		}
+		options = append(options, service.WithCurrencyRegistry(currencies))
+	}
+
+	if *noInitBl {
+		options = append(options, service.WithoutInitialBalance())
+	}
+
+	checker.Add("storage", storageCheck)
 
-	svc := service.NewWalletService(storage)
+	authEnabled := *keysFile != "" || *jwksFile != ""
+	if !authEnabled && !*noAuth {
+		logger.Log("auth", "disabled", "error", "specify --api-keys-file or --jwks-file, or --insecure-no-auth to serve API without authentication")
+		os.Exit(1)
+	}
+
+	svc := service.NewWalletService(storage, options...)
+	if authEnabled {
+		svc = service.AuthorizationMiddleware()(svc)
+	}
 	svc = service.LoggingMiddleware(logger)(svc)
+	svc = service.InstrumentingMiddleware(newServiceMetrics())(svc)
+	svc = service.TracingMiddleware(tracer)(svc)
 
-	endpoints := endpoint.NewEndpointSet(svc)
+	httpOptions := append(transport.HTTPTracingOptions(tracer, propagator), newHTTPMetricsOptions()...)
+	var grpcOptions []grpctransport.ServerOption
+	var middlewares []kitendpoint.Middleware
+	if authEnabled {
+		authMiddleware, err := newAuthMiddleware(*keysFile, *jwksFile)
+		if err != nil {
+			logger.Log("auth", "keys", "error", err)
+			os.Exit(1)
+		}
+		middlewares = append(middlewares, authMiddleware)
+		httpOptions = append(httpOptions, httptransport.ServerBefore(auth.HTTPToContext()))
+		grpcOptions = append(grpcOptions, grpctransport.ServerBefore(auth.GRPCToContext()))
+	} else {
+		logger.Log("auth", "disabled", "warning", "API is available without authentication")
+	}
 
-	handler := transport.NewHTTPHandler(endpoints, nil)
+	endpoints := endpoint.NewEndpointSet(svc, tracer, newEndpointLimits(*rateLim, *accLim), middlewares...)
+
+	handler := http.NewServeMux()
+	handler.Handle("/metrics", promhttp.Handler())
+	handler.Handle("/healthz", checker.LivenessHandler())
+	handler.Handle("/readyz", checker.ReadinessHandler())
+	handler.Handle("/version", health.VersionHandler(health.NewBuildInfo(version)))
+	handler.Handle("/", transport.NewHTTPHandler(endpoints, httpOptions))
 	server := http.Server{Addr: *httpAddr, Handler: handler}
 	go func() {
 		if err := server.ListenAndServe(); err != http.ErrServerClosed {
@@ -48,13 +195,112 @@ func main() {
 		}
 	}()
 
+	var grpcServer *grpc.Server
+	if *grpcAddr != "" {
+		listener, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			logger.Log("transport", "gRPC", "error", err)
+			os.Exit(1)
+		}
+
+		grpcServer = grpc.NewServer()
+		pb.RegisterWalletServer(grpcServer, transport.NewGRPCServer(endpoints, grpcOptions...))
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Log(
+					"transport", "gRPC",
+					"error", err,
+				)
+			}
+		}()
+	}
+
 	stop := make(chan os.Signal, 1)
 
-	signal.Notify(stop, os.Interrupt)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
 	<-stop
 
+	// stop receiving new traffic before draining connections
+	checker.Shutdown()
+	time.Sleep(*stopWait)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	server.Shutdown(ctx)
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+	shutdownTracing(ctx)
+}
+
+// newEndpointLimits applies the same limits to all endpoints, burst allows one second worth of requests
+func newEndpointLimits(global, perAccount float64) endpoint.Limits {
+	limits := endpoint.EndpointLimits{
+		Global:     endpoint.RateLimit{Rate: global, Burst: int(math.Ceil(global))},
+		PerAccount: endpoint.RateLimit{Rate: perAccount, Burst: int(math.Ceil(perAccount))},
+	}
+	return endpoint.Limits{
+		CreateAccount: limits,
+		ListAccounts:  limits,
+		GetAccount:    limits,
+		UpdateAccount: limits,
+		GetPayments:   limits,
+		GetPayment:    limits,
+		MakePayment:   limits,
+		Deposit:       limits,
+		Withdraw:      limits,
+		CreateHold:    limits,
+		GetHold:       limits,
+		CaptureHold:   limits,
+		ReleaseHold:   limits,
+		RefundPayment: limits,
+	}
+}
+
+// migrationsCheck fails if database schema is not at the version expected by this binary
+func migrationsCheck(migrator *db.Migrator) health.Check {
+	return func(ctx context.Context) error {
+		current, err := migrator.Version(ctx)
+		if err != nil {
+			return err
+		}
+		if latest := migrator.LatestVersion(); current != latest {
+			return fmt.Errorf("schema version is %d, expected %d", current, latest)
+		}
+		return nil
+	}
+}
+
+// breakerCheck pings storage through circuit breaker and fails unless the breaker is closed.
+// Instance which is not ready gets no traffic, so the ping is the trial request of half-open breaker
+// which closes it once the database is reachable again
+func breakerCheck(breaker *db.CircuitBreakerStorage) health.Check {
+	return func(ctx context.Context) error {
+		if err := breaker.Ping(ctx); err != nil {
+			return err
+		}
+		if state := breaker.State(); state != db.BreakerClosed {
+			return fmt.Errorf("storage circuit breaker is %s", state)
+		}
+		return nil
+	}
+}
+
+// newAuthMiddleware creates authentication middleware accepting API keys and bearer tokens from specified files
+func newAuthMiddleware(keysFile, jwksFile string) (kitendpoint.Middleware, error) {
+	var keys *auth.APIKeys
+	var tokens *auth.JWTVerifier
+	var err error
+	if keysFile != "" {
+		if keys, err = auth.LoadAPIKeys(keysFile); err != nil {
+			return nil, err
+		}
+	}
+	if jwksFile != "" {
+		if tokens, err = auth.LoadJWKS(jwksFile); err != nil {
+			return nil, err
+		}
+	}
+	return auth.Middleware(keys, tokens), nil
 }
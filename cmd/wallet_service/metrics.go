@@ -0,0 +1,67 @@
+package main
+
+import (
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	httptransport "github.com/go-kit/kit/transport/http"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/shirolimit/wallet-service/pkg/service"
+	"github.com/shirolimit/wallet-service/pkg/transport"
+)
+
+const metricsNamespace = "wallet"
+
+// newServiceMetrics registers Prometheus metrics of service methods and payments
+func newServiceMetrics() service.Metrics {
+	return service.Metrics{
+		RequestCount: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "service",
+			Name:      "requests_total",
+			Help:      "Number of service method calls",
+		}, []string{"method"}),
+		ErrorCount: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "service",
+			Name:      "errors_total",
+			Help:      "Number of failed service method calls by error",
+		}, []string{"method", "error"}),
+		RequestLatency: kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "service",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of service method calls in seconds",
+			Buckets:   stdprometheus.DefBuckets,
+		}, []string{"method"}),
+		PaymentVolume: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "payments",
+			Name:      "volume_total",
+			Help:      "Sum of completed payment amounts by type and currency of source account",
+		}, []string{"type", "currency"}),
+		FailedPayments: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "payments",
+			Name:      "failed_total",
+			Help:      "Number of rejected payments by type and reason",
+		}, []string{"type", "reason"}),
+	}
+}
+
+// newHTTPMetricsOptions registers Prometheus metrics of HTTP requests
+func newHTTPMetricsOptions() []httptransport.ServerOption {
+	return transport.HTTPMetricsOptions(
+		kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of HTTP requests",
+		}, []string{"method", "route", "code"}),
+		kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of HTTP requests in seconds",
+			Buckets:   stdprometheus.DefBuckets,
+		}, []string{"method", "route", "code"}),
+	)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/shirolimit/wallet-service/pkg/db"
+)
+
+// runMigrate handles "migrate up|down|status" subcommands and returns process exit code
+func runMigrate(args []string, logger log.Logger) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: wallet_service migrate up|down|status --connection-string=<postgres_connection_string>")
+		return 2
+	}
+	action := args[0]
+	fs.Parse(args[1:])
+
+	handle, err := sql.Open("postgres", *connStr)
+	if err != nil {
+		logger.Log("migrations", action, "error", err)
+		return 1
+	}
+	defer handle.Close()
+
+	migrator := db.NewMigrator(handle)
+	ctx := context.Background()
+
+	switch action {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "status":
+		err = printMigrationStatus(ctx, migrator)
+	default:
+		err = fmt.Errorf("unknown migrate command %q", action)
+	}
+
+	if err != nil {
+		logger.Log("migrations", action, "error", err)
+		return 1
+	}
+
+	if action != "status" {
+		version, err := migrator.Version(ctx)
+		logger.Log("migrations", action, "version", version, "error", err)
+	}
+	return 0
+}
+
+func printMigrationStatus(ctx context.Context, migrator *db.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	return w.Flush()
+}
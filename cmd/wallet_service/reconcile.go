@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/shirolimit/wallet-service/pkg/db"
+)
+
+// runReconcile handles "reconcile" subcommand: prints reconciliation report as JSON to stdout
+// and returns 1 if any mismatches are found or 2 if reconciliation has failed.
+// With --forbid-initial-balance initial balances of each currency must sum up to zero
+func runReconcile(args []string, logger log.Logger) int {
+	fs.Parse(args)
+
+	handle, err := sql.Open("postgres", *connStr)
+	if err != nil {
+		logger.Log("reconcile", "open", "error", err)
+		return 2
+	}
+	defer handle.Close()
+
+	var options []db.ReconcilerOption
+	if *noInitBl {
+		options = append(options, db.WithoutInitialBalance())
+	}
+
+	report, err := db.NewReconciler(handle, options...).Reconcile(context.Background())
+	if err != nil {
+		logger.Log("reconcile", "run", "error", err)
+		return 2
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(report); err != nil {
+		logger.Log("reconcile", "report", "error", err)
+		return 2
+	}
+
+	logger.Log(
+		"reconcile", "done",
+		"accounts", report.Accounts,
+		"mismatches", len(report.Mismatches),
+		"currency_mismatches", len(report.CurrencyMismatches),
+	)
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
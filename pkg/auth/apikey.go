@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// apiKeysFile is a format of API keys file
+type apiKeysFile struct {
+	Keys []struct {
+		// Name is a subject of principal authenticated with the key
+		Name string `json:"name"`
+
+		// SHA256 is a hex encoded SHA-256 hash of the key, keys themselves are never stored
+		SHA256 string   `json:"sha256"`
+		Roles  []string `json:"roles"`
+	} `json:"keys"`
+}
+
+// APIKeys authenticates clients with static API keys
+type APIKeys struct {
+	byHash map[string]Principal
+}
+
+// NewAPIKeys creates APIKeys with principals indexed by hex encoded SHA-256 hashes of their keys
+func NewAPIKeys(byHash map[string]Principal) *APIKeys {
+	keys := &APIKeys{byHash: make(map[string]Principal, len(byHash))}
+	for hash, p := range byHash {
+		keys.byHash[strings.ToLower(hash)] = p
+	}
+	return keys
+}
+
+// LoadAPIKeys reads API keys from JSON file like
+// {"keys": [{"name": "backoffice", "sha256": "<hex>", "roles": ["admin"]}]}
+func LoadAPIKeys(path string) (*APIKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file apiKeysFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid API keys file %s: %v", path, err)
+	}
+
+	byHash := make(map[string]Principal, len(file.Keys))
+	for _, key := range file.Keys {
+		if hash, err := hex.DecodeString(key.SHA256); err != nil || len(hash) != sha256.Size || key.Name == "" {
+			return nil, fmt.Errorf("invalid API key %q in %s: name and hex encoded SHA-256 hash are required", key.Name, path)
+		}
+		byHash[key.SHA256] = Principal{Subject: key.Name, Roles: key.Roles}
+	}
+	return NewAPIKeys(byHash), nil
+}
+
+// Authenticate returns principal of specified API key
+func (k *APIKeys) Authenticate(key string) (Principal, bool) {
+	hash := sha256.Sum256([]byte(key))
+	p, ok := k.byHash[hex.EncodeToString(hash[:])]
+	return p, ok
+}
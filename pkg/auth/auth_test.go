@@ -0,0 +1,107 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/shirolimit/wallet-service/pkg/auth"
+	"github.com/shirolimit/wallet-service/pkg/entities"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Error while writing %s: %v", name, err)
+	}
+	return path
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Error while signing token: %v", err)
+	}
+	return signed
+}
+
+func Test_Middleware(t *testing.T) {
+	hash := sha256.Sum256([]byte("secret-key"))
+	keys, err := auth.LoadAPIKeys(writeFile(t, "keys.json",
+		fmt.Sprintf(`{"keys": [{"name": "backoffice", "sha256": "%s", "roles": ["admin"]}]}`, hex.EncodeToString(hash[:]))))
+	if err != nil {
+		t.Fatalf("Error while loading API keys: %v", err)
+	}
+
+	secret := []byte("hmac-secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error while generating RSA key: %v", err)
+	}
+	b64 := base64.RawURLEncoding.EncodeToString
+	tokens, err := auth.LoadJWKS(writeFile(t, "jwks.json", fmt.Sprintf(
+		`{"keys": [{"kty": "oct", "kid": "hs", "k": "%s"}, {"kty": "RSA", "kid": "rs", "n": "%s", "e": "%s"}]}`,
+		b64(secret), b64(rsaKey.N.Bytes()), b64(big.NewInt(int64(rsaKey.E)).Bytes()))))
+	if err != nil {
+		t.Fatalf("Error while loading JWKS: %v", err)
+	}
+
+	valid := jwt.MapClaims{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}
+	expired := jwt.MapClaims{"sub": "alice", "exp": time.Now().Add(-time.Hour).Unix()}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    *auth.Principal
+	}{
+		{"api_key", map[string]string{"X-API-Key": "secret-key"}, &auth.Principal{Subject: "backoffice", Roles: []string{"admin"}}},
+		{"wrong_api_key", map[string]string{"X-API-Key": "guess"}, nil},
+		{"hs256", map[string]string{"Authorization": "Bearer " + signToken(t, jwt.SigningMethodHS256, "hs", secret, valid)},
+			&auth.Principal{Subject: "alice"}},
+		{"rs256", map[string]string{"Authorization": "Bearer " + signToken(t, jwt.SigningMethodRS256, "rs", rsaKey, valid)},
+			&auth.Principal{Subject: "alice"}},
+		{"expired", map[string]string{"Authorization": "Bearer " + signToken(t, jwt.SigningMethodHS256, "hs", secret, expired)}, nil},
+		{"wrong_secret", map[string]string{"Authorization": "Bearer " + signToken(t, jwt.SigningMethodHS256, "hs", []byte("guess"), valid)}, nil},
+		{"key_of_other_algorithm", map[string]string{"Authorization": "Bearer " + signToken(t, jwt.SigningMethodHS256, "rs", secret, valid)}, nil},
+		{"no_credentials", nil, nil},
+	}
+
+	endpoint := auth.Middleware(keys, tokens)(func(ctx context.Context, request interface{}) (interface{}, error) {
+		p, _ := auth.FromContext(ctx)
+		return p, nil
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/accounts", nil)
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+
+			got, err := endpoint(auth.HTTPToContext()(context.TODO(), r), nil)
+			if tt.want == nil {
+				if err != entities.ErrUnauthorized {
+					t.Errorf("Middleware() error = %v, want %v", err, entities.ErrUnauthorized)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, *tt.want) {
+				t.Errorf("Middleware() = %v, %v, want %v", got, err, *tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwk is a JSON Web Key of oct (HS256) or RSA (RS256) type
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// verificationKey is a parsed JWK
+type verificationKey struct {
+	alg string
+	key interface{}
+}
+
+// claims are JWT claims used by the service, roles claim is optional
+type claims struct {
+	Roles []string `json:"roles"`
+	jwt.RegisteredClaims
+}
+
+// JWTVerifier authenticates clients with HS256 and RS256 signed JWTs
+type JWTVerifier struct {
+	keys   map[string]verificationKey
+	parser *jwt.Parser
+}
+
+// LoadJWKS reads JSON Web Key Set file and creates verifier of tokens signed with its keys.
+// Tokens must have exp and sub claims, kid header is required if there are several keys
+func LoadJWKS(path string) (*JWTVerifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err = json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS file %s: %v", path, err)
+	}
+
+	keys := make(map[string]verificationKey, len(set.Keys))
+	for _, k := range set.Keys {
+		key, err := k.parse()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q in %s: %v", k.Kid, path, err)
+		}
+		keys[k.Kid] = key
+	}
+	return newJWTVerifier(keys), nil
+}
+
+func newJWTVerifier(keys map[string]verificationKey) *JWTVerifier {
+	return &JWTVerifier{
+		keys: keys,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}),
+			jwt.WithExpirationRequired(),
+		),
+	}
+}
+
+func (k jwk) parse() (verificationKey, error) {
+	switch k.Kty {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || len(secret) == 0 {
+			return verificationKey{}, errors.New("k must be a base64url encoded secret")
+		}
+		return verificationKey{alg: jwt.SigningMethodHS256.Alg(), key: secret}, nil
+	case "RSA":
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 {
+			return verificationKey{}, errors.New("n and e must be base64url encoded numbers")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		return verificationKey{alg: jwt.SigningMethodRS256.Alg(), key: key}, nil
+	default:
+		return verificationKey{}, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// Authenticate verifies token and returns principal of its subject
+func (v *JWTVerifier) Authenticate(token string) (Principal, error) {
+	var c claims
+	if _, err := v.parser.ParseWithClaims(token, &c, v.keyFunc); err != nil {
+		return Principal{}, err
+	}
+	if c.Subject == "" {
+		return Principal{}, errors.New("token has no subject")
+	}
+	return Principal{Subject: c.Subject, Roles: c.Roles}, nil
+}
+
+// keyFunc finds verification key by kid header. The key must be of the token algorithm,
+// so that RSA public key can't be used as HMAC secret
+func (v *JWTVerifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := v.keys[kid]
+	if !ok && kid == "" && len(v.keys) == 1 {
+		for _, key = range v.keys {
+			ok = true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	if key.alg != token.Method.Alg() {
+		return nil, fmt.Errorf("key %q can't verify %s tokens", kid, token.Method.Alg())
+	}
+	return key.key, nil
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/kit/endpoint"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/shirolimit/wallet-service/pkg/entities"
+)
+
+const (
+	// apiKeyHeader carries API key of HTTP request
+	apiKeyHeader = "X-API-Key"
+
+	// bearerPrefix is a prefix of Authorization header carrying JWT
+	bearerPrefix = "Bearer "
+)
+
+// credentials are raw client credentials extracted by transport
+type credentials struct {
+	apiKey string
+	token  string
+}
+
+type credentialsKey struct{}
+
+// HTTPToContext returns request function which moves credentials from X-API-Key
+// or Authorization: Bearer headers into context
+func HTTPToContext() httptransport.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		return context.WithValue(ctx, credentialsKey{}, credentials{
+			apiKey: r.Header.Get(apiKeyHeader),
+			token:  bearerToken(r.Header.Get("Authorization")),
+		})
+	}
+}
+
+// GRPCToContext returns request function which moves credentials from x-api-key
+// or authorization metadata into context
+func GRPCToContext() grpctransport.ServerRequestFunc {
+	return func(ctx context.Context, md metadata.MD) context.Context {
+		var creds credentials
+		if values := md.Get(strings.ToLower(apiKeyHeader)); len(values) > 0 {
+			creds.apiKey = values[0]
+		}
+		if values := md.Get("authorization"); len(values) > 0 {
+			creds.token = bearerToken(values[0])
+		}
+		return context.WithValue(ctx, credentialsKey{}, creds)
+	}
+}
+
+func bearerToken(header string) string {
+	if len(header) > len(bearerPrefix) && strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return strings.TrimSpace(header[len(bearerPrefix):])
+	}
+	return ""
+}
+
+// Middleware returns endpoint middleware which authenticates credentials put into context
+// by HTTPToContext or GRPCToContext and stores Principal in context. API keys are checked
+// with keys and bearer tokens with tokens, nil disables the method. Failures result in ErrUnauthorized
+func Middleware(keys *APIKeys, tokens *JWTVerifier) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			creds, _ := ctx.Value(credentialsKey{}).(credentials)
+
+			var principal Principal
+			var ok bool
+			switch {
+			case creds.apiKey != "" && keys != nil:
+				principal, ok = keys.Authenticate(creds.apiKey)
+			case creds.token != "" && tokens != nil:
+				var err error
+				principal, err = tokens.Authenticate(creds.token)
+				ok = err == nil
+			}
+			if !ok {
+				return nil, entities.ErrUnauthorized
+			}
+			return next(NewContext(ctx, principal), request)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package auth
+
+import "context"
+
+// RoleAdmin is a role of principals allowed to operate on any account
+const RoleAdmin = "admin"
+
+// Principal is an authenticated client of the service
+type Principal struct {
+	// Subject identifies the client, i.e. API key name or JWT subject
+	Subject string
+
+	// Roles granted to the client
+	Roles []string
+}
+
+// HasRole reports whether principal has specified role
+func (p Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying principal
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns principal stored in ctx by authentication middleware
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,231 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+	"github.com/sony/gobreaker"
+
+	"github.com/shirolimit/wallet-service/pkg/entities"
+)
+
+// Circuit breaker states returned by CircuitBreakerStorage.State
+const (
+	BreakerClosed   = "closed"
+	BreakerHalfOpen = "half-open"
+	BreakerOpen     = "open"
+)
+
+// CircuitBreakerStorage is a Storage decorator that stops calling the underlying storage
+// after consecutive connection errors and fails fast with ErrDatabaseConnection until timeout passes
+type CircuitBreakerStorage struct {
+	next    Storage
+	breaker *gobreaker.CircuitBreaker
+}
+
+// NewCircuitBreakerStorage wraps storage with circuit breaker which opens after threshold
+// consecutive connection errors and lets a probe request through after timeout
+func NewCircuitBreakerStorage(next Storage, threshold uint32, timeout time.Duration) *CircuitBreakerStorage {
+	return &CircuitBreakerStorage{
+		next: next,
+		breaker: gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Name:    "storage",
+			Timeout: timeout,
+			ReadyToTrip: func(counts gobreaker.Counts) bool {
+				return counts.ConsecutiveFailures >= threshold
+			},
+			// business errors like ErrAccountNotFound mean the database is fine
+			IsSuccessful: func(err error) bool {
+				return err != entities.ErrDatabaseConnection
+			},
+		}),
+	}
+}
+
+// State returns circuit breaker state: BreakerClosed, BreakerHalfOpen or BreakerOpen
+func (cbs *CircuitBreakerStorage) State() string {
+	return cbs.breaker.State().String()
+}
+
+func (cbs *CircuitBreakerStorage) CreateAccount(ctx context.Context, acc entities.Account) (*entities.Account, error) {
+	result, err := cbs.execute(func() (interface{}, error) {
+		return cbs.next.CreateAccount(ctx, acc)
+	})
+	stored, _ := result.(*entities.Account)
+	return stored, err
+}
+
+func (cbs *CircuitBreakerStorage) GetAccount(ctx context.Context, id entities.AccountID) (*entities.Account, error) {
+	acc, err := cbs.execute(func() (interface{}, error) {
+		return cbs.next.GetAccount(ctx, id)
+	})
+	account, _ := acc.(*entities.Account)
+	return account, err
+}
+
+func (cbs *CircuitBreakerStorage) UpdateAccountStatus(ctx context.Context, id entities.AccountID,
+	status entities.AccountStatus) (*entities.Account, error) {
+
+	acc, err := cbs.execute(func() (interface{}, error) {
+		return cbs.next.UpdateAccountStatus(ctx, id, status)
+	})
+	account, _ := acc.(*entities.Account)
+	return account, err
+}
+
+func (cbs *CircuitBreakerStorage) ListAccounts(ctx context.Context, query entities.AccountsQuery) (entities.AccountsPage, error) {
+	page, err := cbs.execute(func() (interface{}, error) {
+		return cbs.next.ListAccounts(ctx, query)
+	})
+	accounts, _ := page.(entities.AccountsPage)
+	return accounts, err
+}
+
+func (cbs *CircuitBreakerStorage) PaymentsByAccount(ctx context.Context, id entities.AccountID,
+	query entities.PaymentsQuery) (entities.PaymentsPage, error) {
+
+	page, err := cbs.execute(func() (interface{}, error) {
+		return cbs.next.PaymentsByAccount(ctx, id, query)
+	})
+	payments, _ := page.(entities.PaymentsPage)
+	return payments, err
+}
+
+func (cbs *CircuitBreakerStorage) CreatePayment(ctx context.Context, payment entities.Payment) (*entities.Payment, error) {
+	stored, err := cbs.execute(func() (interface{}, error) {
+		return cbs.next.CreatePayment(ctx, payment)
+	})
+	storedPayment, _ := stored.(*entities.Payment)
+	return storedPayment, err
+}
+
+func (cbs *CircuitBreakerStorage) LedgerEntries(ctx context.Context, id entities.AccountID) ([]entities.LedgerEntry, error) {
+	entries, err := cbs.execute(func() (interface{}, error) {
+		return cbs.next.LedgerEntries(ctx, id)
+	})
+	ledger, _ := entries.([]entities.LedgerEntry)
+	return ledger, err
+}
+
+func (cbs *CircuitBreakerStorage) BalanceAt(ctx context.Context, id entities.AccountID, entryID int64) (decimal.Decimal, error) {
+	balance, err := cbs.execute(func() (interface{}, error) {
+		return cbs.next.BalanceAt(ctx, id, entryID)
+	})
+	amount, _ := balance.(decimal.Decimal)
+	return amount, err
+}
+
+func (cbs *CircuitBreakerStorage) CreateHold(ctx context.Context, hold entities.Hold) (*entities.Hold, error) {
+	result, err := cbs.execute(func() (interface{}, error) {
+		return cbs.next.CreateHold(ctx, hold)
+	})
+	stored, _ := result.(*entities.Hold)
+	return stored, err
+}
+
+func (cbs *CircuitBreakerStorage) GetHold(ctx context.Context, id uuid.UUID) (*entities.Hold, error) {
+	result, err := cbs.execute(func() (interface{}, error) {
+		return cbs.next.GetHold(ctx, id)
+	})
+	hold, _ := result.(*entities.Hold)
+	return hold, err
+}
+
+func (cbs *CircuitBreakerStorage) CaptureHold(ctx context.Context, id uuid.UUID, amount *decimal.Decimal) (*entities.Payment, error) {
+	result, err := cbs.execute(func() (interface{}, error) {
+		return cbs.next.CaptureHold(ctx, id, amount)
+	})
+	payment, _ := result.(*entities.Payment)
+	return payment, err
+}
+
+func (cbs *CircuitBreakerStorage) ReleaseHold(ctx context.Context, id uuid.UUID) (*entities.Hold, error) {
+	result, err := cbs.execute(func() (interface{}, error) {
+		return cbs.next.ReleaseHold(ctx, id)
+	})
+	hold, _ := result.(*entities.Hold)
+	return hold, err
+}
+
+func (cbs *CircuitBreakerStorage) GetPayment(ctx context.Context, id uuid.UUID) (*entities.PaymentRecord, error) {
+	result, err := cbs.execute(func() (interface{}, error) {
+		return cbs.next.GetPayment(ctx, id)
+	})
+	record, _ := result.(*entities.PaymentRecord)
+	return record, err
+}
+
+func (cbs *CircuitBreakerStorage) CreateRefund(ctx context.Context, refund entities.Payment) (*entities.Payment, error) {
+	stored, err := cbs.execute(func() (interface{}, error) {
+		return cbs.next.CreateRefund(ctx, refund)
+	})
+	storedPayment, _ := stored.(*entities.Payment)
+	return storedPayment, err
+}
+
+func (cbs *CircuitBreakerStorage) Ping(ctx context.Context) error {
+	_, err := cbs.execute(func() (interface{}, error) {
+		return nil, cbs.next.Ping(ctx)
+	})
+	return err
+}
+
+// execute runs storage call through circuit breaker, connection errors and rejections
+// of open breaker are reported as ErrDatabaseConnection
+func (cbs *CircuitBreakerStorage) execute(call func() (interface{}, error)) (interface{}, error) {
+	result, err := cbs.breaker.Execute(func() (interface{}, error) {
+		result, err := call()
+		if isConnectionError(err) {
+			return result, entities.ErrDatabaseConnection
+		}
+		return result, err
+	})
+
+	if err == gobreaker.ErrOpenState || err == gobreaker.ErrTooManyRequests {
+		return result, entities.ErrDatabaseConnection
+	}
+	return result, err
+}
+
+// isConnectionError reports whether err means that database is unreachable
+// rather than that the query has failed
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	// canceled requests say nothing about the database, and context.DeadlineExceeded
+	// would otherwise pass for a net.Error timeout below
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if err == driver.ErrBadConn || err == sql.ErrConnDone || err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var pgErr *pq.Error
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	switch pgErr.Code.Class() {
+	case "08": // connection exception
+		return true
+	case "53": // insufficient resources, i.e. too many connections
+		return true
+	case "57": // operator intervention, i.e. database shutdown
+		return pgErr.Code != "57014" // query canceled
+	}
+	return false
+}
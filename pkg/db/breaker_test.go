@@ -0,0 +1,56 @@
+package db_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+
+	mydb "github.com/shirolimit/wallet-service/pkg/db"
+	"github.com/shirolimit/wallet-service/pkg/entities"
+)
+
+func Test_CircuitBreakerStorage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mydb.NewMockStorage(ctrl)
+	storage := mydb.NewCircuitBreakerStorage(mockStorage, 2, time.Minute)
+	queryErr := &pq.Error{Code: "42P01"}
+	deadlineErr := fmt.Errorf("query accounts: %w", context.DeadlineExceeded)
+
+	tests := []struct {
+		name       string
+		storageErr error
+		wantCall   bool
+		wantErr    error
+		wantState  string
+	}{
+		{"business_error_is_kept", entities.ErrAccountNotFound, true, entities.ErrAccountNotFound, mydb.BreakerClosed},
+		{"query_error_is_kept", queryErr, true, queryErr, mydb.BreakerClosed},
+		{"canceled_is_kept", context.Canceled, true, context.Canceled, mydb.BreakerClosed},
+		{"deadline_is_kept", deadlineErr, true, deadlineErr, mydb.BreakerClosed},
+		{"connection_error", driver.ErrBadConn, true, entities.ErrDatabaseConnection, mydb.BreakerClosed},
+		{"wrapped_connection_error_opens", fmt.Errorf("query accounts: %w", &pq.Error{Code: "57P03"}), true, entities.ErrDatabaseConnection, mydb.BreakerOpen},
+		{"fails_fast_when_open", nil, false, entities.ErrDatabaseConnection, mydb.BreakerOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantCall {
+				mockStorage.EXPECT().GetAccount(context.TODO(), entities.AccountID("alice")).Return(nil, tt.storageErr)
+			}
+
+			_, err := storage.GetAccount(context.TODO(), "alice")
+			if err != tt.wantErr {
+				t.Errorf("CircuitBreakerStorage.GetAccount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if state := storage.State(); state != tt.wantState {
+				t.Errorf("CircuitBreakerStorage.State() = %v, want %v", state, tt.wantState)
+			}
+		})
+	}
+}
@@ -0,0 +1,574 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shirolimit/wallet-service/pkg/entities"
+)
+
+// memoryStorage is a Storage implementation that keeps all data in memory
+// It is safe for concurrent use and is mostly used in tests and for local development
+type memoryStorage struct {
+	mu       sync.RWMutex
+	accounts map[entities.AccountID]*entities.Account
+	payments []memoryPayment
+
+	// paymentIndex maps payment ID to its position in payments
+	paymentIndex map[uuid.UUID]int
+
+	// ledger keeps entries of all accounts, ID of entry is its position in ledger plus one
+	ledger          []entities.LedgerEntry
+	initialBalances map[entities.AccountID]decimal.Decimal
+
+	// holds keep stored statuses, active holds past their expiration time are reported as expired
+	holds map[uuid.UUID]*entities.Hold
+}
+
+// memoryPayment is a direction-neutral payment record
+type memoryPayment struct {
+	id          uuid.UUID
+	source      entities.AccountID
+	destination entities.AccountID
+	amount      decimal.Decimal
+	currency    string
+	createdAt   time.Time
+	status      entities.PaymentStatus
+	exchange    *entities.Exchange
+	paymentType entities.PaymentType
+	refundOf    *uuid.UUID
+}
+
+// record converts stored payment into PaymentRecord entity
+func (p memoryPayment) record() entities.PaymentRecord {
+	record := entities.PaymentRecord{
+		ID:          p.id,
+		Source:      p.source,
+		Destination: p.destination,
+		Amount:      p.amount,
+		Currency:    p.currency,
+		CreatedAt:   p.createdAt,
+		Status:      p.status,
+		Type:        p.paymentType,
+	}
+
+	// copy references so that callers can't modify stored payments
+	if p.exchange != nil {
+		exchange := *p.exchange
+		record.Exchange = &exchange
+	}
+	if p.refundOf != nil {
+		refundOf := *p.refundOf
+		record.RefundOf = &refundOf
+	}
+	return record
+}
+
+// payment converts stored record into Payment entity as it is seen by specified account
+func (p memoryPayment) payment(account entities.AccountID) entities.Payment {
+	record := p.record()
+	source, destination := record.Source, record.Destination
+	payment := entities.Payment{
+		ID:        record.ID,
+		Account:   account,
+		Amount:    record.Amount,
+		Currency:  record.Currency,
+		CreatedAt: record.CreatedAt,
+		Status:    record.Status,
+		Exchange:  record.Exchange,
+		Type:      record.Type,
+		RefundOf:  record.RefundOf,
+	}
+	if p.source == account {
+		payment.Direction = entities.Outgoing
+		payment.ToAccount = &destination
+	} else {
+		payment.Direction = entities.Incoming
+		payment.FromAccount = &source
+	}
+	return payment
+}
+
+// NewMemoryStorage creates new empty in-memory storage
+func NewMemoryStorage() Storage {
+	return &memoryStorage{
+		accounts:        make(map[entities.AccountID]*entities.Account),
+		paymentIndex:    make(map[uuid.UUID]int),
+		initialBalances: make(map[entities.AccountID]decimal.Decimal),
+		holds:           make(map[uuid.UUID]*entities.Hold),
+	}
+}
+
+// Ping always succeeds, memory is always reachable
+func (ms *memoryStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (ms *memoryStorage) CreateAccount(ctx context.Context, acc entities.Account) (*entities.Account, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if _, ok := ms.accounts[acc.ID]; ok {
+		return nil, entities.ErrAccountAlreadyExists
+	}
+
+	ms.accounts[acc.ID] = &acc
+	ms.initialBalances[acc.ID] = acc.Balance
+	result := ms.withAvailable(&acc)
+	return &result, nil
+}
+
+func (ms *memoryStorage) GetAccount(ctx context.Context, id entities.AccountID) (*entities.Account, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	acc, ok := ms.accounts[id]
+	if !ok {
+		return nil, entities.ErrAccountNotFound
+	}
+
+	result := ms.withAvailable(acc)
+	return &result, nil
+}
+
+func (ms *memoryStorage) UpdateAccountStatus(ctx context.Context, id entities.AccountID,
+	status entities.AccountStatus) (*entities.Account, error) {
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	acc, ok := ms.accounts[id]
+	if !ok {
+		return nil, entities.ErrAccountNotFound
+	}
+
+	if err := acc.CheckStatusChange(status); err != nil {
+		return nil, err
+	}
+
+	acc.Status = status
+	result := ms.withAvailable(acc)
+	return &result, nil
+}
+
+func (ms *memoryStorage) ListAccounts(ctx context.Context, query entities.AccountsQuery) (entities.AccountsPage, error) {
+	page := entities.AccountsPage{Accounts: []entities.Account{}}
+
+	var cursor *entities.AccountCursor
+	if query.Cursor != "" {
+		decoded, err := entities.DecodeAccountCursor(query.Cursor)
+		if err != nil {
+			return page, err
+		}
+		cursor = &decoded
+	}
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	for _, acc := range ms.accounts {
+		if query.Match(*acc) && (cursor == nil || cursor.After(*acc, query.Sort)) {
+			page.Accounts = append(page.Accounts, ms.withAvailable(acc))
+		}
+	}
+	sort.Slice(page.Accounts, func(i, j int) bool { return query.Sort.Less(page.Accounts[i], page.Accounts[j]) })
+
+	if query.Limit > 0 && len(page.Accounts) > query.Limit {
+		page.Accounts = page.Accounts[:query.Limit]
+		page.NextCursor = entities.NewAccountCursor(page.Accounts[query.Limit-1]).Encode()
+	}
+	return page, nil
+}
+
+func (ms *memoryStorage) GetPayment(ctx context.Context, id uuid.UUID) (*entities.PaymentRecord, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	i, ok := ms.paymentIndex[id]
+	if !ok {
+		return nil, entities.ErrPaymentNotFound
+	}
+
+	record := ms.payments[i].record()
+	return &record, nil
+}
+
+func (ms *memoryStorage) PaymentsByAccount(ctx context.Context, id entities.AccountID,
+	query entities.PaymentsQuery) (entities.PaymentsPage, error) {
+
+	page := entities.PaymentsPage{Payments: []entities.Payment{}}
+
+	var cursor *entities.PaymentCursor
+	if query.Cursor != "" {
+		decoded, err := entities.DecodePaymentCursor(query.Cursor)
+		if err != nil {
+			return page, err
+		}
+		cursor = &decoded
+	}
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	if _, ok := ms.accounts[id]; !ok {
+		return page, entities.ErrAccountNotFound
+	}
+
+	for _, p := range ms.payments {
+		if p.source != id && p.destination != id {
+			continue
+		}
+
+		payment := p.payment(id)
+		if !query.Match(payment) || (cursor != nil && !cursor.After(payment)) {
+			continue
+		}
+
+		// payments are appended in order of creation
+		if query.Limit > 0 && len(page.Payments) == query.Limit {
+			page.NextCursor = entities.NewPaymentCursor(page.Payments[query.Limit-1]).Encode()
+			break
+		}
+		page.Payments = append(page.Payments, payment)
+	}
+	return page, nil
+}
+
+func (ms *memoryStorage) CreatePayment(ctx context.Context, payment entities.Payment) (*entities.Payment, error) {
+	var source, destination entities.AccountID
+	if payment.Direction == entities.Outgoing {
+		source, destination = payment.Account, *payment.ToAccount
+	} else {
+		source, destination = *payment.FromAccount, payment.Account
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	return ms.createPayment(payment, source, destination)
+}
+
+// createPayment transfers money between accounts, the caller must hold the write lock
+func (ms *memoryStorage) createPayment(payment entities.Payment, source, destination entities.AccountID) (*entities.Payment, error) {
+	// payment ID is an idempotency key
+	if i, ok := ms.paymentIndex[payment.ID]; ok {
+		stored := ms.payments[i]
+		if stored.source != source || stored.destination != destination || !stored.amount.Equal(payment.Amount) ||
+			!sameRefundOf(stored.refundOf, payment.RefundOf) {
+			return nil, entities.ErrPaymentAlreadyDone
+		}
+		original := stored.payment(payment.Account)
+		return &original, entities.ErrPaymentDuplicate
+	}
+
+	sourceAccount, ok := ms.accounts[source]
+	if !ok {
+		return nil, entities.ErrPaymentSourceNotFound
+	}
+
+	destinationAccount, ok := ms.accounts[destination]
+	if !ok {
+		return nil, entities.ErrPaymentDestinationNotFound
+	}
+
+	if err := entities.CheckPaymentAccounts(*sourceAccount, *destinationAccount); err != nil {
+		return nil, err
+	}
+
+	credited, err := creditedAmount(payment, sourceAccount.Currency, destinationAccount.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// held funds can't be spent, mirrors balance_non_negative constraint of accounts table
+	if !source.IsExternal() && ms.withAvailable(sourceAccount).AvailableBalance.LessThan(payment.Amount) {
+		return nil, entities.ErrInsufficientFunds
+	}
+
+	// keep creation times strictly increasing, so that payments order matches pagination order
+	createdAt := time.Now().UTC()
+	if n := len(ms.payments); n > 0 && !createdAt.After(ms.payments[n-1].createdAt) {
+		createdAt = ms.payments[n-1].createdAt.Add(time.Nanosecond)
+	}
+
+	stored := memoryPayment{
+		id:          payment.ID,
+		source:      source,
+		destination: destination,
+		amount:      payment.Amount,
+		currency:    sourceAccount.Currency,
+		createdAt:   createdAt,
+		status:      entities.Completed,
+		paymentType: payment.Type,
+	}
+	if payment.Exchange != nil {
+		exchange := *payment.Exchange
+		stored.exchange = &exchange
+	}
+	if payment.RefundOf != nil {
+		refundOf := *payment.RefundOf
+		stored.refundOf = &refundOf
+	}
+	ms.paymentIndex[payment.ID] = len(ms.payments)
+	ms.payments = append(ms.payments, stored)
+	sourceAccount.Balance = sourceAccount.Balance.Sub(payment.Amount)
+	destinationAccount.Balance = destinationAccount.Balance.Add(credited)
+	ms.appendEntry(payment.ID, sourceAccount, payment.Amount.Neg(), createdAt)
+	ms.appendEntry(payment.ID, destinationAccount, credited, createdAt)
+
+	result := stored.payment(payment.Account)
+	return &result, nil
+}
+
+func (ms *memoryStorage) CreateRefund(ctx context.Context, refund entities.Payment) (*entities.Payment, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	i, ok := ms.paymentIndex[*refund.RefundOf]
+	if !ok {
+		return nil, entities.ErrPaymentNotFound
+	}
+
+	// only the recipient can refund the payment
+	original := ms.payments[i]
+	if refund.Account != original.destination {
+		return nil, entities.ErrPaymentNotFound
+	}
+
+	if err := checkRefundable(original.record()); err != nil {
+		return nil, err
+	}
+
+	// the refund itself is excluded, so that a retry of completed refund is reported as duplicate
+	refunded := decimal.Zero
+	for _, p := range ms.payments {
+		if p.refundOf != nil && *p.refundOf == original.id && p.id != refund.ID {
+			refunded = refunded.Add(p.amount)
+		}
+	}
+
+	amount, err := refundAmount(refund.Amount, original.amount, refunded)
+	if err != nil {
+		return nil, err
+	}
+
+	source, destination := original.destination, original.source
+	payment := refund
+	payment.Amount = amount
+	payment.Direction = entities.Outgoing
+	payment.ToAccount = &destination
+	payment.FromAccount = nil
+	payment.Exchange = nil
+	payment.Type = entities.Refund
+	return ms.createPayment(payment, source, destination)
+}
+
+// appendEntry appends ledger entry of already updated account
+func (ms *memoryStorage) appendEntry(paymentID uuid.UUID, acc *entities.Account, amount decimal.Decimal, createdAt time.Time) {
+	ms.ledger = append(ms.ledger, entities.LedgerEntry{
+		ID:        int64(len(ms.ledger) + 1),
+		PaymentID: paymentID,
+		Account:   acc.ID,
+		Amount:    amount,
+		Balance:   acc.Balance,
+		CreatedAt: createdAt,
+	})
+}
+
+func (ms *memoryStorage) LedgerEntries(ctx context.Context, id entities.AccountID) ([]entities.LedgerEntry, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	if _, ok := ms.accounts[id]; !ok {
+		return nil, entities.ErrAccountNotFound
+	}
+
+	entries := []entities.LedgerEntry{}
+	for _, entry := range ms.ledger {
+		if entry.Account == id {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (ms *memoryStorage) BalanceAt(ctx context.Context, id entities.AccountID, entryID int64) (decimal.Decimal, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	balance, ok := ms.initialBalances[id]
+	if !ok {
+		return decimal.Decimal{}, entities.ErrAccountNotFound
+	}
+
+	for _, entry := range ms.ledger {
+		if entry.ID > entryID {
+			break
+		}
+		if entry.Account == id {
+			balance = balance.Add(entry.Amount)
+		}
+	}
+	return balance, nil
+}
+
+// withAvailable returns a copy of account with its available balance at the moment,
+// the caller must hold the lock
+func (ms *memoryStorage) withAvailable(acc *entities.Account) entities.Account {
+	result := *acc
+	result.AvailableBalance = acc.Balance
+	now := time.Now()
+	for _, hold := range ms.holds {
+		if hold.Account == acc.ID && currentHold(hold, now).Status == entities.HoldActive {
+			result.AvailableBalance = result.AvailableBalance.Sub(hold.Amount)
+		}
+	}
+	return result
+}
+
+// currentHold returns a copy of stored hold with its status at specified time
+func currentHold(hold *entities.Hold, now time.Time) entities.Hold {
+	result := *hold
+	if result.Status == entities.HoldActive && !now.Before(result.ExpiresAt) {
+		result.Status = entities.HoldExpired
+	}
+	if hold.CapturedAmount != nil {
+		captured := *hold.CapturedAmount
+		result.CapturedAmount = &captured
+	}
+	return result
+}
+
+func (ms *memoryStorage) CreateHold(ctx context.Context, hold entities.Hold) (*entities.Hold, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if _, ok := ms.holds[hold.ID]; ok {
+		return nil, entities.ErrHoldAlreadyExists
+	}
+
+	sourceAccount, ok := ms.accounts[hold.Account]
+	if !ok {
+		return nil, entities.ErrPaymentSourceNotFound
+	}
+
+	destinationAccount, ok := ms.accounts[hold.ToAccount]
+	if !ok {
+		return nil, entities.ErrPaymentDestinationNotFound
+	}
+
+	if err := entities.CheckPaymentAccounts(*sourceAccount, *destinationAccount); err != nil {
+		return nil, err
+	}
+
+	if sourceAccount.Currency != destinationAccount.Currency {
+		return nil, entities.ErrDifferentCurrencies
+	}
+
+	if ms.withAvailable(sourceAccount).AvailableBalance.LessThan(hold.Amount) {
+		return nil, entities.ErrInsufficientFunds
+	}
+
+	stored := hold
+	stored.Currency = sourceAccount.Currency
+	stored.Status = entities.HoldActive
+	stored.CapturedAmount = nil
+	stored.CreatedAt = time.Now().UTC()
+	stored.ExpiresAt = hold.ExpiresAt.UTC()
+	ms.holds[hold.ID] = &stored
+
+	result := currentHold(&stored, time.Now())
+	return &result, nil
+}
+
+func (ms *memoryStorage) GetHold(ctx context.Context, id uuid.UUID) (*entities.Hold, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	hold, ok := ms.holds[id]
+	if !ok {
+		return nil, entities.ErrHoldNotFound
+	}
+
+	result := currentHold(hold, time.Now())
+	return &result, nil
+}
+
+func (ms *memoryStorage) CaptureHold(ctx context.Context, id uuid.UUID, amount *decimal.Decimal) (*entities.Payment, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	hold, ok := ms.holds[id]
+	if !ok {
+		return nil, entities.ErrHoldNotFound
+	}
+
+	// retried capture gets the payment made by the original one
+	if hold.Status == entities.HoldCaptured {
+		i, ok := ms.paymentIndex[hold.ID]
+		if !ok || (amount != nil && !amount.Equal(*hold.CapturedAmount)) {
+			return nil, entities.ErrHoldNotActive
+		}
+		original := ms.payments[i].payment(hold.Account)
+		return &original, entities.ErrPaymentDuplicate
+	}
+
+	if currentHold(hold, time.Now()).Status != entities.HoldActive {
+		return nil, entities.ErrHoldNotActive
+	}
+
+	captured := hold.Amount
+	if amount != nil {
+		captured = *amount
+	}
+	if !captured.IsPositive() || captured.GreaterThan(hold.Amount) {
+		return nil, entities.ErrWrongCaptureAmount
+	}
+
+	// hold is captured before the payment, so that its funds become available to the payment
+	hold.Status = entities.HoldCaptured
+	hold.CapturedAmount = &captured
+
+	toAccount := hold.ToAccount
+	payment := entities.Payment{
+		ID:        hold.ID,
+		Account:   hold.Account,
+		Amount:    captured,
+		Direction: entities.Outgoing,
+		ToAccount: &toAccount,
+	}
+	stored, err := ms.createPayment(payment, hold.Account, hold.ToAccount)
+	if err != nil {
+		hold.Status = entities.HoldActive
+		hold.CapturedAmount = nil
+		if err == entities.ErrPaymentDuplicate {
+			// unrelated payment has been made with the same ID
+			return nil, entities.ErrPaymentAlreadyDone
+		}
+		return nil, err
+	}
+	return stored, nil
+}
+
+func (ms *memoryStorage) ReleaseHold(ctx context.Context, id uuid.UUID) (*entities.Hold, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	hold, ok := ms.holds[id]
+	if !ok {
+		return nil, entities.ErrHoldNotFound
+	}
+
+	if currentHold(hold, time.Now()).Status != entities.HoldActive {
+		return nil, entities.ErrHoldNotActive
+	}
+
+	hold.Status = entities.HoldReleased
+	result := currentHold(hold, time.Now())
+	return &result, nil
+}
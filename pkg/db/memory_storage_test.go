@@ -0,0 +1,612 @@
+package db_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	mydb "github.com/shirolimit/wallet-service/pkg/db"
+	"github.com/shirolimit/wallet-service/pkg/entities"
+)
+
+func newMemoryStorageWithAccounts(t *testing.T, accounts ...entities.Account) mydb.Storage {
+	storage := mydb.NewMemoryStorage()
+	for _, acc := range accounts {
+		if _, err := storage.CreateAccount(context.TODO(), acc); err != nil {
+			t.Fatalf("Error while creating account: %v", err)
+		}
+	}
+	return storage
+}
+
+func Test_MemoryStorage_CreateAccount(t *testing.T) {
+	acc := entities.Account{ID: "alice", Balance: decimal.New(100, 0), Currency: "USD"}
+	storage := mydb.NewMemoryStorage()
+	created, err := storage.CreateAccount(context.TODO(), acc)
+	if err != nil {
+		t.Fatalf("Error while creating account: %v", err)
+	}
+
+	if _, err := storage.CreateAccount(context.TODO(), acc); err != entities.ErrAccountAlreadyExists {
+		t.Errorf("Expectation failed. Expected error = %v, actual = %v", entities.ErrAccountAlreadyExists, err)
+	}
+
+	got, err := storage.GetAccount(context.TODO(), "alice")
+	if err != nil {
+		t.Fatalf("Error while getting account: %v", err)
+	}
+	expected := acc
+	expected.AvailableBalance = acc.Balance
+	if !reflect.DeepEqual(*got, expected) || !reflect.DeepEqual(*created, expected) {
+		t.Errorf("Expectation failed. Expected account = %v, actual = %v, created = %v", expected, *got, *created)
+	}
+
+	if _, err := storage.GetAccount(context.TODO(), "bob"); err != entities.ErrAccountNotFound {
+		t.Errorf("Expectation failed. Expected error = %v, actual = %v", entities.ErrAccountNotFound, err)
+	}
+}
+
+func Test_MemoryStorage_ListAccounts(t *testing.T) {
+	storage := newMemoryStorageWithAccounts(t,
+		entities.Account{ID: "bob", Balance: decimal.New(300, 0), Currency: "USD"},
+		entities.Account{ID: "alice", Balance: decimal.New(100, 0), Currency: "USD", Owner: "alice"},
+		entities.Account{ID: "alex", Balance: decimal.New(200, 0), Currency: "EUR", Owner: "alex"},
+		entities.Account{ID: "carol", Balance: decimal.New(100, 0), Currency: "USD"},
+	)
+
+	ids := func(query entities.AccountsQuery) []entities.AccountID {
+		var result []entities.AccountID
+		for {
+			page, err := storage.ListAccounts(context.TODO(), query)
+			if err != nil {
+				t.Fatalf("Error while quering accounts list: %v", err)
+			}
+			for _, acc := range page.Accounts {
+				result = append(result, acc.ID)
+			}
+			if page.NextCursor == "" {
+				return result
+			}
+			query.Cursor = page.NextCursor
+		}
+	}
+
+	minBalance, maxBalance := decimal.New(100, 0), decimal.New(200, 0)
+
+	tests := []struct {
+		name  string
+		query entities.AccountsQuery
+		want  []entities.AccountID
+	}{
+		{"all", entities.AccountsQuery{}, []entities.AccountID{"alex", "alice", "bob", "carol"}},
+		{"pages", entities.AccountsQuery{Limit: 3}, []entities.AccountID{"alex", "alice", "bob", "carol"}},
+		{"prefix", entities.AccountsQuery{Limit: 1, IDPrefix: "al"}, []entities.AccountID{"alex", "alice"}},
+		{"owner", entities.AccountsQuery{Owner: "alice"}, []entities.AccountID{"alice"}},
+		{"currency", entities.AccountsQuery{Limit: 1, Currency: "USD"}, []entities.AccountID{"alice", "bob", "carol"}},
+		{"balance_range", entities.AccountsQuery{Limit: 2, MinBalance: &minBalance, MaxBalance: &maxBalance}, []entities.AccountID{"alex", "alice", "carol"}},
+		{"id_desc", entities.AccountsQuery{Limit: 3, Sort: entities.SortByIDDesc}, []entities.AccountID{"carol", "bob", "alice", "alex"}},
+		{"balance", entities.AccountsQuery{Limit: 1, Sort: entities.SortByBalance}, []entities.AccountID{"alice", "carol", "alex", "bob"}},
+		{"balance_desc", entities.AccountsQuery{Limit: 1, Sort: entities.SortByBalanceDesc}, []entities.AccountID{"bob", "alex", "carol", "alice"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("memoryStorage.ListAccounts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := storage.ListAccounts(context.TODO(), entities.AccountsQuery{Cursor: "garbage"}); err != entities.ErrInvalidCursor {
+		t.Errorf("Expectation failed. Expected error = %v, actual = %v", entities.ErrInvalidCursor, err)
+	}
+}
+
+func Test_MemoryStorage_CreatePayment(t *testing.T) {
+	storage := newMemoryStorageWithAccounts(t,
+		entities.Account{ID: "alice", Balance: decimal.New(100, 0), Currency: "USD"},
+		entities.Account{ID: "bob", Balance: decimal.New(200, 0), Currency: "USD"},
+		entities.Account{ID: "eve", Balance: decimal.New(200, 0), Currency: "EUR"},
+	)
+
+	payment := func(from, to string, amount int64) entities.Payment {
+		toAccount := entities.AccountID(to)
+		return entities.Payment{
+			ID:        uuid.New(),
+			Account:   entities.AccountID(from),
+			Amount:    decimal.New(amount, 0),
+			ToAccount: &toAccount,
+			Direction: entities.Outgoing,
+		}
+	}
+
+	done := payment("alice", "bob", 30)
+	changed := done
+	changed.Amount = decimal.New(10, 0)
+
+	tests := []struct {
+		name    string
+		payment entities.Payment
+		wantErr error
+	}{
+		{"unknown_source", payment("mallory", "bob", 10), entities.ErrPaymentSourceNotFound},
+		{"unknown_destination", payment("alice", "mallory", 10), entities.ErrPaymentDestinationNotFound},
+		{"different_currencies", payment("alice", "eve", 10), entities.ErrDifferentCurrencies},
+		{"insufficient_funds", payment("alice", "bob", 101), entities.ErrInsufficientFunds},
+		{"success", done, nil},
+		{"duplicate", done, entities.ErrPaymentDuplicate},
+		{"duplicate_with_different_data", changed, entities.ErrPaymentAlreadyDone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := storage.CreatePayment(context.TODO(), tt.payment); err != tt.wantErr {
+				t.Errorf("memoryStorage.CreatePayment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	alice, _ := storage.GetAccount(context.TODO(), "alice")
+	bob, _ := storage.GetAccount(context.TODO(), "bob")
+	if !alice.Balance.Equal(decimal.New(70, 0)) || !bob.Balance.Equal(decimal.New(230, 0)) {
+		t.Errorf("Expectation failed. Actual balances: alice = %v, bob = %v", alice.Balance, bob.Balance)
+	}
+
+	page, err := storage.PaymentsByAccount(context.TODO(), "bob", entities.PaymentsQuery{})
+	if err != nil {
+		t.Fatalf("Error while quering payments: %v", err)
+	}
+	payments := page.Payments
+	if len(payments) != 1 || payments[0].Direction != entities.Incoming || *payments[0].FromAccount != "alice" {
+		t.Errorf("Expectation failed. Actual payments = %v", payments)
+	}
+	if payments[0].CreatedAt.IsZero() || payments[0].CreatedAt.Location() != time.UTC || payments[0].Status != entities.Completed {
+		t.Errorf("Expectation failed. Actual payment = %v", payments[0])
+	}
+}
+
+func Test_MemoryStorage_UpdateAccountStatus(t *testing.T) {
+	storage := newMemoryStorageWithAccounts(t,
+		entities.Account{ID: "alice", Balance: decimal.New(100, 0), Currency: "USD"},
+		entities.Account{ID: "bob", Balance: decimal.New(0, 0), Currency: "USD"},
+		entities.Account{ID: "carol", Balance: decimal.New(100, 0), Currency: "USD"},
+	)
+
+	payment := func(from, to string) entities.Payment {
+		toAccount := entities.AccountID(to)
+		return entities.Payment{
+			ID:        uuid.New(),
+			Account:   entities.AccountID(from),
+			Amount:    decimal.New(10, 0),
+			ToAccount: &toAccount,
+			Direction: entities.Outgoing,
+		}
+	}
+
+	// steps are run in order, each one depends on the state left by previous ones
+	tests := []struct {
+		name       string
+		account    entities.AccountID
+		status     entities.AccountStatus
+		payment    *entities.Payment
+		wantErr    error
+		wantStatus entities.AccountStatus
+	}{
+		{"unknown_account", "mallory", entities.Frozen, nil, entities.ErrAccountNotFound, entities.Active},
+		{"freeze", "alice", entities.Frozen, nil, nil, entities.Frozen},
+		{"debit_frozen", "alice", entities.Frozen, paymentPtr(payment("alice", "bob")), entities.ErrAccountFrozen, entities.Frozen},
+		{"credit_frozen", "alice", entities.Frozen, paymentPtr(payment("carol", "alice")), nil, entities.Frozen},
+		{"unfreeze", "alice", entities.Active, nil, nil, entities.Active},
+		{"close_not_empty", "alice", entities.Closed, nil, entities.ErrAccountNotEmpty, entities.Active},
+		{"close", "bob", entities.Closed, nil, nil, entities.Closed},
+		{"credit_closed", "bob", entities.Closed, paymentPtr(payment("alice", "bob")), entities.ErrAccountClosed, entities.Closed},
+		{"reopen", "bob", entities.Active, nil, entities.ErrAccountClosed, entities.Closed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.payment != nil {
+				_, err = storage.CreatePayment(context.TODO(), *tt.payment)
+			} else {
+				_, err = storage.UpdateAccountStatus(context.TODO(), tt.account, tt.status)
+			}
+			if err != tt.wantErr {
+				t.Errorf("memoryStorage error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if acc, err := storage.GetAccount(context.TODO(), tt.account); err == nil && acc.Status != tt.wantStatus {
+				t.Errorf("Expectation failed. Expected status = %v, actual = %v", tt.wantStatus, acc.Status)
+			}
+		})
+	}
+}
+
+func paymentPtr(payment entities.Payment) *entities.Payment {
+	return &payment
+}
+
+func Test_MemoryStorage_Ledger(t *testing.T) {
+	storage := newMemoryStorageWithAccounts(t,
+		entities.Account{ID: "alice", Balance: decimal.New(100, 0), Currency: "USD"},
+		entities.Account{ID: "bob", Balance: decimal.New(200, 0), Currency: "USD"},
+	)
+
+	for _, amount := range []int64{10, 20} {
+		toAccount := entities.AccountID("bob")
+		payment := entities.Payment{
+			ID:        uuid.New(),
+			Account:   "alice",
+			Amount:    decimal.New(amount, 0),
+			ToAccount: &toAccount,
+			Direction: entities.Outgoing,
+		}
+		if _, err := storage.CreatePayment(context.TODO(), payment); err != nil {
+			t.Fatalf("Error while creating payment: %v", err)
+		}
+	}
+
+	entries, err := storage.LedgerEntries(context.TODO(), "bob")
+	if err != nil {
+		t.Fatalf("Error while quering ledger entries: %v", err)
+	}
+	if len(entries) != 2 || !entries[0].Amount.Equal(decimal.New(10, 0)) || !entries[1].Balance.Equal(decimal.New(230, 0)) {
+		t.Errorf("Expectation failed. Actual entries = %v", entries)
+	}
+
+	tests := []struct {
+		name    string
+		account entities.AccountID
+		entryID int64
+		want    decimal.Decimal
+		wantErr error
+	}{
+		{"initial_balance", "alice", 0, decimal.New(100, 0), nil},
+		{"after_first_payment", "alice", 1, decimal.New(90, 0), nil},
+		{"entry_of_other_account", "alice", 2, decimal.New(90, 0), nil},
+		{"current_balance", "alice", 4, decimal.New(70, 0), nil},
+		{"after_last_entry", "bob", 100, decimal.New(230, 0), nil},
+		{"unknown_account", "mallory", 1, decimal.Decimal{}, entities.ErrAccountNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := storage.BalanceAt(context.TODO(), tt.account, tt.entryID)
+			if err != tt.wantErr {
+				t.Errorf("memoryStorage.BalanceAt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("memoryStorage.BalanceAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_MemoryStorage_Holds(t *testing.T) {
+	storage := newMemoryStorageWithAccounts(t,
+		entities.Account{ID: "alice", Balance: decimal.New(100, 0), Currency: "USD"},
+		entities.Account{ID: "bob", Balance: decimal.New(0, 0), Currency: "USD"},
+	)
+
+	hold := func(amount int64, ttl time.Duration) entities.Hold {
+		return entities.Hold{
+			ID:        uuid.New(),
+			Account:   "alice",
+			ToAccount: "bob",
+			Amount:    decimal.New(amount, 0),
+			ExpiresAt: time.Now().Add(ttl),
+		}
+	}
+	createHold := func(hold entities.Hold) func() error {
+		return func() error {
+			_, err := storage.CreateHold(context.TODO(), hold)
+			return err
+		}
+	}
+	captureHold := func(id uuid.UUID, amount *decimal.Decimal) func() error {
+		return func() error {
+			_, err := storage.CaptureHold(context.TODO(), id, amount)
+			return err
+		}
+	}
+	releaseHold := func(id uuid.UUID) func() error {
+		return func() error {
+			_, err := storage.ReleaseHold(context.TODO(), id)
+			return err
+		}
+	}
+
+	captured, expired, released := hold(60, time.Hour), hold(30, -time.Second), hold(20, time.Hour)
+	toAccount := entities.AccountID("bob")
+	payment := entities.Payment{
+		ID:        uuid.New(),
+		Account:   "alice",
+		Amount:    decimal.New(50, 0),
+		ToAccount: &toAccount,
+		Direction: entities.Outgoing,
+	}
+
+	// steps are run in order, each one depends on the state left by previous ones
+	tests := []struct {
+		name          string
+		run           func() error
+		wantErr       error
+		wantAvailable decimal.Decimal
+	}{
+		{"create", createHold(captured), nil, decimal.New(40, 0)},
+		{"duplicate", createHold(captured), entities.ErrHoldAlreadyExists, decimal.New(40, 0)},
+		{"pay_held_funds", func() error {
+			_, err := storage.CreatePayment(context.TODO(), payment)
+			return err
+		}, entities.ErrInsufficientFunds, decimal.New(40, 0)},
+		{"hold_held_funds", createHold(hold(50, time.Hour)), entities.ErrInsufficientFunds, decimal.New(40, 0)},
+		{"capture_too_much", captureHold(captured.ID, decimalPtr(decimal.New(70, 0))), entities.ErrWrongCaptureAmount, decimal.New(40, 0)},
+		{"capture_partially", captureHold(captured.ID, decimalPtr(decimal.New(45, 0))), nil, decimal.New(55, 0)},
+		{"capture_retry", captureHold(captured.ID, nil), entities.ErrPaymentDuplicate, decimal.New(55, 0)},
+		{"capture_retry_same_amount", captureHold(captured.ID, decimalPtr(decimal.New(45, 0))), entities.ErrPaymentDuplicate, decimal.New(55, 0)},
+		{"capture_other_amount", captureHold(captured.ID, decimalPtr(decimal.New(40, 0))), entities.ErrHoldNotActive, decimal.New(55, 0)},
+		{"create_expired", createHold(expired), nil, decimal.New(55, 0)},
+		{"capture_expired", captureHold(expired.ID, nil), entities.ErrHoldNotActive, decimal.New(55, 0)},
+		{"create_released", createHold(released), nil, decimal.New(35, 0)},
+		{"release", releaseHold(released.ID), nil, decimal.New(55, 0)},
+		{"release_twice", releaseHold(released.ID), entities.ErrHoldNotActive, decimal.New(55, 0)},
+		{"unknown_hold", releaseHold(uuid.New()), entities.ErrHoldNotFound, decimal.New(55, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err != tt.wantErr {
+				t.Errorf("memoryStorage error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			acc, err := storage.GetAccount(context.TODO(), "alice")
+			if err != nil {
+				t.Fatalf("Error while getting account: %v", err)
+			}
+			if !acc.AvailableBalance.Equal(tt.wantAvailable) {
+				t.Errorf("Expectation failed. Expected available balance = %v, actual = %v", tt.wantAvailable, acc.AvailableBalance)
+			}
+		})
+	}
+
+	wantStatuses := map[uuid.UUID]entities.HoldStatus{
+		captured.ID: entities.HoldCaptured,
+		expired.ID:  entities.HoldExpired,
+		released.ID: entities.HoldReleased,
+	}
+	for id, want := range wantStatuses {
+		got, err := storage.GetHold(context.TODO(), id)
+		if err != nil {
+			t.Fatalf("Error while getting hold: %v", err)
+		}
+		if got.Status != want {
+			t.Errorf("Expectation failed. Expected hold status = %v, actual = %v", want, got.Status)
+		}
+	}
+}
+
+func Test_MemoryStorage_Refunds(t *testing.T) {
+	storage := newMemoryStorageWithAccounts(t,
+		entities.Account{ID: "alice", Balance: decimal.New(100, 0), Currency: "USD"},
+		entities.Account{ID: "bob", Balance: decimal.New(0, 0), Currency: "USD"},
+	)
+
+	toAccount := entities.AccountID("bob")
+	payment := entities.Payment{
+		ID:        uuid.New(),
+		Account:   "alice",
+		Amount:    decimal.New(60, 0),
+		ToAccount: &toAccount,
+		Direction: entities.Outgoing,
+	}
+	if _, err := storage.CreatePayment(context.TODO(), payment); err != nil {
+		t.Fatalf("Error while creating payment: %v", err)
+	}
+
+	refund := func(id uuid.UUID, account entities.AccountID, refundOf uuid.UUID, amount int64) entities.Payment {
+		return entities.Payment{ID: id, Account: account, RefundOf: &refundOf, Amount: decimal.New(amount, 0)}
+	}
+	partial, rest := uuid.New(), uuid.New()
+
+	// steps are run in order, each one depends on the state left by previous ones
+	tests := []struct {
+		name        string
+		refund      entities.Payment
+		wantErr     error
+		wantAmount  decimal.Decimal
+		wantBalance decimal.Decimal
+	}{
+		{"unknown_payment", refund(uuid.New(), "bob", uuid.New(), 10), entities.ErrPaymentNotFound, decimal.Zero, decimal.New(40, 0)},
+		{"not_recipient", refund(uuid.New(), "alice", payment.ID, 10), entities.ErrPaymentNotFound, decimal.Zero, decimal.New(40, 0)},
+		{"too_much", refund(uuid.New(), "bob", payment.ID, 70), entities.ErrRefundExceedsPayment, decimal.Zero, decimal.New(40, 0)},
+		{"partial", refund(partial, "bob", payment.ID, 25), nil, decimal.New(25, 0), decimal.New(65, 0)},
+		{"replay", refund(partial, "bob", payment.ID, 25), entities.ErrPaymentDuplicate, decimal.New(25, 0), decimal.New(65, 0)},
+		{"refund_of_refund", refund(uuid.New(), "alice", partial, 5), entities.ErrPaymentNotRefundable, decimal.Zero, decimal.New(65, 0)},
+		{"exceeds_rest", refund(uuid.New(), "bob", payment.ID, 40), entities.ErrRefundExceedsPayment, decimal.Zero, decimal.New(65, 0)},
+		{"rest", refund(rest, "bob", payment.ID, 0), nil, decimal.New(35, 0), decimal.New(100, 0)},
+		{"fully_refunded", refund(uuid.New(), "bob", payment.ID, 0), entities.ErrRefundExceedsPayment, decimal.Zero, decimal.New(100, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored, err := storage.CreateRefund(context.TODO(), tt.refund)
+			if err != tt.wantErr {
+				t.Errorf("memoryStorage.CreateRefund() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if stored != nil {
+				if !stored.Amount.Equal(tt.wantAmount) || stored.Type != entities.Refund ||
+					stored.RefundOf == nil || *stored.RefundOf != *tt.refund.RefundOf {
+					t.Errorf("Expectation failed. Actual refund = %v", stored)
+				}
+			}
+
+			acc, err := storage.GetAccount(context.TODO(), "alice")
+			if err != nil {
+				t.Fatalf("Error while getting account: %v", err)
+			}
+			if !acc.Balance.Equal(tt.wantBalance) {
+				t.Errorf("Expectation failed. Expected balance = %v, actual = %v", tt.wantBalance, acc.Balance)
+			}
+		})
+	}
+
+	page, err := storage.PaymentsByAccount(context.TODO(), "alice", entities.PaymentsQuery{})
+	if err != nil {
+		t.Fatalf("Error while getting payments: %v", err)
+	}
+	if len(page.Payments) != 3 {
+		t.Fatalf("Expectation failed. Expected 3 payments, actual = %v", page.Payments)
+	}
+	for _, p := range page.Payments[1:] {
+		if p.Type != entities.Refund || p.Direction != entities.Incoming || *p.RefundOf != payment.ID {
+			t.Errorf("Expectation failed. Expected incoming refund of %v, actual = %v", payment.ID, p)
+		}
+	}
+}
+
+func Test_MemoryStorage_GetPayment(t *testing.T) {
+	storage := newMemoryStorageWithAccounts(t,
+		entities.Account{ID: "alice", Balance: decimal.New(100, 0), Currency: "USD"},
+		entities.Account{ID: "bob", Balance: decimal.New(0, 0), Currency: "USD"},
+	)
+
+	toAccount := entities.AccountID("bob")
+	payment := entities.Payment{
+		ID:        uuid.New(),
+		Account:   "alice",
+		Amount:    decimal.New(60, 0),
+		ToAccount: &toAccount,
+		Direction: entities.Outgoing,
+	}
+	stored, err := storage.CreatePayment(context.TODO(), payment)
+	if err != nil {
+		t.Fatalf("Error while creating payment: %v", err)
+	}
+
+	record, err := storage.GetPayment(context.TODO(), payment.ID)
+	if err != nil {
+		t.Fatalf("memoryStorage.GetPayment() error = %v", err)
+	}
+	want := entities.PaymentRecord{
+		ID:          payment.ID,
+		Source:      "alice",
+		Destination: "bob",
+		Amount:      decimal.New(60, 0),
+		Currency:    "USD",
+		CreatedAt:   stored.CreatedAt,
+		Status:      entities.Completed,
+		Type:        entities.RegularPayment,
+	}
+	if !reflect.DeepEqual(*record, want) {
+		t.Errorf("memoryStorage.GetPayment() = %v, want %v", *record, want)
+	}
+
+	if _, err = storage.GetPayment(context.TODO(), uuid.New()); err != entities.ErrPaymentNotFound {
+		t.Errorf("memoryStorage.GetPayment() error = %v, wantErr %v", err, entities.ErrPaymentNotFound)
+	}
+}
+
+func Test_MemoryStorage_ConcurrentPayments(t *testing.T) {
+	storage := newMemoryStorageWithAccounts(t,
+		entities.Account{ID: "alice", Balance: decimal.New(100, 0), Currency: "USD"},
+		entities.Account{ID: "bob", Balance: decimal.New(0, 0), Currency: "USD"},
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 150; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			toAccount := entities.AccountID("bob")
+			storage.CreatePayment(context.TODO(), entities.Payment{
+				ID:        uuid.New(),
+				Account:   "alice",
+				Amount:    decimal.New(1, 0),
+				ToAccount: &toAccount,
+				Direction: entities.Outgoing,
+			})
+		}()
+	}
+	wg.Wait()
+
+	alice, _ := storage.GetAccount(context.TODO(), "alice")
+	bob, _ := storage.GetAccount(context.TODO(), "bob")
+	if !alice.Balance.IsZero() || !bob.Balance.Equal(decimal.New(100, 0)) {
+		t.Errorf("Expectation failed. Actual balances: alice = %v, bob = %v", alice.Balance, bob.Balance)
+	}
+}
+
+func Test_MemoryStorage_PaymentsByAccountPagination(t *testing.T) {
+	storage := newMemoryStorageWithAccounts(t,
+		entities.Account{ID: "alice", Balance: decimal.New(100, 0), Currency: "USD"},
+		entities.Account{ID: "bob", Balance: decimal.New(100, 0), Currency: "USD"},
+		entities.Account{ID: "carol", Balance: decimal.New(100, 0), Currency: "USD"},
+	)
+
+	transfers := []struct {
+		from, to string
+		amount   int64
+	}{
+		{"alice", "bob", 10},
+		{"bob", "alice", 20},
+		{"alice", "carol", 30},
+		{"carol", "alice", 40},
+		{"alice", "bob", 50},
+	}
+	for _, tr := range transfers {
+		toAccount := entities.AccountID(tr.to)
+		_, err := storage.CreatePayment(context.TODO(), entities.Payment{
+			ID:        uuid.New(),
+			Account:   entities.AccountID(tr.from),
+			Amount:    decimal.New(tr.amount, 0),
+			ToAccount: &toAccount,
+			Direction: entities.Outgoing,
+		})
+		if err != nil {
+			t.Fatalf("Error while creating payment: %v", err)
+		}
+	}
+
+	amounts := func(query entities.PaymentsQuery) []int64 {
+		var result []int64
+		for {
+			page, err := storage.PaymentsByAccount(context.TODO(), "alice", query)
+			if err != nil {
+				t.Fatalf("Error while quering payments: %v", err)
+			}
+			for _, p := range page.Payments {
+				result = append(result, p.Amount.IntPart())
+			}
+			if page.NextCursor == "" {
+				return result
+			}
+			query.Cursor = page.NextCursor
+		}
+	}
+
+	outgoing := entities.Outgoing
+	carol := entities.AccountID("carol")
+	minAmount, maxAmount := decimal.New(20, 0), decimal.New(40, 0)
+
+	tests := []struct {
+		name  string
+		query entities.PaymentsQuery
+		want  []int64
+	}{
+		{"all", entities.PaymentsQuery{}, []int64{10, 20, 30, 40, 50}},
+		{"pages", entities.PaymentsQuery{Limit: 2}, []int64{10, 20, 30, 40, 50}},
+		{"direction", entities.PaymentsQuery{Limit: 1, Direction: &outgoing}, []int64{10, 30, 50}},
+		{"counterparty", entities.PaymentsQuery{Limit: 1, Counterparty: &carol}, []int64{30, 40}},
+		{"amount_range", entities.PaymentsQuery{Limit: 2, MinAmount: &minAmount, MaxAmount: &maxAmount}, []int64{20, 30, 40}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := amounts(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("memoryStorage.PaymentsByAccount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := storage.PaymentsByAccount(context.TODO(), "alice", entities.PaymentsQuery{Cursor: "garbage"}); err != entities.ErrInvalidCursor {
+		t.Errorf("Expectation failed. Expected error = %v, actual = %v", entities.ErrInvalidCursor, err)
+	}
+}
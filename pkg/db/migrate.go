@@ -0,0 +1,256 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationFileName matches names like 0001_initial.up.sql
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// migrationsLockID is a key of advisory lock that prevents concurrent migrations
+const migrationsLockID = 7123440215
+
+// Migration is a numbered schema change embedded in the binary
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus describes whether migration has been applied to the database
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator applies embedded schema migrations to Postgres database.
+// Applied versions are tracked in schema_migrations table
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator creates new Migrator for specified sql.DB instance
+func NewMigrator(db *sql.DB) *Migrator {
+	migrations, err := loadMigrations()
+	if err != nil {
+		panic(fmt.Sprintln(err))
+	}
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}
+}
+
+// LatestVersion returns the version of the newest embedded migration
+func (m *Migrator) LatestVersion() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the version of the last applied migration, 0 means empty database
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	if err := m.createVersionTable(ctx, m.db); err != nil {
+		return 0, err
+	}
+	return m.currentVersion(ctx, m.db)
+}
+
+// Up applies all pending migrations in a single transaction
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.inLockedTransaction(ctx, func(tx *sql.Tx) error {
+		version, err := m.currentVersion(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if mig.Version <= version {
+				continue
+			}
+
+			if _, err = tx.ExecContext(ctx, mig.Up); err != nil {
+				return fmt.Errorf("migration %d_%s failed: %v", mig.Version, mig.Name, err)
+			}
+
+			_, err = tx.ExecContext(
+				ctx,
+				"insert into schema_migrations (version, name) values ($1, $2);",
+				mig.Version, mig.Name,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down rolls back the last applied migration
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.inLockedTransaction(ctx, func(tx *sql.Tx) error {
+		version, err := m.currentVersion(ctx, tx)
+		if err != nil || version == 0 {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if mig.Version != version {
+				continue
+			}
+
+			if _, err = tx.ExecContext(ctx, mig.Down); err != nil {
+				return fmt.Errorf("rollback of migration %d_%s failed: %v", mig.Version, mig.Name, err)
+			}
+
+			_, err = tx.ExecContext(ctx, "delete from schema_migrations where version = $1;", mig.Version)
+			return err
+		}
+		return fmt.Errorf("migration %d is applied but unknown to this binary", version)
+	})
+}
+
+// Status returns all embedded migrations with their application time
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	if err := m.createVersionTable(ctx, m.db); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, "select version, applied_at from schema_migrations;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		status := MigrationStatus{Migration: mig}
+		if appliedAt, ok := applied[mig.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// inLockedTransaction runs fn in a transaction holding migrations advisory lock,
+// so that several service instances started with auto migration don't interfere
+func (m *Migrator) inLockedTransaction(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, "select pg_advisory_xact_lock($1);", migrationsLockID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err = m.createVersionTable(ctx, tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// execQueryer is a common interface of sql.DB and sql.Tx
+type execQueryer interface {
+	queryer
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (m *Migrator) createVersionTable(ctx context.Context, q execQueryer) error {
+	_, err := q.ExecContext(
+		ctx,
+		`create table if not exists schema_migrations (
+			version integer primary key,
+			name varchar(128) not null,
+			applied_at timestamptz not null default now()
+		);`,
+	)
+	return err
+}
+
+func (m *Migrator) currentVersion(ctx context.Context, q queryer) (int, error) {
+	var version int
+	err := q.QueryRowContext(ctx, "select coalesce(max(version), 0) from schema_migrations;").Scan(&version)
+	return version, err
+}
+
+// loadMigrations reads embedded migration files and returns them ordered by version
+func loadMigrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file name %s", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		data, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		} else if mig.Name != match[2] {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", mig.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			mig.Up = string(data)
+		} else {
+			mig.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
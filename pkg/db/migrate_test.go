@@ -0,0 +1,95 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	mydb "github.com/shirolimit/wallet-service/pkg/db"
+)
+
+func Test_Migrator_Up(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' while opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	migrator := mydb.NewMigrator(db)
+	if migrator.LatestVersion() < 1 {
+		t.Fatalf("Embedded migrations are not found")
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("select pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("create table if not exists schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("select coalesce\\(max\\(version\\), 0\\) from schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(0))
+	for version := 1; version <= migrator.LatestVersion(); version++ {
+		mock.ExpectExec(".+").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("insert into schema_migrations").
+			WithArgs(version, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	if err := migrator.Up(context.TODO()); err != nil {
+		t.Errorf("Error while applying migrations: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func Test_Migrator_UpToDate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' while opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	migrator := mydb.NewMigrator(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("select pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("create table if not exists schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("select coalesce\\(max\\(version\\), 0\\) from schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(migrator.LatestVersion()))
+	mock.ExpectCommit()
+
+	if err := migrator.Up(context.TODO()); err != nil {
+		t.Errorf("Error while applying migrations: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func Test_Migrator_Down(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' while opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	migrator := mydb.NewMigrator(db)
+	latest := migrator.LatestVersion()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("select pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("create table if not exists schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("select coalesce\\(max\\(version\\), 0\\) from schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(latest))
+	mock.ExpectExec(".+").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("delete from schema_migrations").
+		WithArgs(latest).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := migrator.Down(context.TODO()); err != nil {
+		t.Errorf("Error while rolling back migration: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,3 @@
+drop table payments;
+
+drop table accounts;
@@ -1,4 +1,6 @@
-create table accounts (
+-- tables could have been created by hand before migrations were introduced
+
+create table if not exists accounts (
   id serial primary key,
   account_id varchar(128) unique,
   currency varchar(32) not null,
@@ -7,7 +9,7 @@ create table accounts (
   constraint balance_non_negative check (balance >= 0.0)
 );
 
-create table payments (
+create table if not exists payments (
   id uuid primary key,
   source_id integer not null,
   destination_id integer not null,
@@ -21,4 +23,4 @@ create table payments (
     references accounts (id) match simple
     on update no action
     on delete no action
-)
\ No newline at end of file
+);
@@ -0,0 +1,5 @@
+drop index payments_destination_created_idx;
+drop index payments_source_created_idx;
+
+alter table payments drop column status;
+alter table payments drop column created_at;
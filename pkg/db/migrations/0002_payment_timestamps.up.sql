@@ -0,0 +1,6 @@
+-- payments made before this migration get the time of migration
+alter table payments add column created_at timestamptz not null default now();
+alter table payments add column status varchar(16) not null default 'completed';
+
+create index payments_source_created_idx on payments (source_id, created_at, id);
+create index payments_destination_created_idx on payments (destination_id, created_at, id);
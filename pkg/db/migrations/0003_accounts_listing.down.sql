@@ -0,0 +1,2 @@
+drop index accounts_balance_idx;
+drop index accounts_account_id_c_idx;
@@ -0,0 +1,3 @@
+-- accounts are listed in "C" collation order, so that it matches byte order of IDs
+create index accounts_account_id_c_idx on accounts (account_id collate "C");
+create index accounts_balance_idx on accounts (balance, account_id collate "C");
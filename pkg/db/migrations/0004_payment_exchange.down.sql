@@ -0,0 +1,3 @@
+alter table payments drop column rate_timestamp;
+alter table payments drop column exchange_rate;
+alter table payments drop column destination_amount;
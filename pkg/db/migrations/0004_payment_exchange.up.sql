@@ -0,0 +1,4 @@
+-- exchange columns are null for payments between accounts with the same currency
+alter table payments add column destination_amount numeric;
+alter table payments add column exchange_rate numeric;
+alter table payments add column rate_timestamp timestamptz;
@@ -0,0 +1,2 @@
+-- subject of authenticated principal owning the account, null for accounts created without authentication
+alter table accounts add column owner varchar(128);
@@ -0,0 +1,2 @@
+alter table accounts drop constraint account_status_valid;
+alter table accounts drop column status;
@@ -0,0 +1,3 @@
+-- lifecycle state of the account, see entities.AccountStatus
+alter table accounts add column status varchar(16) not null default 'active';
+alter table accounts add constraint account_status_valid check (status in ('active', 'frozen', 'closed'));
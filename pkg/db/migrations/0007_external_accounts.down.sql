@@ -0,0 +1,2 @@
+alter table accounts drop constraint balance_non_negative;
+alter table accounts add constraint balance_non_negative check (balance >= 0.0);
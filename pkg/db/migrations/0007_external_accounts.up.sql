@@ -0,0 +1,3 @@
+-- system accounts of external funding sources go negative by the amount deposited into the system
+alter table accounts drop constraint balance_non_negative;
+alter table accounts add constraint balance_non_negative check (balance >= 0.0 or account_id like 'external:%');
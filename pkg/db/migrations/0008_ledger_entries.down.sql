@@ -0,0 +1,4 @@
+drop table ledger_entries;
+drop function ledger_entries_check_balance();
+drop function ledger_entries_append_only();
+alter table accounts drop column initial_balance;
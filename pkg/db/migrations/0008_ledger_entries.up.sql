@@ -0,0 +1,76 @@
+-- balance of account before any payments, the same as balance of accounts created after this migration.
+-- For existing accounts it is derived from their balances and payment history, so any drift between them
+-- is absorbed into initial balance and is not reported by account checks of reconciliation
+alter table accounts add column initial_balance numeric;
+update accounts as a set initial_balance = a.balance
+  - coalesce((select sum(coalesce(p.destination_amount, p.amount)) from payments as p where p.destination_id = a.id), 0)
+  + coalesce((select sum(p.amount) from payments as p where p.source_id = a.id), 0);
+alter table accounts alter column initial_balance set not null;
+
+-- append-only double-entry ledger: each payment has a debit entry of source account
+-- and a credit entry of destination account, balance is the balance of account after the entry
+create table ledger_entries (
+  id bigserial primary key,
+  payment_id uuid not null,
+  account_id integer not null,
+  amount numeric not null,
+  balance numeric not null,
+  created_at timestamptz not null default now(),
+
+  constraint ledger_entries_amount_non_zero check (amount <> 0),
+  constraint ledger_entries_payment_fk foreign key (payment_id)
+    references payments (id) match simple
+    on update no action
+    on delete no action,
+  constraint ledger_entries_account_fk foreign key (account_id)
+    references accounts (id) match simple
+    on update no action
+    on delete no action
+);
+
+create index ledger_entries_account_idx on ledger_entries (account_id, id);
+
+-- entries of already made payments in the order they were made
+insert into ledger_entries (payment_id, account_id, amount, balance, created_at)
+select e.payment_id, e.account_id, e.amount,
+  a.initial_balance + sum(e.amount) over (partition by e.account_id order by e.created_at, e.payment_id),
+  e.created_at
+from (
+  select id as payment_id, source_id as account_id, -amount as amount, created_at from payments
+  union all
+  select id, destination_id, coalesce(destination_amount, amount), created_at from payments
+) as e
+  join accounts as a on a.id = e.account_id
+order by e.created_at, e.payment_id, e.amount;
+
+create function ledger_entries_append_only() returns trigger as $$
+begin
+  raise exception 'ledger entries are append-only';
+end;
+$$ language plpgsql;
+
+create trigger ledger_entries_append_only before update or delete on ledger_entries
+  for each row execute procedure ledger_entries_append_only();
+
+-- cached balance of account must be equal to the balance of its previous entry plus amount
+create function ledger_entries_check_balance() returns trigger as $$
+declare
+  previous numeric;
+  cached numeric;
+begin
+  select balance into previous from ledger_entries where account_id = new.account_id order by id desc limit 1;
+  if not found then
+    select initial_balance into previous from accounts where id = new.account_id;
+  end if;
+
+  select balance into cached from accounts where id = new.account_id;
+  if new.balance <> previous + new.amount or new.balance <> cached then
+    raise exception 'ledger entry does not match balance of account %', new.account_id
+      using errcode = 'check_violation';
+  end if;
+  return new;
+end;
+$$ language plpgsql;
+
+create trigger ledger_entries_check_balance before insert on ledger_entries
+  for each row execute procedure ledger_entries_check_balance();
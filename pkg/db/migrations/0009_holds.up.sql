@@ -0,0 +1,26 @@
+-- funds of account reserved for a future payment to destination account.
+-- Holds past expires_at keep 'active' status but no longer reserve funds
+create table holds (
+  id uuid primary key,
+  account_id integer not null,
+  destination_id integer not null,
+  amount numeric not null,
+  captured_amount numeric,
+  status varchar(16) not null default 'active',
+  created_at timestamptz not null default now(),
+  expires_at timestamptz not null,
+
+  constraint holds_amount_positive check (amount > 0),
+  constraint holds_captured_amount_valid check (captured_amount > 0 and captured_amount <= amount),
+  constraint hold_status_valid check (status in ('active', 'captured', 'released')),
+  constraint holds_account_fk foreign key (account_id)
+    references accounts (id) match simple
+    on update no action
+    on delete no action,
+  constraint holds_destination_fk foreign key (destination_id)
+    references accounts (id) match simple
+    on update no action
+    on delete no action
+);
+
+create index holds_active_idx on holds (account_id, expires_at) where status = 'active';
@@ -0,0 +1,5 @@
+drop index payments_refund_of_idx;
+alter table payments drop constraint payment_refund_of_valid;
+alter table payments drop constraint payment_type_valid;
+alter table payments drop column refund_of;
+alter table payments drop column type;
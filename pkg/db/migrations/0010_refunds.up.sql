@@ -0,0 +1,7 @@
+-- kind of the payment, see entities.PaymentType. Refunds reference refunded payment
+alter table payments add column type varchar(16) not null default 'payment';
+alter table payments add column refund_of uuid references payments (id);
+alter table payments add constraint payment_type_valid check (type in ('payment', 'refund'));
+alter table payments add constraint payment_refund_of_valid check ((type = 'refund') = (refund_of is not null));
+
+create index payments_refund_of_idx on payments (refund_of) where refund_of is not null;
@@ -4,17 +4,43 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 
 	"github.com/lib/pq"
 	"github.com/shirolimit/wallet-service/pkg/entities"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	// txMaxAttempts limits the number of attempts to run transaction
+	// that fails because of serialization failures or deadlocks
+	txMaxAttempts = 5
+
+	// txRetryBackoff is a delay before the first retry of transaction, it is doubled on each next retry
+	txRetryBackoff = 10 * time.Millisecond
 )
 
 // pgStorage is a Storage implementation that uses Postgres
 type pgStorage struct {
-	db *sql.DB
+	db     *sql.DB
+	tracer trace.Tracer
+}
+
+// PgOption is a function that configures Postgres storage
+type PgOption func(*pgStorage)
+
+// WithTracer makes Postgres storage start spans of SQL queries with specified tracer
+func WithTracer(tracer trace.Tracer) PgOption {
+	return func(ps *pgStorage) {
+		ps.tracer = tracer
+	}
 }
 
 // pgAccount is a helper struct for working with Account entity
@@ -22,61 +48,167 @@ type pgStorage struct {
 type pgAccount struct {
 	account    entities.Account
 	internalID int64
+	status     string
+}
+
+// parseStatus converts selected status column into account status
+func (acc *pgAccount) parseStatus() (err error) {
+	acc.account.Status, err = entities.ParseAccountStatus(acc.status)
+	return
 }
 
 // balanceUpdateHelper is a tiny struct to simplify balance updates
 type balanceUpdateHelper struct {
+	accountID         entities.AccountID
 	internalAccountID int64
 	diff              decimal.Decimal
 }
 
 type getPaymentsHelper struct {
-	id            uuid.UUID
-	source        entities.AccountID
-	destination   entities.AccountID
-	sourceID      int64
-	destinationID int64
-	amount        decimal.Decimal
+	id          uuid.UUID
+	source      entities.AccountID
+	destination entities.AccountID
+	amount      decimal.Decimal
+	createdAt   time.Time
+	status      string
+	paymentType string
+	refundOf    *uuid.UUID
+
+	// exchange columns are null for payments without currency exchange
+	sourceCurrency      string
+	destinationCurrency string
+	destinationAmount   decimal.NullDecimal
+	rate                decimal.NullDecimal
+	rateTimestamp       sql.NullTime
+}
+
+// availableBalanceColumn is balance of account minus funds reserved by its active holds that haven't expired yet
+const availableBalanceColumn = `balance - coalesce((select sum(h.amount) from holds as h where h.account_id = accounts.id and h.status = 'active' and h.expires_at > now()), 0)`
+
+// paymentColumns are selected into getPaymentsHelper by scanTargets
+const paymentColumns = `a1.account_id as source, a2.account_id as destination, p.amount, p.created_at, p.status,
+	a1.currency, a2.currency, p.destination_amount, p.exchange_rate, p.rate_timestamp, p.type, p.refund_of`
+
+// scanTargets returns pointers to helper fields in the order of paymentColumns
+func (h *getPaymentsHelper) scanTargets() []interface{} {
+	return []interface{}{
+		&h.source, &h.destination, &h.amount, &h.createdAt, &h.status,
+		&h.sourceCurrency, &h.destinationCurrency, &h.destinationAmount, &h.rate, &h.rateTimestamp,
+		&h.paymentType, &h.refundOf,
+	}
+}
+
+// record converts helper into direction-neutral PaymentRecord entity
+func (h *getPaymentsHelper) record() (entities.PaymentRecord, error) {
+	status, err := entities.ParsePaymentStatus(h.status)
+	if err != nil {
+		return entities.PaymentRecord{}, err
+	}
+
+	paymentType, err := entities.ParsePaymentType(h.paymentType)
+	if err != nil {
+		return entities.PaymentRecord{}, err
+	}
+
+	record := entities.PaymentRecord{
+		ID:          h.id,
+		Source:      h.source,
+		Destination: h.destination,
+		Amount:      h.amount,
+		Currency:    h.sourceCurrency,
+		CreatedAt:   h.createdAt.UTC(),
+		Status:      status,
+		Type:        paymentType,
+		RefundOf:    h.refundOf,
+	}
+	if h.destinationAmount.Valid {
+		record.Exchange = &entities.Exchange{
+			SourceCurrency:      h.sourceCurrency,
+			SourceAmount:        h.amount,
+			DestinationCurrency: h.destinationCurrency,
+			DestinationAmount:   h.destinationAmount.Decimal,
+			Rate:                h.rate.Decimal,
+			RateTimestamp:       h.rateTimestamp.Time.UTC(),
+		}
+	}
+	return record, nil
+}
+
+// payment converts helper into Payment entity as it is seen by specified account
+func (h *getPaymentsHelper) payment(account entities.AccountID) (entities.Payment, error) {
+	record, err := h.record()
+	if err != nil {
+		return entities.Payment{}, err
+	}
+
+	payment := entities.Payment{
+		ID:        record.ID,
+		Account:   account,
+		Amount:    record.Amount,
+		Currency:  record.Currency,
+		CreatedAt: record.CreatedAt,
+		Status:    record.Status,
+		Exchange:  record.Exchange,
+		Type:      record.Type,
+		RefundOf:  record.RefundOf,
+	}
+	if h.source == account {
+		payment.Direction = entities.Outgoing
+		payment.ToAccount = &h.destination
+	} else {
+		payment.Direction = entities.Incoming
+		payment.FromAccount = &h.source
+	}
+	return payment, nil
 }
 
 // NewPgStorage creates new Postgres storage with specified connection string
-func NewPgStorage(connectionString string) Storage {
+func NewPgStorage(connectionString string, options ...PgOption) Storage {
 	db, err := sql.Open("postgres", connectionString)
 	if err != nil {
 		panic(fmt.Sprintln(err))
 	}
-	return &pgStorage{
-		db: db,
-	}
+	return PgStorageFromHandle(db, options...)
 }
 
 // PgStorageFromHandle creates new Postgres storage with specified sql.DB instance
-// It is mostly used in tests
-func PgStorageFromHandle(db *sql.DB) Storage {
-	return &pgStorage{
-		db: db,
+// It is used in tests and when the same handle is shared with Migrator
+func PgStorageFromHandle(db *sql.DB, options ...PgOption) Storage {
+	ps := &pgStorage{
+		db:     db,
+		tracer: noop.NewTracerProvider().Tracer(""),
 	}
+	for _, option := range options {
+		option(ps)
+	}
+	return ps
 }
 
-func (ps *pgStorage) CreateAccount(ctx context.Context, acc entities.Account) error {
-	_, err := ps.db.ExecContext(
+func (ps *pgStorage) CreateAccount(ctx context.Context, acc entities.Account) (*entities.Account, error) {
+	ctx, span := ps.startSpan(ctx, "insert", "accounts", acc.ID)
+	var stored pgAccount
+	// new account has no holds, so all its balance is available
+	err := ps.db.QueryRowContext(
 		ctx,
-		"insert into accounts (account_id, currency, balance) values ($1, $2, $3);",
-		acc.ID, acc.Currency, acc.Balance,
-	)
+		`insert into accounts (account_id, currency, balance, initial_balance, owner) values ($1, $2, $3, $3, nullif($4, ''))
+		returning id, account_id, currency, balance, coalesce(owner, ''), status, balance;`,
+		acc.ID, acc.Currency, acc.Balance, acc.Owner,
+	).Scan(&stored.internalID, &stored.account.ID, &stored.account.Currency, &stored.account.Balance,
+		&stored.account.Owner, &stored.status, &stored.account.AvailableBalance)
+	endSpan(span, err)
 
 	if err != nil {
 		pgErr, ok := err.(*pq.Error)
 		if !ok {
-			return err
+			return nil, err
 		}
 
 		if pgErr.Code == pq.ErrorCode("23505") {
-			return entities.ErrAccountAlreadyExists
+			return nil, entities.ErrAccountAlreadyExists
 		}
-		return err
+		return nil, err
 	}
-	return nil
+	return &stored.account, stored.parseStatus()
 }
 
 func (ps *pgStorage) GetAccount(ctx context.Context, id entities.AccountID) (*entities.Account, error) {
@@ -91,156 +223,964 @@ func (ps *pgStorage) GetAccount(ctx context.Context, id entities.AccountID) (*en
 	return &pgAcc.account, nil
 }
 
-func (ps *pgStorage) ListAccounts(ctx context.Context) ([]entities.AccountID, error) {
-	rows, err := ps.db.QueryContext(ctx, "select account_id from accounts;")
+func (ps *pgStorage) ListAccounts(ctx context.Context, query entities.AccountsQuery) (entities.AccountsPage, error) {
+	page := entities.AccountsPage{Accounts: []entities.Account{}}
+
+	sqlQuery, args, err := accountsQuery(query)
 	if err != nil {
-		return nil, err
+		return page, err
+	}
+
+	ctx, span := ps.startSpan(ctx, "select", "accounts")
+	rows, err := ps.db.QueryContext(ctx, sqlQuery, args...)
+	endSpan(span, err)
+	if err != nil {
+		return page, err
 	}
+	defer rows.Close()
 
-	accounts := make([]entities.AccountID, 0)
 	for rows.Next() {
-		var account string
-		err = rows.Scan(&account)
+		var acc pgAccount
+		err = rows.Scan(&acc.account.ID, &acc.account.Currency, &acc.account.Balance, &acc.account.Owner, &acc.status,
+			&acc.account.AvailableBalance)
 		if err != nil {
-			break
+			return page, err
+		}
+		if err = acc.parseStatus(); err != nil {
+			return page, err
 		}
-		accounts = append(accounts, entities.AccountID(account))
+		page.Accounts = append(page.Accounts, acc.account)
+	}
+	if err = rows.Err(); err != nil {
+		return page, err
+	}
+
+	// one extra row is selected to find out whether there is the next page
+	if query.Limit > 0 && len(page.Accounts) > query.Limit {
+		page.Accounts = page.Accounts[:query.Limit]
+		page.NextCursor = entities.NewAccountCursor(page.Accounts[query.Limit-1]).Encode()
+	}
+	return page, nil
+}
+
+func (ps *pgStorage) UpdateAccountStatus(ctx context.Context, id entities.AccountID,
+	status entities.AccountStatus) (*entities.Account, error) {
+
+	var updated *entities.Account
+	err := ps.inTransaction(ctx, func(tx *sql.Tx) error {
+		// account row is locked, so that no payment can change its balance before it is closed
+		accounts, err := ps.lockAccounts(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		acc, ok := accounts[id]
+		if !ok {
+			return entities.ErrAccountNotFound
+		}
+
+		if err = acc.account.CheckStatusChange(status); err != nil {
+			return err
+		}
+
+		updateCtx, span := ps.startSpan(ctx, "update", "accounts", id)
+		_, err = tx.ExecContext(
+			updateCtx,
+			"update accounts set status = $1 where id = $2;",
+			status.String(),
+			acc.internalID,
+		)
+		endSpan(span, err)
+		if err != nil {
+			return err
+		}
+
+		acc.account.Status = status
+		updated = &acc.account
+		return nil
+	})
+	return updated, err
+}
+
+func (ps *pgStorage) Ping(ctx context.Context) error {
+	return ps.db.PingContext(ctx)
+}
+
+// likeEscaper escapes special characters of LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// accountsQuery builds SQL query and its arguments for listing accounts.
+// Account IDs are compared with "C" collation to get the same order as Go strings have
+func accountsQuery(query entities.AccountsQuery) (string, []interface{}, error) {
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"true"}
+	if query.IDPrefix != "" {
+		conditions = append(conditions, "account_id like "+arg(likeEscaper.Replace(query.IDPrefix)+"%"))
+	}
+
+	if query.Owner != "" {
+		conditions = append(conditions, "owner = "+arg(query.Owner))
+	}
+
+	if query.Currency != "" {
+		conditions = append(conditions, "currency = "+arg(query.Currency))
+	}
+
+	if query.MinBalance != nil {
+		conditions = append(conditions, "balance >= "+arg(*query.MinBalance))
+	}
+
+	if query.MaxBalance != nil {
+		conditions = append(conditions, "balance <= "+arg(*query.MaxBalance))
+	}
+
+	var cursor *entities.AccountCursor
+	if query.Cursor != "" {
+		decoded, err := entities.DecodeAccountCursor(query.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		cursor = &decoded
+	}
+
+	var order string
+	switch query.Sort {
+	case entities.SortByIDDesc:
+		order = `account_id collate "C" desc`
+		if cursor != nil {
+			conditions = append(conditions, `account_id collate "C" < `+arg(cursor.ID))
+		}
+
+	case entities.SortByBalance:
+		order = `balance, account_id collate "C"`
+		if cursor != nil {
+			conditions = append(conditions,
+				fmt.Sprintf(`(balance, account_id collate "C") > (%s, %s)`, arg(cursor.Balance), arg(cursor.ID)))
+		}
+
+	case entities.SortByBalanceDesc:
+		order = `balance desc, account_id collate "C" desc`
+		if cursor != nil {
+			conditions = append(conditions,
+				fmt.Sprintf(`(balance, account_id collate "C") < (%s, %s)`, arg(cursor.Balance), arg(cursor.ID)))
+		}
+
+	default:
+		order = `account_id collate "C"`
+		if cursor != nil {
+			conditions = append(conditions, `account_id collate "C" > `+arg(cursor.ID))
+		}
+	}
+
+	sqlQuery := "select account_id, currency, balance, coalesce(owner, ''), status, " + availableBalanceColumn + " from accounts where " +
+		strings.Join(conditions, " and ") + " order by " + order
+
+	if query.Limit > 0 {
+		sqlQuery += " limit " + arg(query.Limit+1)
 	}
-	return accounts, nil
+	return sqlQuery + ";", args, nil
 }
 
-func (ps *pgStorage) PaymentsByAccount(ctx context.Context, id entities.AccountID) ([]entities.Payment, error) {
+func (ps *pgStorage) PaymentsByAccount(ctx context.Context, id entities.AccountID,
+	query entities.PaymentsQuery) (entities.PaymentsPage, error) {
+
+	page := entities.PaymentsPage{Payments: []entities.Payment{}}
+
 	pgAcc, err := ps.selectAccount(ctx, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, entities.ErrAccountNotFound
+			return page, entities.ErrAccountNotFound
 		}
-		return nil, err
+		return page, err
 	}
 
-	rows, err := ps.db.QueryContext(
-		ctx,
-		`select p.id, p.source_id, p.destination_id, a1.account_id as source, a2.account_id as destination, p.amount 
-		from payments as p
-			join accounts as a1 on source_id = a1.id
-			join accounts as a2 on destination_id = a2.id
-		where p.source_id = $1 or p.destination_id = $1`,
-		pgAcc.internalID,
-	)
+	sqlQuery, args, err := paymentsQuery(pgAcc.internalID, query)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return []entities.Payment{}, nil
-		}
-		return nil, err
+		return page, err
+	}
+
+	ctx, span := ps.startSpan(ctx, "select", "payments", id)
+	rows, err := ps.db.QueryContext(ctx, sqlQuery, args...)
+	endSpan(span, err)
+	if err != nil {
+		return page, err
 	}
+	defer rows.Close()
 
-	payments := make([]entities.Payment, 0)
 	for rows.Next() {
 		var helper getPaymentsHelper
-		err = rows.Scan(&helper.id, &helper.sourceID, &helper.destinationID,
-			&helper.source, &helper.destination, &helper.amount)
-
+		err = rows.Scan(append([]interface{}{&helper.id}, helper.scanTargets()...)...)
 		if err != nil {
-			break
+			return page, err
 		}
 
-		payment := entities.Payment{
-			Account: pgAcc.account.ID,
-			Amount:  helper.amount,
-			ID:      helper.id,
+		payment, err := helper.payment(pgAcc.account.ID)
+		if err != nil {
+			return page, err
 		}
-		if helper.sourceID == pgAcc.internalID {
-			payment.Direction = entities.Outgoing
-			payment.ToAccount = &helper.destination
+		page.Payments = append(page.Payments, payment)
+	}
+	if err = rows.Err(); err != nil {
+		return page, err
+	}
+
+	// one extra row is selected to find out whether there is the next page
+	if query.Limit > 0 && len(page.Payments) > query.Limit {
+		page.Payments = page.Payments[:query.Limit]
+		page.NextCursor = entities.NewPaymentCursor(page.Payments[query.Limit-1]).Encode()
+	}
+	return page, nil
+}
+
+// paymentsQuery builds SQL query and its arguments for selecting payments of account
+// with specified internal ID
+func paymentsQuery(internalID int64, query entities.PaymentsQuery) (string, []interface{}, error) {
+	args := []interface{}{internalID}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"(p.source_id = $1 or p.destination_id = $1)"}
+	if query.Direction != nil {
+		if *query.Direction == entities.Outgoing {
+			conditions = append(conditions, "p.source_id = $1")
 		} else {
-			payment.Direction = entities.Incoming
-			payment.FromAccount = &helper.source
+			conditions = append(conditions, "p.destination_id = $1")
 		}
-		payments = append(payments, payment)
 	}
-	return payments, nil
-}
 
-func (ps *pgStorage) CreatePayment(ctx context.Context, payment entities.Payment) error {
-	var sourceAccount *pgAccount
-	var destinationAccount *pgAccount
-	var err error
+	if query.Counterparty != nil {
+		counterparty := arg(*query.Counterparty)
+		conditions = append(conditions, fmt.Sprintf("(a1.account_id = %s or a2.account_id = %s)", counterparty, counterparty))
+	}
+
+	if query.MinAmount != nil {
+		conditions = append(conditions, "p.amount >= "+arg(*query.MinAmount))
+	}
+
+	if query.MaxAmount != nil {
+		conditions = append(conditions, "p.amount <= "+arg(*query.MaxAmount))
+	}
+
+	if query.From != nil {
+		conditions = append(conditions, "p.created_at >= "+arg(*query.From))
+	}
+
+	if query.To != nil {
+		conditions = append(conditions, "p.created_at < "+arg(*query.To))
+	}
+
+	if query.Cursor != "" {
+		cursor, err := entities.DecodePaymentCursor(query.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(p.created_at, p.id) > (%s, %s)", arg(cursor.CreatedAt), arg(cursor.ID)))
+	}
+
+	sqlQuery := `select p.id, ` + paymentColumns + `
+		from payments as p
+			join accounts as a1 on source_id = a1.id
+			join accounts as a2 on destination_id = a2.id
+		where ` + strings.Join(conditions, " and ") + `
+		order by p.created_at, p.id`
+
+	if query.Limit > 0 {
+		sqlQuery += " limit " + arg(query.Limit+1)
+	}
+	return sqlQuery + ";", args, nil
+}
 
+func (ps *pgStorage) CreatePayment(ctx context.Context, payment entities.Payment) (*entities.Payment, error) {
+	var source, destination entities.AccountID
 	if payment.Direction == entities.Outgoing {
-		sourceAccount, err = ps.selectAccount(ctx, payment.Account)
-		destinationAccount, err = ps.selectAccount(ctx, *payment.ToAccount)
+		source, destination = payment.Account, *payment.ToAccount
 	} else {
-		sourceAccount, err = ps.selectAccount(ctx, *payment.FromAccount)
-		destinationAccount, err = ps.selectAccount(ctx, payment.Account)
+		source, destination = *payment.FromAccount, payment.Account
 	}
 
-	if sourceAccount == nil {
-		return entities.ErrPaymentSourceNotFound
+	var stored *entities.Payment
+	err := ps.inTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		stored, err = ps.createPayment(ctx, tx, payment, source, destination)
+		return err
+	})
+
+	if isUniqueViolation(err) {
+		// concurrent request with the same payment ID has won the race
+		if dup, dupErr := ps.checkDuplicatePayment(ctx, ps.db, payment, source, destination); dupErr != nil {
+			return dup, dupErr
+		}
 	}
+	return stored, err
+}
 
-	if destinationAccount == nil {
-		return entities.ErrPaymentDestinationNotFound
+// createPayment transfers money between accounts inside of transaction
+func (ps *pgStorage) createPayment(ctx context.Context, tx *sql.Tx, payment entities.Payment,
+	source, destination entities.AccountID) (*entities.Payment, error) {
+
+	accounts, err := ps.lockAccounts(ctx, tx, source, destination)
+	if err != nil {
+		return nil, err
 	}
 
-	if sourceAccount.account.Currency != destinationAccount.account.Currency {
-		return entities.ErrDifferentCurrencies
+	sourceAccount, ok := accounts[source]
+	if !ok {
+		return nil, entities.ErrPaymentSourceNotFound
 	}
 
-	tx, err := ps.db.BeginTx(ctx, nil)
+	destinationAccount, ok := accounts[destination]
+	if !ok {
+		return nil, entities.ErrPaymentDestinationNotFound
+	}
+
+	// payment ID is an idempotency key, so check whether it has been already made
+	// before checking funds: a retry of completed payment must not fail
+	dup, err := ps.checkDuplicatePayment(ctx, tx, payment, source, destination)
 	if err != nil {
-		return err
+		return dup, err
+	}
+
+	if err = entities.CheckPaymentAccounts(sourceAccount.account, destinationAccount.account); err != nil {
+		return nil, err
 	}
 
-	// insert payment
-	_, err = tx.Exec(
-		"insert into payments (id, source_id, destination_id, amount) values ($1, $2, $3, $4);",
+	credited, err := creditedAmount(payment, sourceAccount.account.Currency, destinationAccount.account.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// held funds can't be spent, external accounts are allowed to go negative, see balance_non_negative constraint
+	if !source.IsExternal() && sourceAccount.account.AvailableBalance.LessThan(payment.Amount) {
+		return nil, entities.ErrInsufficientFunds
+	}
+
+	var destinationAmount, rate decimal.NullDecimal
+	var rateTimestamp sql.NullTime
+	if payment.Exchange != nil {
+		destinationAmount = decimal.NullDecimal{Decimal: payment.Exchange.DestinationAmount, Valid: true}
+		rate = decimal.NullDecimal{Decimal: payment.Exchange.Rate, Valid: true}
+		rateTimestamp = sql.NullTime{Time: payment.Exchange.RateTimestamp, Valid: true}
+	}
+
+	// insert payment, creation time is assigned by database
+	stored := payment
+	stored.Currency = sourceAccount.account.Currency
+	stored.Status = entities.Completed
+	insertCtx, span := ps.startSpan(ctx, "insert", "payments", source, destination)
+	err = tx.QueryRowContext(
+		insertCtx,
+		`insert into payments (id, source_id, destination_id, amount, status, destination_amount, exchange_rate, rate_timestamp,
+			type, refund_of)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		returning created_at;`,
 		payment.ID,
 		sourceAccount.internalID,
 		destinationAccount.internalID,
 		payment.Amount,
-	)
+		stored.Status.String(),
+		destinationAmount,
+		rate,
+		rateTimestamp,
+		payment.Type.String(),
+		payment.RefundOf,
+	).Scan(&stored.CreatedAt)
+	endSpan(span, err)
 	if err != nil {
-		tx.Rollback()
-		return err
+		return nil, err
 	}
+	stored.CreatedAt = stored.CreatedAt.UTC()
 
-	// update balances
 	updates := []balanceUpdateHelper{
-		{internalAccountID: sourceAccount.internalID, diff: payment.Amount.Neg()},
-		{internalAccountID: destinationAccount.internalID, diff: payment.Amount},
+		{accountID: source, internalAccountID: sourceAccount.internalID, diff: payment.Amount.Neg()},
+		{accountID: destination, internalAccountID: destinationAccount.internalID, diff: credited},
 	}
 
-	// make sure that we update accounts in the same order to avoid deadlocks
+	// update accounts in the same order they were locked
 	if sourceAccount.internalID > destinationAccount.internalID {
 		updates[0], updates[1] = updates[1], updates[0]
 	}
 
+	// update cached balances and append ledger entries, balance of each entry is checked against the cache
 	for _, u := range updates {
-		_, err = tx.Exec(
-			"update accounts set balance = balance + $1 where id = $2;",
+		var balance decimal.Decimal
+		updateCtx, span := ps.startSpan(ctx, "update", "accounts", u.accountID)
+		err = tx.QueryRowContext(
+			updateCtx,
+			"update accounts set balance = balance + $1 where id = $2 returning balance;",
 			u.diff,
 			u.internalAccountID,
+		).Scan(&balance)
+		endSpan(span, err)
+		if err != nil {
+			if isCheckViolation(err) {
+				return nil, entities.ErrInsufficientFunds
+			}
+			return nil, err
+		}
+
+		insertCtx, span := ps.startSpan(ctx, "insert", "ledger_entries", u.accountID)
+		_, err = tx.ExecContext(
+			insertCtx,
+			"insert into ledger_entries (payment_id, account_id, amount, balance) values ($1, $2, $3, $4);",
+			payment.ID,
+			u.internalAccountID,
+			u.diff,
+			balance,
+		)
+		endSpan(span, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &stored, nil
+}
+
+func (ps *pgStorage) CreateRefund(ctx context.Context, refund entities.Payment) (*entities.Payment, error) {
+	var stored *entities.Payment
+	var payment entities.Payment
+	err := ps.inTransaction(ctx, func(tx *sql.Tx) error {
+		// refunded payment is locked, so that concurrent refunds can't exceed its amount
+		original, err := ps.selectPayment(ctx, tx, *refund.RefundOf, true)
+		if err != nil {
+			return err
+		}
+
+		// only the recipient can refund the payment
+		if refund.Account != original.Destination {
+			return entities.ErrPaymentNotFound
+		}
+
+		if err = checkRefundable(*original); err != nil {
+			return err
+		}
+
+		// the refund itself is excluded, so that a retry of completed refund is reported as duplicate
+		var refunded decimal.Decimal
+		sumCtx, span := ps.startSpan(ctx, "select", "payments", original.Source, original.Destination)
+		err = tx.QueryRowContext(
+			sumCtx,
+			"select coalesce(sum(amount), 0) from payments where refund_of = $1 and id <> $2;",
+			original.ID,
+			refund.ID,
+		).Scan(&refunded)
+		endSpan(span, err)
+		if err != nil {
+			return err
+		}
+
+		payment = refund
+		payment.Direction = entities.Outgoing
+		payment.ToAccount = &original.Source
+		payment.FromAccount = nil
+		payment.Exchange = nil
+		payment.Type = entities.Refund
+		if payment.Amount, err = refundAmount(refund.Amount, original.Amount, refunded); err != nil {
+			return err
+		}
+
+		stored, err = ps.createPayment(ctx, tx, payment, original.Destination, original.Source)
+		return err
+	})
+
+	if isUniqueViolation(err) {
+		// concurrent request with the same refund ID has won the race
+		if dup, dupErr := ps.checkDuplicatePayment(ctx, ps.db, payment, payment.Account, *payment.ToAccount); dupErr != nil {
+			return dup, dupErr
+		}
+	}
+	return stored, err
+}
+
+func (ps *pgStorage) GetPayment(ctx context.Context, id uuid.UUID) (*entities.PaymentRecord, error) {
+	return ps.selectPayment(ctx, ps.db, id, false)
+}
+
+// selectPayment selects payment with specified ID, the payment row is locked if forUpdate is set
+func (ps *pgStorage) selectPayment(ctx context.Context, q queryer, id uuid.UUID, forUpdate bool) (*entities.PaymentRecord, error) {
+	query := `select ` + paymentColumns + `
+		from payments as p
+			join accounts as a1 on source_id = a1.id
+			join accounts as a2 on destination_id = a2.id
+		where p.id = $1`
+	operation := "select"
+	if forUpdate {
+		query += " for update of p"
+		operation = "select for update"
+	}
+
+	ctx, span := ps.startSpan(ctx, operation, "payments")
+	helper := getPaymentsHelper{id: id}
+	err := q.QueryRowContext(ctx, query+";", id).Scan(helper.scanTargets()...)
+	endSpan(span, err)
+
+	if err == sql.ErrNoRows {
+		return nil, entities.ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := helper.record()
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (ps *pgStorage) LedgerEntries(ctx context.Context, id entities.AccountID) ([]entities.LedgerEntry, error) {
+	pgAcc, err := ps.selectAccount(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, entities.ErrAccountNotFound
+		}
+		return nil, err
+	}
+
+	ctx, span := ps.startSpan(ctx, "select", "ledger_entries", id)
+	rows, err := ps.db.QueryContext(
+		ctx,
+		"select id, payment_id, amount, balance, created_at from ledger_entries where account_id = $1 order by id;",
+		pgAcc.internalID,
+	)
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []entities.LedgerEntry{}
+	for rows.Next() {
+		entry := entities.LedgerEntry{Account: id}
+		err = rows.Scan(&entry.ID, &entry.PaymentID, &entry.Amount, &entry.Balance, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entry.CreatedAt = entry.CreatedAt.UTC()
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (ps *pgStorage) BalanceAt(ctx context.Context, id entities.AccountID, entryID int64) (decimal.Decimal, error) {
+	ctx, span := ps.startSpan(ctx, "select", "ledger_entries", id)
+	var balance decimal.Decimal
+	err := ps.db.QueryRowContext(
+		ctx,
+		`select a.initial_balance + coalesce(sum(e.amount), 0)
+		from accounts as a
+			left join ledger_entries as e on e.account_id = a.id and e.id <= $2
+		where a.account_id = $1
+		group by a.id;`,
+		id,
+		entryID,
+	).Scan(&balance)
+	endSpan(span, err)
+
+	if err == sql.ErrNoRows {
+		return decimal.Decimal{}, entities.ErrAccountNotFound
+	}
+	return balance, err
+}
+
+// holdColumns are selected by selectHold, holds past their expiration time are reported as expired
+const holdColumns = `h.id, a1.account_id, a2.account_id, h.amount, a1.currency, h.captured_amount,
+	case when h.status = 'active' and h.expires_at <= now() then 'expired' else h.status end, h.created_at, h.expires_at`
+
+func (ps *pgStorage) CreateHold(ctx context.Context, hold entities.Hold) (*entities.Hold, error) {
+	var stored *entities.Hold
+	err := ps.inTransaction(ctx, func(tx *sql.Tx) error {
+		// accounts are locked, so that concurrent payments and holds can't spend the same funds
+		accounts, err := ps.lockAccounts(ctx, tx, hold.Account, hold.ToAccount)
+		if err != nil {
+			return err
+		}
+
+		sourceAccount, ok := accounts[hold.Account]
+		if !ok {
+			return entities.ErrPaymentSourceNotFound
+		}
+
+		destinationAccount, ok := accounts[hold.ToAccount]
+		if !ok {
+			return entities.ErrPaymentDestinationNotFound
+		}
+
+		if err = entities.CheckPaymentAccounts(sourceAccount.account, destinationAccount.account); err != nil {
+			return err
+		}
+
+		if sourceAccount.account.Currency != destinationAccount.account.Currency {
+			return entities.ErrDifferentCurrencies
+		}
+
+		if sourceAccount.account.AvailableBalance.LessThan(hold.Amount) {
+			return entities.ErrInsufficientFunds
+		}
+
+		result := hold
+		result.Currency = sourceAccount.account.Currency
+		result.Status = entities.HoldActive
+		result.CapturedAmount = nil
+		insertCtx, span := ps.startSpan(ctx, "insert", "holds", hold.Account, hold.ToAccount)
+		err = tx.QueryRowContext(
+			insertCtx,
+			`insert into holds (id, account_id, destination_id, amount, expires_at)
+			values ($1, $2, $3, $4, $5)
+			returning created_at;`,
+			hold.ID,
+			sourceAccount.internalID,
+			destinationAccount.internalID,
+			hold.Amount,
+			hold.ExpiresAt,
+		).Scan(&result.CreatedAt)
+		endSpan(span, err)
+		if err != nil {
+			return err
+		}
+
+		result.CreatedAt = result.CreatedAt.UTC()
+		result.ExpiresAt = result.ExpiresAt.UTC()
+		stored = &result
+		return nil
+	})
+
+	if isUniqueViolation(err) {
+		return nil, entities.ErrHoldAlreadyExists
+	}
+	return stored, err
+}
+
+func (ps *pgStorage) GetHold(ctx context.Context, id uuid.UUID) (*entities.Hold, error) {
+	return ps.selectHold(ctx, ps.db, id, false)
+}
+
+func (ps *pgStorage) CaptureHold(ctx context.Context, id uuid.UUID, amount *decimal.Decimal) (*entities.Payment, error) {
+	var stored *entities.Payment
+	err := ps.inTransaction(ctx, func(tx *sql.Tx) error {
+		hold, err := ps.selectHold(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+
+		if hold.Status == entities.HoldCaptured {
+			stored, err = ps.capturedPayment(ctx, tx, hold, amount)
+			return err
+		}
+
+		if hold.Status != entities.HoldActive {
+			return entities.ErrHoldNotActive
+		}
+
+		captured := hold.Amount
+		if amount != nil {
+			captured = *amount
+		}
+		if !captured.IsPositive() || captured.GreaterThan(hold.Amount) {
+			return entities.ErrWrongCaptureAmount
+		}
+
+		// hold is captured before the payment, so that its funds become available to the payment
+		updateCtx, span := ps.startSpan(ctx, "update", "holds", hold.Account, hold.ToAccount)
+		_, err = tx.ExecContext(
+			updateCtx,
+			"update holds set status = $1, captured_amount = $2 where id = $3;",
+			entities.HoldCaptured.String(),
+			captured,
+			id,
+		)
+		endSpan(span, err)
+		if err != nil {
+			return err
+		}
+
+		payment := entities.Payment{
+			ID:        hold.ID,
+			Account:   hold.Account,
+			Amount:    captured,
+			Direction: entities.Outgoing,
+			ToAccount: &hold.ToAccount,
+		}
+		stored, err = ps.createPayment(ctx, tx, payment, hold.Account, hold.ToAccount)
+		if err == entities.ErrPaymentDuplicate {
+			// unrelated payment has been made with the same ID
+			return entities.ErrPaymentAlreadyDone
+		}
+		return err
+	})
+	return stored, err
+}
+
+// capturedPayment returns the payment made by capture of hold and ErrPaymentDuplicate,
+// so that retried capture is replayed. Capture of different amount fails with ErrHoldNotActive
+func (ps *pgStorage) capturedPayment(ctx context.Context, q queryer, hold *entities.Hold,
+	amount *decimal.Decimal) (*entities.Payment, error) {
+
+	if amount != nil && !amount.Equal(*hold.CapturedAmount) {
+		return nil, entities.ErrHoldNotActive
+	}
+
+	payment := entities.Payment{
+		ID:        hold.ID,
+		Account:   hold.Account,
+		Amount:    *hold.CapturedAmount,
+		Direction: entities.Outgoing,
+		ToAccount: &hold.ToAccount,
+	}
+	stored, err := ps.checkDuplicatePayment(ctx, q, payment, hold.Account, hold.ToAccount)
+	if stored == nil && err == nil {
+		return nil, entities.ErrHoldNotActive
+	}
+	return stored, err
+}
+
+func (ps *pgStorage) ReleaseHold(ctx context.Context, id uuid.UUID) (*entities.Hold, error) {
+	var released *entities.Hold
+	err := ps.inTransaction(ctx, func(tx *sql.Tx) error {
+		hold, err := ps.selectHold(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+
+		if hold.Status != entities.HoldActive {
+			return entities.ErrHoldNotActive
+		}
+
+		updateCtx, span := ps.startSpan(ctx, "update", "holds", hold.Account, hold.ToAccount)
+		_, err = tx.ExecContext(
+			updateCtx,
+			"update holds set status = $1 where id = $2;",
+			entities.HoldReleased.String(),
+			id,
 		)
+		endSpan(span, err)
 		if err != nil {
-			tx.Rollback()
 			return err
 		}
+
+		hold.Status = entities.HoldReleased
+		released = hold
+		return nil
+	})
+	return released, err
+}
+
+// selectHold selects hold with specified ID, the hold row is locked if forUpdate is set
+func (ps *pgStorage) selectHold(ctx context.Context, q queryer, id uuid.UUID, forUpdate bool) (*entities.Hold, error) {
+	query := `select ` + holdColumns + `
+		from holds as h
+			join accounts as a1 on h.account_id = a1.id
+			join accounts as a2 on h.destination_id = a2.id
+		where h.id = $1`
+	operation := "select"
+	if forUpdate {
+		query += " for update of h"
+		operation = "select for update"
+	}
+
+	ctx, span := ps.startSpan(ctx, operation, "holds")
+	var hold entities.Hold
+	var captured decimal.NullDecimal
+	var status string
+	err := q.QueryRowContext(ctx, query+";", id).Scan(
+		&hold.ID, &hold.Account, &hold.ToAccount, &hold.Amount, &hold.Currency, &captured,
+		&status, &hold.CreatedAt, &hold.ExpiresAt,
+	)
+	endSpan(span, err)
+
+	if err == sql.ErrNoRows {
+		return nil, entities.ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if hold.Status, err = entities.ParseHoldStatus(status); err != nil {
+		return nil, err
+	}
+	if captured.Valid {
+		hold.CapturedAmount = &captured.Decimal
+	}
+	hold.CreatedAt = hold.CreatedAt.UTC()
+	hold.ExpiresAt = hold.ExpiresAt.UTC()
+	return &hold, nil
+}
+
+// lockAccounts selects specified accounts for update. Rows are locked in the order of internal IDs,
+// so concurrent transfers between the same accounts can't deadlock each other
+func (ps *pgStorage) lockAccounts(ctx context.Context, tx *sql.Tx, ids ...entities.AccountID) (map[entities.AccountID]*pgAccount, error) {
+	ctx, span := ps.startSpan(ctx, "select for update", "accounts", ids...)
+	rows, err := tx.QueryContext(
+		ctx,
+		"select id, account_id, currency, balance, coalesce(owner, ''), status, "+availableBalanceColumn+
+			" from accounts where account_id = any($1) order by id for update;",
+		pq.Array(ids),
+	)
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := make(map[entities.AccountID]*pgAccount, len(ids))
+	for rows.Next() {
+		var acc pgAccount
+		err = rows.Scan(&acc.internalID, &acc.account.ID, &acc.account.Currency, &acc.account.Balance, &acc.account.Owner, &acc.status,
+			&acc.account.AvailableBalance)
+		if err != nil {
+			return nil, err
+		}
+		if err = acc.parseStatus(); err != nil {
+			return nil, err
+		}
+		accounts[acc.account.ID] = &acc
 	}
+	return accounts, rows.Err()
+}
 
+// inTransaction runs fn inside of serializable transaction. Transaction is rolled back if fn fails,
+// serialization failures and deadlocks are retried with exponential backoff
+func (ps *pgStorage) inTransaction(ctx context.Context, fn func(*sql.Tx) error) error {
+	backoff := txRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := ps.runTransaction(ctx, fn)
+		if !isRetryable(err) || attempt == txMaxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (ps *pgStorage) runTransaction(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := ps.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
 	return tx.Commit()
 }
 
+// queryer is a common interface of sql.DB and sql.Tx used for reading
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// checkDuplicatePayment looks for already stored payment with the same ID.
+// It returns stored payment and ErrPaymentDuplicate if it has the same data,
+// ErrPaymentAlreadyDone if the data differs and nil if there is no such payment
+func (ps *pgStorage) checkDuplicatePayment(ctx context.Context, q queryer, payment entities.Payment,
+	source, destination entities.AccountID) (*entities.Payment, error) {
+
+	ctx, span := ps.startSpan(ctx, "select", "payments", source, destination)
+	helper := getPaymentsHelper{id: payment.ID}
+	err := q.QueryRowContext(
+		ctx,
+		`select `+paymentColumns+`
+		from payments as p
+			join accounts as a1 on source_id = a1.id
+			join accounts as a2 on destination_id = a2.id
+		where p.id = $1;`,
+		payment.ID,
+	).Scan(helper.scanTargets()...)
+	endSpan(span, err)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if helper.source != source || helper.destination != destination || !helper.amount.Equal(payment.Amount) ||
+		!sameRefundOf(helper.refundOf, payment.RefundOf) {
+		return nil, entities.ErrPaymentAlreadyDone
+	}
+
+	stored, err := helper.payment(payment.Account)
+	if err != nil {
+		return nil, err
+	}
+	return &stored, entities.ErrPaymentDuplicate
+}
+
 func (ps *pgStorage) selectAccount(ctx context.Context, id entities.AccountID) (*pgAccount, error) {
+	ctx, span := ps.startSpan(ctx, "select", "accounts", id)
 	var acc pgAccount
 	err := ps.db.QueryRowContext(
 		ctx,
-		"select id, account_id, currency, balance from accounts where account_id = $1;",
+		"select id, account_id, currency, balance, coalesce(owner, ''), status, "+availableBalanceColumn+
+			" from accounts where account_id = $1;",
 		id,
-	).Scan(&acc.internalID, &acc.account.ID, &acc.account.Currency, &acc.account.Balance)
+	).Scan(&acc.internalID, &acc.account.ID, &acc.account.Currency, &acc.account.Balance, &acc.account.Owner, &acc.status,
+		&acc.account.AvailableBalance)
+	endSpan(span, err)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &acc, nil
+	return &acc, acc.parseStatus()
+}
+
+// startSpan starts a child span of SQL query with its operation, table and IDs of related accounts
+func (ps *pgStorage) startSpan(ctx context.Context, operation, table string, ids ...entities.AccountID) (context.Context, trace.Span) {
+	accountIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		accountIDs = append(accountIDs, string(id))
+	}
+
+	return ps.tracer.Start(ctx, operation+" "+table,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", operation),
+			attribute.String("db.sql.table", table),
+			attribute.StringSlice("wallet.account_ids", accountIDs),
+		),
+	)
+}
+
+// endSpan records query error in span and ends it. Missing rows are not treated as errors
+func endSpan(span trace.Span, err error) {
+	if err != nil && err != sql.ErrNoRows {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// isCheckViolation reports whether err is a Postgres check constraint violation,
+// balance updates can only violate balance_non_negative constraint
+func isCheckViolation(err error) bool {
+	pgErr, ok := err.(*pq.Error)
+	return ok && pgErr.Code == pq.ErrorCode("23514")
+}
+
+// isRetryable reports whether transaction failed because of serialization failure or deadlock
+// and can be safely retried
+func isRetryable(err error) bool {
+	pgErr, ok := err.(*pq.Error)
+	return ok && (pgErr.Code == pq.ErrorCode("40001") || pgErr.Code == pq.ErrorCode("40P01"))
+}
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	pgErr, ok := err.(*pq.Error)
+	return ok && pgErr.Code == pq.ErrorCode("23505")
 }
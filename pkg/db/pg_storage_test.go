@@ -5,12 +5,18 @@ import (
 	"database/sql"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/shirolimit/wallet-service/pkg/entities"
 	"github.com/shopspring/decimal"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	mydb "github.com/shirolimit/wallet-service/pkg/db"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func Test_PgStorage_ListAccounts(t *testing.T) {
@@ -20,18 +26,62 @@ func Test_PgStorage_ListAccounts(t *testing.T) {
 	}
 	defer db.Close()
 
-	expected := []entities.AccountID{"alice", "bob"}
+	expected := []entities.Account{
+		{ID: "alice", Balance: decimal.New(100, 0), AvailableBalance: decimal.New(100, 0), Currency: "USD"},
+		{ID: "bob", Balance: decimal.New(200, 0), AvailableBalance: decimal.New(200, 0), Currency: "USD"},
+	}
+
+	mock.ExpectQuery("select account_id, currency, balance, coalesce\\(owner, ''\\), status, (.+) from accounts where true order by account_id").
+		WillReturnRows(sqlmock.NewRows([]string{"account_id", "currency", "balance", "owner", "status", "available_balance"}).
+			AddRow("alice", "USD", "100", "", "active", "100").
+			AddRow("bob", "USD", "200", "", "active", "200"))
+
+	storage := mydb.PgStorageFromHandle(db)
+	page, storageErr := storage.ListAccounts(context.TODO(), entities.AccountsQuery{})
+	if storageErr != nil {
+		t.Errorf("Error while quering accounts list: %v", storageErr)
+	}
+	if !reflect.DeepEqual(page.Accounts, expected) || page.NextCursor != "" {
+		t.Errorf("Expectation failed. Expected accounts = %v, actual = %v", expected, page)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func Test_PgStorage_ListAccountsPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' while opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	cursor := entities.NewAccountCursor(entities.Account{ID: "alex", Balance: decimal.New(100, 0)})
+	minBalance := decimal.New(50, 0)
+	query := entities.AccountsQuery{
+		Limit:      1,
+		Cursor:     cursor.Encode(),
+		IDPrefix:   "al_",
+		Owner:      "alice",
+		Currency:   "USD",
+		MinBalance: &minBalance,
+		Sort:       entities.SortByBalance,
+	}
 
-	mock.ExpectQuery("select account_id from accounts;").
-		WillReturnRows(sqlmock.NewRows([]string{"account_id"}).AddRow("alice").AddRow("bob"))
+	mock.ExpectQuery("select account_id, currency, balance, coalesce\\(owner, ''\\), status, (.+) from accounts where (.+) order by balance").
+		WithArgs(`al\_%`, "alice", "USD", minBalance, cursor.Balance, cursor.ID, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"account_id", "currency", "balance", "owner", "status", "available_balance"}).
+			AddRow("al_ice", "USD", "100", "", "active", "100").
+			AddRow("al_ex", "USD", "200", "", "active", "200"))
 
 	storage := mydb.PgStorageFromHandle(db)
-	accounts, storageErr := storage.ListAccounts(context.TODO())
+	page, storageErr := storage.ListAccounts(context.TODO(), query)
 	if storageErr != nil {
-		t.Errorf("Error while quering accounts list: %v", err)
+		t.Errorf("Error while quering accounts list: %v", storageErr)
 	}
-	if !reflect.DeepEqual(accounts, expected) {
-		t.Errorf("Expectation failed. Expected accounts = %v, actual = %v", expected, accounts)
+	expectedCursor := entities.NewAccountCursor(entities.Account{ID: "al_ice", Balance: decimal.New(100, 0)}).Encode()
+	if len(page.Accounts) != 1 || page.Accounts[0].ID != "al_ice" || page.NextCursor != expectedCursor {
+		t.Errorf("Expectation failed. Actual page = %v", page)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("Unfulfilled expectations: %v", err)
@@ -45,13 +95,18 @@ func Test_PgStorage_ListAccountsError(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectQuery("select account_id from accounts;").WillReturnError(sql.ErrConnDone)
+	mock.ExpectQuery("select account_id, currency, balance, coalesce\\(owner, ''\\), status, (.+) from accounts").WillReturnError(sql.ErrConnDone)
 
 	storage := mydb.PgStorageFromHandle(db)
-	_, storageErr := storage.ListAccounts(context.TODO())
+	_, storageErr := storage.ListAccounts(context.TODO(), entities.AccountsQuery{})
 	if storageErr == nil {
 		t.Errorf("Error expectation failed")
 	}
+
+	_, storageErr = storage.ListAccounts(context.TODO(), entities.AccountsQuery{Cursor: "garbage"})
+	if storageErr != entities.ErrInvalidCursor {
+		t.Errorf("Expectation failed. Expected error = %v, actual = %v", entities.ErrInvalidCursor, storageErr)
+	}
 }
 
 func Test_PgStorage_CreateAccount(t *testing.T) {
@@ -67,20 +122,51 @@ func Test_PgStorage_CreateAccount(t *testing.T) {
 		Currency: "USD",
 	}
 
-	mock.ExpectExec("insert into accounts").
-		WithArgs(acc.ID, acc.Currency, acc.Balance).
-		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("insert into accounts (.+) returning").
+		WithArgs(acc.ID, acc.Currency, acc.Balance, acc.Owner).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "account_id", "currency", "balance", "owner", "status", "available_balance"}).
+			AddRow(1, "alice", "USD", acc.Balance, "", "active", acc.Balance))
 
 	storage := mydb.PgStorageFromHandle(db)
-	storageErr := storage.CreateAccount(context.TODO(), acc)
+	created, storageErr := storage.CreateAccount(context.TODO(), acc)
 	if storageErr != nil {
-		t.Errorf("Error while creating account: %v", storageErr)
+		t.Fatalf("Error while creating account: %v", storageErr)
+	}
+	if created.ID != acc.ID || created.Status != entities.Active || !created.AvailableBalance.Equal(acc.Balance) {
+		t.Errorf("Expectation failed. Actual account = %v", created)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("Unfulfilled expectations: %v", err)
 	}
 }
 
+// createdAt is a payment creation time returned by mocked database
+var createdAt = time.Date(2019, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+// paymentColumns are columns of payments selected by storage
+var paymentColumns = []string{"source", "destination", "amount", "created_at", "status",
+	"source_currency", "destination_currency", "destination_amount", "exchange_rate", "rate_timestamp",
+	"type", "refund_of"}
+
+// expectLockAccounts sets expectations for locking alice (id 1) and bob (id 2) accounts
+func expectLockAccounts(mock sqlmock.Sqlmock, aliceBalance, bobBalance decimal.Decimal) {
+	mock.ExpectQuery("select id, account_id, currency, balance, coalesce\\(owner, ''\\), status, (.+) from accounts (.+) for update").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "account_id", "currency", "balance", "owner", "status", "available_balance"}).
+			AddRow(1, "alice", "USD", aliceBalance, "", "active", aliceBalance).
+			AddRow(2, "bob", "USD", bobBalance, "", "active", bobBalance))
+}
+
+// expectBalanceUpdate expects update of cached balance followed by ledger entry of payment
+func expectBalanceUpdate(mock sqlmock.Sqlmock, paymentID uuid.UUID, internalID int, diff decimal.Decimal) {
+	mock.ExpectQuery("update accounts set balance (.+) returning balance").
+		WithArgs(diff, internalID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(decimal.New(1000, 0)))
+	mock.ExpectExec("insert into ledger_entries").
+		WithArgs(paymentID, internalID, diff, decimal.New(1000, 0)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
 func Test_PgStorage_CreatePayment(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -96,35 +182,681 @@ func Test_PgStorage_CreatePayment(t *testing.T) {
 		Direction: entities.Outgoing,
 	}
 
-	mock.ExpectQuery("select id, account_id, currency, balance from accounts").
-		WithArgs(payment.Account).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "account_id", "currency", "balance"}).
-			AddRow(1, "alice", "USD", decimal.New(100, 0)))
+	mock.ExpectBegin()
+	expectLockAccounts(mock, decimal.New(100, 0), decimal.New(200, 0))
+	mock.ExpectQuery("select (.+) from payments").
+		WithArgs(payment.ID).
+		WillReturnRows(sqlmock.NewRows(paymentColumns))
+
+	mock.ExpectQuery("insert into payments").
+		WithArgs(payment.ID, 1, 2, payment.Amount, "completed", nil, nil, nil, "payment", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(createdAt))
+
+	expectBalanceUpdate(mock, payment.ID, 1, payment.Amount.Neg())
+
+	expectBalanceUpdate(mock, payment.ID, 2, payment.Amount)
+
+	mock.ExpectCommit()
+
+	storage := mydb.PgStorageFromHandle(db)
+	stored, storageErr := storage.CreatePayment(context.TODO(), payment)
+	if storageErr != nil {
+		t.Fatalf("Error while creating payment: %v", storageErr)
+	}
+	if !stored.CreatedAt.Equal(createdAt) || stored.Status != entities.Completed {
+		t.Errorf("Expectation failed. Actual payment = %v", stored)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func Test_PgStorage_CreatePaymentTracing(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' while opening a mock database connection", err)
+	}
+	defer db.Close()
 
-	mock.ExpectQuery("select id, account_id, currency, balance from accounts").
-		WithArgs(*payment.ToAccount).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "account_id", "currency", "balance"}).
-			AddRow(2, "bob", "USD", decimal.New(200, 0)))
+	toAccount := entities.AccountID("bob")
+	payment := entities.Payment{
+		Account:   "alice",
+		Amount:    decimal.New(100, 0),
+		ToAccount: &toAccount,
+		Direction: entities.Outgoing,
+	}
 
 	mock.ExpectBegin()
-	mock.ExpectExec("insert into payments").
-		WithArgs(payment.ID, 1, 2, payment.Amount).
-		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLockAccounts(mock, decimal.New(50, 0), decimal.New(200, 0))
+	mock.ExpectQuery("select (.+) from payments").
+		WithArgs(payment.ID).
+		WillReturnRows(sqlmock.NewRows(paymentColumns))
+	mock.ExpectRollback()
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	storage := mydb.PgStorageFromHandle(db, mydb.WithTracer(tracer))
+	if _, storageErr := storage.CreatePayment(context.TODO(), payment); storageErr != entities.ErrInsufficientFunds {
+		t.Fatalf("Expectation failed. Expected error = %v, actual = %v", entities.ErrInsufficientFunds, storageErr)
+	}
+
+	accountIDs := attribute.StringSlice("wallet.account_ids", []string{"alice", "bob"})
+	expected := []struct {
+		name      string
+		operation string
+	}{
+		{"select for update accounts", "select for update"},
+		{"select payments", "select"},
+	}
+	spans := recorder.Ended()
+	if len(spans) != len(expected) {
+		t.Fatalf("Expectation failed. Expected %d spans, actual = %d", len(expected), len(spans))
+	}
+	for i, span := range spans {
+		attrs := span.Attributes()
+		if span.Name() != expected[i].name ||
+			!containsAttribute(attrs, attribute.String("db.operation", expected[i].operation)) ||
+			!containsAttribute(attrs, accountIDs) {
+			t.Errorf("Expectation failed. Actual span %s attributes = %v", span.Name(), attrs)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func containsAttribute(attrs []attribute.KeyValue, expected attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if attr.Key == expected.Key && attr.Value.Emit() == expected.Value.Emit() {
+			return true
+		}
+	}
+	return false
+}
+
+func Test_PgStorage_CreatePaymentExchange(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' while opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	toAccount := entities.AccountID("bob")
+	payment := entities.Payment{
+		Account:   "alice",
+		Amount:    decimal.New(100, 0),
+		ToAccount: &toAccount,
+		Direction: entities.Outgoing,
+		Exchange: entities.NewExchange(decimal.New(100, 0), entities.ExchangeRate{
+			From:      "USD",
+			To:        "EUR",
+			Rate:      decimal.New(9, -1),
+			Timestamp: createdAt,
+		}),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("select id, account_id, currency, balance, coalesce\\(owner, ''\\), status, (.+) from accounts (.+) for update").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "account_id", "currency", "balance", "owner", "status", "available_balance"}).
+			AddRow(1, "alice", "USD", decimal.New(100, 0), "", "active", decimal.New(100, 0)).
+			AddRow(2, "bob", "EUR", decimal.New(200, 0), "", "active", decimal.New(200, 0)))
+	mock.ExpectQuery("select (.+) from payments").
+		WithArgs(payment.ID).
+		WillReturnRows(sqlmock.NewRows(paymentColumns))
+
+	// both legs are stored in the same transaction: source is debited in USD, destination is credited in EUR
+	mock.ExpectQuery("insert into payments").
+		WithArgs(payment.ID, 1, 2, payment.Amount, "completed", decimal.New(90, 0), decimal.New(9, -1), createdAt, "payment", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(createdAt))
+	expectBalanceUpdate(mock, payment.ID, 1, payment.Amount.Neg())
+	expectBalanceUpdate(mock, payment.ID, 2, decimal.New(90, 0))
+	mock.ExpectCommit()
+
+	storage := mydb.PgStorageFromHandle(db)
+	stored, storageErr := storage.CreatePayment(context.TODO(), payment)
+	if storageErr != nil {
+		t.Fatalf("Error while creating payment: %v", storageErr)
+	}
+	if stored.Exchange == nil || !stored.Exchange.DestinationAmount.Equal(decimal.New(90, 0)) {
+		t.Errorf("Expectation failed. Actual payment = %v", stored)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func Test_PgStorage_CreatePaymentDifferentCurrencies(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' while opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	toAccount := entities.AccountID("bob")
+	payment := entities.Payment{
+		Account:   "alice",
+		Amount:    decimal.New(100, 0),
+		ToAccount: &toAccount,
+		Direction: entities.Outgoing,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("select id, account_id, currency, balance, coalesce\\(owner, ''\\), status, (.+) from accounts (.+) for update").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "account_id", "currency", "balance", "owner", "status", "available_balance"}).
+			AddRow(1, "alice", "USD", decimal.New(100, 0), "", "active", decimal.New(100, 0)).
+			AddRow(2, "bob", "EUR", decimal.New(200, 0), "", "active", decimal.New(200, 0)))
+	mock.ExpectQuery("select (.+) from payments").
+		WithArgs(payment.ID).
+		WillReturnRows(sqlmock.NewRows(paymentColumns))
+	mock.ExpectRollback()
 
-	mock.ExpectExec("update accounts").
+	storage := mydb.PgStorageFromHandle(db)
+	_, storageErr := storage.CreatePayment(context.TODO(), payment)
+	if storageErr != entities.ErrDifferentCurrencies {
+		t.Errorf("Expectation failed. Expected error = %v, actual = %v", entities.ErrDifferentCurrencies, storageErr)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func Test_PgStorage_CreatePaymentInsufficientFunds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' while opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	toAccount := entities.AccountID("alice")
+	payment := entities.Payment{
+		Account:   "bob",
+		Amount:    decimal.New(150, 0),
+		ToAccount: &toAccount,
+		Direction: entities.Outgoing,
+	}
+
+	mock.ExpectBegin()
+	expectLockAccounts(mock, decimal.New(200, 0), decimal.New(100, 0))
+	mock.ExpectQuery("select (.+) from payments").
+		WithArgs(payment.ID).
+		WillReturnRows(sqlmock.NewRows(paymentColumns))
+	mock.ExpectRollback()
+
+	storage := mydb.PgStorageFromHandle(db)
+	_, storageErr := storage.CreatePayment(context.TODO(), payment)
+	if storageErr != entities.ErrInsufficientFunds {
+		t.Errorf("Expectation failed. Expected error = %v, actual = %v", entities.ErrInsufficientFunds, storageErr)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func Test_PgStorage_CreatePaymentCheckViolation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' while opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	toAccount := entities.AccountID("bob")
+	payment := entities.Payment{
+		Account:   "alice",
+		Amount:    decimal.New(100, 0),
+		ToAccount: &toAccount,
+		Direction: entities.Outgoing,
+	}
+
+	mock.ExpectBegin()
+	expectLockAccounts(mock, decimal.New(100, 0), decimal.New(200, 0))
+	mock.ExpectQuery("select (.+) from payments").
+		WithArgs(payment.ID).
+		WillReturnRows(sqlmock.NewRows(paymentColumns))
+	mock.ExpectQuery("insert into payments").
+		WithArgs(payment.ID, 1, 2, payment.Amount, "completed", nil, nil, nil, "payment", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(createdAt))
+	mock.ExpectQuery("update accounts").
 		WithArgs(payment.Amount.Neg(), 1).
-		WillReturnResult(sqlmock.NewResult(0, 1))
+		WillReturnError(&pq.Error{Code: "23514", Constraint: "balance_non_negative"})
+	mock.ExpectRollback()
 
-	mock.ExpectExec("update accounts").
-		WithArgs(payment.Amount, 2).
-		WillReturnResult(sqlmock.NewResult(0, 1))
+	storage := mydb.PgStorageFromHandle(db)
+	_, storageErr := storage.CreatePayment(context.TODO(), payment)
+	if storageErr != entities.ErrInsufficientFunds {
+		t.Errorf("Expectation failed. Expected error = %v, actual = %v", entities.ErrInsufficientFunds, storageErr)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func Test_PgStorage_CreatePaymentAccountNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' while opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	toAccount := entities.AccountID("mallory")
+	payment := entities.Payment{
+		Account:   "alice",
+		Amount:    decimal.New(100, 0),
+		ToAccount: &toAccount,
+		Direction: entities.Outgoing,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("select id, account_id, currency, balance, coalesce\\(owner, ''\\), status, (.+) from accounts (.+) for update").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "account_id", "currency", "balance", "owner", "status", "available_balance"}).
+			AddRow(1, "alice", "USD", decimal.New(100, 0), "", "active", decimal.New(100, 0)))
+	mock.ExpectRollback()
+
+	storage := mydb.PgStorageFromHandle(db)
+	_, storageErr := storage.CreatePayment(context.TODO(), payment)
+	if storageErr != entities.ErrPaymentDestinationNotFound {
+		t.Errorf("Expectation failed. Expected error = %v, actual = %v", entities.ErrPaymentDestinationNotFound, storageErr)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func Test_PgStorage_UpdateAccountStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		balance decimal.Decimal
+		current string
+		status  entities.AccountStatus
+		wantErr error
+	}{
+		{"freeze", decimal.New(100, 0), "active", entities.Frozen, nil},
+		{"close", decimal.New(0, 0), "frozen", entities.Closed, nil},
+		{"close_not_empty", decimal.New(100, 0), "active", entities.Closed, entities.ErrAccountNotEmpty},
+		{"reopen", decimal.New(0, 0), "closed", entities.Active, entities.ErrAccountClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("An error '%s' while opening a mock database connection", err)
+			}
+			defer db.Close()
 
+			mock.ExpectBegin()
+			mock.ExpectQuery("select id, account_id, currency, balance, coalesce\\(owner, ''\\), status, (.+) from accounts (.+) for update").
+				WithArgs(sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "account_id", "currency", "balance", "owner", "status", "available_balance"}).
+					AddRow(1, "alice", "USD", tt.balance, "", tt.current, tt.balance))
+			if tt.wantErr == nil {
+				mock.ExpectExec("update accounts set status").
+					WithArgs(tt.status.String(), 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			storage := mydb.PgStorageFromHandle(db)
+			acc, storageErr := storage.UpdateAccountStatus(context.TODO(), "alice", tt.status)
+			if storageErr != tt.wantErr {
+				t.Errorf("pgStorage.UpdateAccountStatus() error = %v, wantErr %v", storageErr, tt.wantErr)
+			}
+			if tt.wantErr == nil && acc.Status != tt.status {
+				t.Errorf("Expectation failed. Expected status = %v, actual = %v", tt.status, acc.Status)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func Test_PgStorage_CaptureHold(t *testing.T) {
+	id := uuid.New()
+	captured := decimal.New(40, 0)
+	tests := []struct {
+		name     string
+		status   string
+		captured *decimal.Decimal
+		amount   *decimal.Decimal
+		want     decimal.Decimal
+		wantErr  error
+	}{
+		{"full", "active", nil, nil, decimal.New(60, 0), nil},
+		{"partial", "active", nil, decimalPtr(decimal.New(40, 0)), decimal.New(40, 0), nil},
+		{"exceeds_hold", "active", nil, decimalPtr(decimal.New(70, 0)), decimal.Decimal{}, entities.ErrWrongCaptureAmount},
+		{"expired", "expired", nil, nil, decimal.Decimal{}, entities.ErrHoldNotActive},
+		{"retry", "captured", &captured, nil, captured, entities.ErrPaymentDuplicate},
+		{"retry_same_amount", "captured", &captured, decimalPtr(decimal.New(40, 0)), captured, entities.ErrPaymentDuplicate},
+		{"retry_other_amount", "captured", &captured, decimalPtr(decimal.New(30, 0)), decimal.Decimal{}, entities.ErrHoldNotActive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("An error '%s' while opening a mock database connection", err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery("select (.+) from holds (.+) for update of h").
+				WithArgs(id).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "source", "destination", "amount", "currency",
+					"captured_amount", "status", "created_at", "expires_at"}).
+					AddRow(id, "alice", "bob", decimal.New(60, 0), "USD", tt.captured, tt.status, createdAt, createdAt.Add(time.Hour)))
+			switch tt.wantErr {
+			case nil:
+				mock.ExpectExec("update holds set status").
+					WithArgs("captured", tt.want, id).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectLockAccounts(mock, decimal.New(100, 0), decimal.New(200, 0))
+				mock.ExpectQuery("select (.+) from payments").
+					WithArgs(id).
+					WillReturnRows(sqlmock.NewRows(paymentColumns))
+				mock.ExpectQuery("insert into payments").
+					WithArgs(id, 1, 2, tt.want, "completed", nil, nil, nil, "payment", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(createdAt))
+				expectBalanceUpdate(mock, id, 1, tt.want.Neg())
+				expectBalanceUpdate(mock, id, 2, tt.want)
+				mock.ExpectCommit()
+			case entities.ErrPaymentDuplicate:
+				mock.ExpectQuery("select (.+) from payments").
+					WithArgs(id).
+					WillReturnRows(sqlmock.NewRows(paymentColumns).
+						AddRow("alice", "bob", tt.want, createdAt, "completed", "USD", "USD", nil, nil, nil, "payment", nil))
+				mock.ExpectRollback()
+			default:
+				mock.ExpectRollback()
+			}
+
+			storage := mydb.PgStorageFromHandle(db)
+			payment, storageErr := storage.CaptureHold(context.TODO(), id, tt.amount)
+			if storageErr != tt.wantErr {
+				t.Errorf("pgStorage.CaptureHold() error = %v, wantErr %v", storageErr, tt.wantErr)
+			}
+			if (tt.wantErr == nil || tt.wantErr == entities.ErrPaymentDuplicate) && (payment.ID != id || !payment.Amount.Equal(tt.want)) {
+				t.Errorf("Expectation failed. Actual payment = %v", payment)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func Test_PgStorage_CreateRefund(t *testing.T) {
+	originalID, id := uuid.New(), uuid.New()
+	tests := []struct {
+		name     string
+		amount   decimal.Decimal
+		refunded decimal.Decimal
+		want     decimal.Decimal
+		wantErr  error
+	}{
+		{"partial", decimal.New(40, 0), decimal.New(0, 0), decimal.New(40, 0), nil},
+		{"rest", decimal.New(0, 0), decimal.New(20, 0), decimal.New(40, 0), nil},
+		{"exceeds_rest", decimal.New(50, 0), decimal.New(20, 0), decimal.Decimal{}, entities.ErrRefundExceedsPayment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("An error '%s' while opening a mock database connection", err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery("select (.+) from payments (.+) for update of p").
+				WithArgs(originalID).
+				WillReturnRows(sqlmock.NewRows(paymentColumns).
+					AddRow("alice", "bob", decimal.New(60, 0), createdAt, "completed", "USD", "USD", nil, nil, nil, "payment", nil))
+			mock.ExpectQuery("select coalesce\\(sum\\(amount\\), 0\\) from payments").
+				WithArgs(originalID, id).
+				WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(tt.refunded))
+			if tt.wantErr == nil {
+				expectLockAccounts(mock, decimal.New(100, 0), decimal.New(200, 0))
+				mock.ExpectQuery("select (.+) from payments").
+					WithArgs(id).
+					WillReturnRows(sqlmock.NewRows(paymentColumns))
+				mock.ExpectQuery("insert into payments").
+					WithArgs(id, 2, 1, tt.want, "completed", nil, nil, nil, "refund", originalID).
+					WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(createdAt))
+				expectBalanceUpdate(mock, id, 1, tt.want)
+				expectBalanceUpdate(mock, id, 2, tt.want.Neg())
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			storage := mydb.PgStorageFromHandle(db)
+			refund := entities.Payment{ID: id, Account: "bob", Amount: tt.amount, RefundOf: &originalID}
+			stored, storageErr := storage.CreateRefund(context.TODO(), refund)
+			if storageErr != tt.wantErr {
+				t.Errorf("pgStorage.CreateRefund() error = %v, wantErr %v", storageErr, tt.wantErr)
+			}
+			if tt.wantErr == nil && (stored.Type != entities.Refund || *stored.ToAccount != "alice" || !stored.Amount.Equal(tt.want)) {
+				t.Errorf("Expectation failed. Actual refund = %v", stored)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func Test_PgStorage_GetPayment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' while opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	id, refundOf := uuid.New(), uuid.New()
+	mock.ExpectQuery("select (.+) from payments (.+) where p.id = (.+);").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(paymentColumns).
+			AddRow("bob", "alice", decimal.New(20, 0), createdAt, "completed", "USD", "USD", nil, nil, nil, "refund", refundOf))
+	mock.ExpectQuery("select (.+) from payments").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnError(sql.ErrNoRows)
+
+	storage := mydb.PgStorageFromHandle(db)
+	record, storageErr := storage.GetPayment(context.TODO(), id)
+	if storageErr != nil {
+		t.Fatalf("pgStorage.GetPayment() error = %v", storageErr)
+	}
+	want := entities.PaymentRecord{
+		ID:          id,
+		Source:      "bob",
+		Destination: "alice",
+		Amount:      decimal.New(20, 0),
+		Currency:    "USD",
+		CreatedAt:   createdAt,
+		Status:      entities.Completed,
+		Type:        entities.Refund,
+		RefundOf:    &refundOf,
+	}
+	if !reflect.DeepEqual(*record, want) {
+		t.Errorf("pgStorage.GetPayment() = %v, want %v", *record, want)
+	}
+
+	if _, storageErr = storage.GetPayment(context.TODO(), uuid.New()); storageErr != entities.ErrPaymentNotFound {
+		t.Errorf("pgStorage.GetPayment() error = %v, wantErr %v", storageErr, entities.ErrPaymentNotFound)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func decimalPtr(value decimal.Decimal) *decimal.Decimal {
+	return &value
+}
+
+func Test_PgStorage_CreatePaymentRetry(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' while opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	toAccount := entities.AccountID("bob")
+	payment := entities.Payment{
+		Account:   "alice",
+		Amount:    decimal.New(100, 0),
+		ToAccount: &toAccount,
+		Direction: entities.Outgoing,
+	}
+
+	// serialization failure and deadlock are retried
+	for _, code := range []pq.ErrorCode{"40001", "40P01"} {
+		mock.ExpectBegin()
+		mock.ExpectQuery("select id, account_id, currency, balance, coalesce\\(owner, ''\\), status, (.+) from accounts (.+) for update").
+			WithArgs(sqlmock.AnyArg()).
+			WillReturnError(&pq.Error{Code: code})
+		mock.ExpectRollback()
+	}
+
+	mock.ExpectBegin()
+	expectLockAccounts(mock, decimal.New(100, 0), decimal.New(200, 0))
+	mock.ExpectQuery("select (.+) from payments").
+		WithArgs(payment.ID).
+		WillReturnRows(sqlmock.NewRows(paymentColumns))
+	mock.ExpectQuery("insert into payments").
+		WithArgs(payment.ID, 1, 2, payment.Amount, "completed", nil, nil, nil, "payment", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(createdAt))
+	expectBalanceUpdate(mock, payment.ID, 1, payment.Amount.Neg())
+	expectBalanceUpdate(mock, payment.ID, 2, payment.Amount)
 	mock.ExpectCommit()
 
 	storage := mydb.PgStorageFromHandle(db)
-	storageErr := storage.CreatePayment(context.TODO(), payment)
+	stored, storageErr := storage.CreatePayment(context.TODO(), payment)
 	if storageErr != nil {
-		t.Errorf("Error while creating payment: %v", storageErr)
+		t.Fatalf("Error while creating payment: %v", storageErr)
+	}
+	if !stored.CreatedAt.Equal(createdAt) || stored.Status != entities.Completed {
+		t.Errorf("Expectation failed. Actual payment = %v", stored)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func Test_PgStorage_CreatePaymentDuplicate(t *testing.T) {
+	toAccount := entities.AccountID("bob")
+	payment := entities.Payment{
+		Account:   "alice",
+		Amount:    decimal.New(100, 0),
+		ToAccount: &toAccount,
+		Direction: entities.Outgoing,
+	}
+
+	tests := []struct {
+		name         string
+		storedAmount decimal.Decimal
+		wantErr      error
+	}{
+		{"same_data", decimal.New(100, 0), entities.ErrPaymentDuplicate},
+		{"different_data", decimal.New(50, 0), entities.ErrPaymentAlreadyDone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("An error '%s' while opening a mock database connection", err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			expectLockAccounts(mock, decimal.New(0, 0), decimal.New(300, 0))
+			mock.ExpectQuery("select (.+) from payments").
+				WithArgs(payment.ID).
+				WillReturnRows(sqlmock.NewRows(paymentColumns).
+					AddRow("alice", "bob", tt.storedAmount, createdAt, "completed", "USD", "USD", nil, nil, nil, "payment", nil))
+			mock.ExpectRollback()
+
+			storage := mydb.PgStorageFromHandle(db)
+			stored, storageErr := storage.CreatePayment(context.TODO(), payment)
+			if storageErr != tt.wantErr {
+				t.Errorf("Expectation failed. Expected error = %v, actual = %v", tt.wantErr, storageErr)
+			}
+			if tt.wantErr == entities.ErrPaymentDuplicate && (stored == nil || !stored.CreatedAt.Equal(createdAt)) {
+				t.Errorf("Expectation failed. Expected original payment, actual = %v", stored)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func Test_PgStorage_PaymentsByAccount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' while opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	cursor := entities.PaymentCursor{CreatedAt: createdAt, ID: uuid.New()}
+	outgoing := entities.Outgoing
+	query := entities.PaymentsQuery{Limit: 2, Cursor: cursor.Encode(), Direction: &outgoing}
+
+	mock.ExpectQuery("select id, account_id, currency, balance, coalesce\\(owner, ''\\), status, (.+) from accounts").
+		WithArgs("alice").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "account_id", "currency", "balance", "owner", "status", "available_balance"}).
+			AddRow(1, "alice", "USD", decimal.New(100, 0), "", "active", decimal.New(100, 0)))
+
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	mock.ExpectQuery("from payments (.+) p.source_id = \\$1 and \\(p.created_at, p.id\\) > \\(\\$2, \\$3\\) (.+) limit \\$4").
+		WithArgs(1, cursor.CreatedAt, cursor.ID, 3).
+		WillReturnRows(sqlmock.NewRows(append([]string{"id"}, paymentColumns...)).
+			AddRow(ids[0], "alice", "bob", decimal.New(10, 0), createdAt.Add(time.Second), "completed", "USD", "USD", nil, nil, nil, "payment", nil).
+			AddRow(ids[1], "alice", "bob", decimal.New(20, 0), createdAt.Add(2*time.Second), "completed", "USD", "USD", nil, nil, nil, "payment", nil).
+			AddRow(ids[2], "alice", "bob", decimal.New(30, 0), createdAt.Add(3*time.Second), "completed", "USD", "USD", nil, nil, nil, "payment", nil))
+
+	storage := mydb.PgStorageFromHandle(db)
+	page, storageErr := storage.PaymentsByAccount(context.TODO(), "alice", query)
+	if storageErr != nil {
+		t.Fatalf("Error while quering payments: %v", storageErr)
+	}
+	if len(page.Payments) != 2 || page.Payments[1].ID != ids[1] || page.Payments[1].Direction != entities.Outgoing {
+		t.Errorf("Expectation failed. Actual payments = %v", page.Payments)
+	}
+
+	expectedCursor := entities.NewPaymentCursor(page.Payments[1]).Encode()
+	if page.NextCursor != expectedCursor {
+		t.Errorf("Expectation failed. Expected cursor = %v, actual = %v", expectedCursor, page.NextCursor)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func Test_PgStorage_BalanceAt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' while opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("select a.initial_balance \\+ coalesce\\(sum\\(e.amount\\), 0\\) (.+) e.id <= \\$2").
+		WithArgs("alice", int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(decimal.New(70, 0)))
+	mock.ExpectQuery("select a.initial_balance").
+		WithArgs("mallory", int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}))
+
+	storage := mydb.PgStorageFromHandle(db)
+	balance, storageErr := storage.BalanceAt(context.TODO(), "alice", 42)
+	if storageErr != nil || !balance.Equal(decimal.New(70, 0)) {
+		t.Errorf("Expectation failed. Actual balance = %v, error = %v", balance, storageErr)
+	}
+
+	if _, storageErr = storage.BalanceAt(context.TODO(), "mallory", 42); storageErr != entities.ErrAccountNotFound {
+		t.Errorf("Expectation failed. Expected error = %v, actual = %v", entities.ErrAccountNotFound, storageErr)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("Unfulfilled expectations: %v", err)
@@ -0,0 +1,202 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/shirolimit/wallet-service/pkg/entities"
+)
+
+// AccountMismatch is an account whose cached balance differs from the one recomputed from its history
+type AccountMismatch struct {
+	Account  entities.AccountID `json:"account"`
+	Currency string             `json:"currency"`
+	Balance  decimal.Decimal    `json:"balance"`
+
+	// Expected is initial balance plus incoming minus outgoing payments
+	Expected decimal.Decimal `json:"expected"`
+
+	// LedgerBalance is the balance of the last ledger entry of account
+	LedgerBalance decimal.Decimal `json:"ledger_balance"`
+}
+
+// CurrencyMismatch is a currency whose total of balances, including its external account, is not conserved:
+// it must be equal to the total of initial balances plus ledger entries, which differ from zero only
+// by the amounts exchanged into or out of other currencies. When initial balances are forbidden
+// their total must be zero as well, since all money comes from deposits
+type CurrencyMismatch struct {
+	Currency       string          `json:"currency"`
+	Total          decimal.Decimal `json:"total"`
+	Expected       decimal.Decimal `json:"expected"`
+	InitialBalance decimal.Decimal `json:"initial_balance"`
+}
+
+// ReconciliationReport is a result of balances reconciliation
+type ReconciliationReport struct {
+	Accounts           int                `json:"accounts"`
+	Mismatches         []AccountMismatch  `json:"mismatches"`
+	CurrencyMismatches []CurrencyMismatch `json:"currency_mismatches"`
+}
+
+// OK reports whether no mismatches have been found
+func (r ReconciliationReport) OK() bool {
+	return len(r.Mismatches) == 0 && len(r.CurrencyMismatches) == 0
+}
+
+// Reconciler checks balances of Postgres storage against payment history
+type Reconciler struct {
+	db *sql.DB
+
+	withoutInitialBalance bool
+}
+
+// ReconcilerOption is an optional setting of Reconciler
+type ReconcilerOption func(*Reconciler)
+
+// WithoutInitialBalance makes Reconciler check that total of initial balances of each currency is zero,
+// i.e. money of all accounts has come from deposits to external accounts. It catches drift absorbed into
+// initial balances by migration, which other checks can't see
+func WithoutInitialBalance() ReconcilerOption {
+	return func(r *Reconciler) {
+		r.withoutInitialBalance = true
+	}
+}
+
+// NewReconciler creates new Reconciler for specified sql.DB instance
+func NewReconciler(db *sql.DB, options ...ReconcilerOption) *Reconciler {
+	r := &Reconciler{db: db}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Reconcile recomputes balance of each account from its initial balance and payments, compares it
+// with cached and ledger balances and checks that total of each currency is conserved.
+// All checks are made on the same snapshot of database
+func (r *Reconciler) Reconcile(ctx context.Context) (ReconciliationReport, error) {
+	report := ReconciliationReport{
+		Mismatches:         []AccountMismatch{},
+		CurrencyMismatches: []CurrencyMismatch{},
+	}
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return report, err
+	}
+	defer tx.Rollback()
+
+	if err = tx.QueryRowContext(ctx, "select count(*) from accounts;").Scan(&report.Accounts); err != nil {
+		return report, err
+	}
+
+	if report.Mismatches, err = accountMismatches(ctx, tx); err != nil {
+		return report, err
+	}
+
+	if report.CurrencyMismatches, err = currencyMismatches(ctx, tx, r.withoutInitialBalance); err != nil {
+		return report, err
+	}
+	return report, tx.Commit()
+}
+
+func accountMismatches(ctx context.Context, tx *sql.Tx) ([]AccountMismatch, error) {
+	rows, err := tx.QueryContext(ctx, `select account_id, currency, balance, expected, ledger_balance from (
+			select a.account_id, a.currency, a.balance,
+				a.initial_balance
+					+ coalesce((select sum(coalesce(p.destination_amount, p.amount)) from payments as p where p.destination_id = a.id), 0)
+					- coalesce((select sum(p.amount) from payments as p where p.source_id = a.id), 0) as expected,
+				coalesce((select e.balance from ledger_entries as e where e.account_id = a.id order by e.id desc limit 1),
+					a.initial_balance) as ledger_balance
+			from accounts as a
+		) as r
+		where balance <> expected or balance <> ledger_balance
+		order by account_id collate "C";`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mismatches := []AccountMismatch{}
+	for rows.Next() {
+		var m AccountMismatch
+		if err = rows.Scan(&m.Account, &m.Currency, &m.Balance, &m.Expected, &m.LedgerBalance); err != nil {
+			return nil, err
+		}
+		mismatches = append(mismatches, m)
+	}
+	return mismatches, rows.Err()
+}
+
+func currencyMismatches(ctx context.Context, tx *sql.Tx, withoutInitialBalance bool) ([]CurrencyMismatch, error) {
+	totals := make(map[string]*CurrencyMismatch)
+	total := func(currency string) *CurrencyMismatch {
+		if _, ok := totals[currency]; !ok {
+			totals[currency] = &CurrencyMismatch{Currency: currency}
+		}
+		return totals[currency]
+	}
+
+	// total of each currency, including its external account, starts at the sum of initial balances
+	err := queryCurrencySums(ctx, tx, "select currency, sum(balance), sum(initial_balance) from accounts group by currency;",
+		func(currency string, sums ...decimal.Decimal) {
+			total(currency).Total = sums[0]
+			total(currency).InitialBalance = sums[1]
+			total(currency).Expected = total(currency).Expected.Add(sums[1])
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	// and is changed by ledger entries, which sum up to zero for payments within currency
+	err = queryCurrencySums(ctx, tx, `select a.currency, sum(e.amount) from ledger_entries as e
+			join accounts as a on a.id = e.account_id
+		group by a.currency;`,
+		func(currency string, sums ...decimal.Decimal) {
+			total(currency).Expected = total(currency).Expected.Add(sums[0])
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	mismatches := []CurrencyMismatch{}
+	for _, t := range totals {
+		if !t.Total.Equal(t.Expected) || (withoutInitialBalance && !t.InitialBalance.IsZero()) {
+			mismatches = append(mismatches, *t)
+		}
+	}
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i].Currency < mismatches[j].Currency })
+	return mismatches, nil
+}
+
+// queryCurrencySums runs query selecting currency and decimal sums and calls fn for each row
+func queryCurrencySums(ctx context.Context, tx *sql.Tx, query string, fn func(string, ...decimal.Decimal)) error {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var currency string
+		sums := make([]decimal.Decimal, len(columns)-1)
+		targets := []interface{}{&currency}
+		for i := range sums {
+			targets = append(targets, &sums[i])
+		}
+
+		if err = rows.Scan(targets...); err != nil {
+			return err
+		}
+		fn(currency, sums...)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,91 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
+
+	mydb "github.com/shirolimit/wallet-service/pkg/db"
+)
+
+// expectCurrencyTotals sets expectations for queries of currency totals: USD accounts have 20 more
+// than initial balances plus ledger entries, EUR ones have no initial balance and are conserved
+func expectCurrencyTotals(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("select currency, sum\\(balance\\), sum\\(initial_balance\\) from accounts group by currency").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "balance", "initial_balance"}).
+			AddRow("USD", decimal.New(331, 0), decimal.New(300, 0)).
+			AddRow("EUR", decimal.New(-10, 0), decimal.New(0, 0)))
+	mock.ExpectQuery("select a.currency, sum\\(e.amount\\) from ledger_entries").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "amount"}).
+			AddRow("USD", decimal.New(11, 0)).
+			AddRow("EUR", decimal.New(-10, 0)))
+}
+
+func Test_Reconciler_Reconcile(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' while opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("select count\\(\\*\\) from accounts").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery("where balance <> expected or balance <> ledger_balance").
+		WillReturnRows(sqlmock.NewRows([]string{"account_id", "currency", "balance", "expected", "ledger_balance"}).
+			AddRow("alice", "USD", decimal.New(120, 0), decimal.New(100, 0), decimal.New(100, 0)))
+	expectCurrencyTotals(mock)
+	mock.ExpectCommit()
+
+	report, err := mydb.NewReconciler(db).Reconcile(context.TODO())
+	if err != nil {
+		t.Fatalf("Error while reconciling balances: %v", err)
+	}
+	if report.OK() || report.Accounts != 3 {
+		t.Errorf("Expectation failed. Actual report = %v", report)
+	}
+	if len(report.Mismatches) != 1 || report.Mismatches[0].Account != "alice" {
+		t.Errorf("Expectation failed. Actual mismatches = %v", report.Mismatches)
+	}
+
+	currencies := report.CurrencyMismatches
+	if len(currencies) != 1 || currencies[0].Currency != "USD" || !currencies[0].Expected.Equal(decimal.New(311, 0)) {
+		t.Errorf("Expectation failed. Actual currency mismatches = %v", currencies)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func Test_Reconciler_ReconcileWithoutInitialBalance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' while opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("select count\\(\\*\\) from accounts").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+	mock.ExpectQuery("where balance <> expected or balance <> ledger_balance").
+		WillReturnRows(sqlmock.NewRows([]string{"account_id", "currency", "balance", "expected", "ledger_balance"}))
+	expectCurrencyTotals(mock)
+	mock.ExpectCommit()
+
+	report, err := mydb.NewReconciler(db, mydb.WithoutInitialBalance()).Reconcile(context.TODO())
+	if err != nil {
+		t.Fatalf("Error while reconciling balances: %v", err)
+	}
+
+	// EUR is still conserved, USD has non-zero initial balance absorbed by migration
+	currencies := report.CurrencyMismatches
+	if report.OK() || len(currencies) != 1 || currencies[0].Currency != "USD" ||
+		!currencies[0].InitialBalance.Equal(decimal.New(300, 0)) {
+		t.Errorf("Expectation failed. Actual currency mismatches = %v", currencies)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
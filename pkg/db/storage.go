@@ -3,15 +3,114 @@ package db
 import (
 	"context"
 
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
 	"github.com/shirolimit/wallet-service/pkg/entities"
 )
 
 // Storage is an interface to some persistent storage, for example relational database
 type Storage interface {
-	CreateAccount(context.Context, entities.Account) error
+	// CreateAccount stores new account and returns it as stored
+	CreateAccount(context.Context, entities.Account) (*entities.Account, error)
 	GetAccount(context.Context, entities.AccountID) (*entities.Account, error)
-	ListAccounts(context.Context) ([]entities.AccountID, error)
 
-	PaymentsByAccount(context.Context, entities.AccountID) ([]entities.Payment, error)
-	CreatePayment(context.Context, entities.Payment) error
+	// UpdateAccountStatus moves account into specified status and returns updated account.
+	// Closed accounts can't be changed and only accounts with zero balance can be closed
+	UpdateAccountStatus(context.Context, entities.AccountID, entities.AccountStatus) (*entities.Account, error)
+
+	// ListAccounts returns a page of accounts matching the query
+	ListAccounts(context.Context, entities.AccountsQuery) (entities.AccountsPage, error)
+
+	// GetPayment returns direction-neutral record of payment with specified ID
+	GetPayment(context.Context, uuid.UUID) (*entities.PaymentRecord, error)
+
+	// PaymentsByAccount returns a page of account payments matching the query, ordered by creation time
+	PaymentsByAccount(context.Context, entities.AccountID, entities.PaymentsQuery) (entities.PaymentsPage, error)
+
+	// CreatePayment stores payment, updates balances of both accounts and returns stored payment
+	// with server-assigned fields. Payments between accounts with different currencies must carry
+	// Exchange, the destination account is credited with its destination amount. Payment ID is an idempotency key: if the payment with the same ID
+	// and data already exists, the original payment is returned along with ErrPaymentDuplicate
+	// and balances are left untouched, the same ID with different data results in ErrPaymentAlreadyDone.
+	// Balances of external accounts are allowed to go negative
+	CreatePayment(context.Context, entities.Payment) (*entities.Payment, error)
+
+	// LedgerEntries returns ledger entries of account ordered from the oldest to the newest.
+	// Each payment has a debit entry of source account and a credit entry of destination account
+	LedgerEntries(context.Context, entities.AccountID) ([]entities.LedgerEntry, error)
+
+	// BalanceAt rebuilds account balance from its initial balance and ledger entries
+	// up to and including the entry with specified ID, zero ID means the initial balance
+	BalanceAt(context.Context, entities.AccountID, int64) (decimal.Decimal, error)
+
+	// CreateHold reserves funds of account for a future payment to destination account and returns
+	// stored hold with server-assigned fields. Held funds are excluded from available balance until
+	// the hold is captured, released or expires
+	CreateHold(context.Context, entities.Hold) (*entities.Hold, error)
+	GetHold(context.Context, uuid.UUID) (*entities.Hold, error)
+
+	// CaptureHold makes a payment of specified amount from active hold, nil amount captures the whole hold.
+	// The payment has the same ID as the hold, the rest of held amount is released.
+	// Capture of already captured hold with the same or nil amount returns its payment and ErrPaymentDuplicate
+	CaptureHold(context.Context, uuid.UUID, *decimal.Decimal) (*entities.Payment, error)
+
+	// ReleaseHold cancels active hold without payment
+	ReleaseHold(context.Context, uuid.UUID) (*entities.Hold, error)
+
+	// CreateRefund returns funds of the payment referenced by RefundOf from its destination back to its source.
+	// Zero amount refunds the whole amount that hasn't been refunded yet, total amount of refunds can't exceed
+	// the amount of refunded payment. Refund ID is an idempotency key just like payment ID of CreatePayment
+	CreateRefund(context.Context, entities.Payment) (*entities.Payment, error)
+
+	// Ping checks that storage is reachable
+	Ping(context.Context) error
+}
+
+// creditedAmount returns the amount credited to destination account of payment.
+// Exchange currencies and source amount must match the accounts and the payment
+func creditedAmount(payment entities.Payment, sourceCurrency, destinationCurrency string) (decimal.Decimal, error) {
+	exchange := payment.Exchange
+	if exchange == nil {
+		if sourceCurrency != destinationCurrency {
+			return decimal.Decimal{}, entities.ErrDifferentCurrencies
+		}
+		return payment.Amount, nil
+	}
+
+	if exchange.SourceCurrency != sourceCurrency || exchange.DestinationCurrency != destinationCurrency ||
+		!exchange.SourceAmount.Equal(payment.Amount) {
+		return decimal.Decimal{}, entities.ErrDifferentCurrencies
+	}
+	return exchange.DestinationAmount, nil
+}
+
+// checkRefundable checks that payment can be refunded
+func checkRefundable(payment entities.PaymentRecord) error {
+	if payment.Type != entities.RegularPayment || payment.Exchange != nil ||
+		payment.Source.IsExternal() || payment.Destination.IsExternal() {
+		return entities.ErrPaymentNotRefundable
+	}
+	return nil
+}
+
+// refundAmount returns the amount of refund given the amount of refunded payment and the amount
+// refunded so far. Zero requested amount means the whole amount that hasn't been refunded yet
+func refundAmount(requested, paid, refunded decimal.Decimal) (decimal.Decimal, error) {
+	remaining := paid.Sub(refunded)
+	if requested.IsZero() {
+		requested = remaining
+	}
+	if !requested.IsPositive() || requested.GreaterThan(remaining) {
+		return decimal.Decimal{}, entities.ErrRefundExceedsPayment
+	}
+	return requested, nil
+}
+
+// sameRefundOf reports whether both references point to the same refunded payment or both are nil
+func sameRefundOf(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
 }
@@ -7,7 +7,9 @@ package db
 import (
 	context "context"
 	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
 	entities "github.com/shirolimit/wallet-service/pkg/entities"
+	decimal "github.com/shopspring/decimal"
 	reflect "reflect"
 )
 
@@ -34,12 +36,43 @@ func (m *MockStorage) EXPECT() *MockStorageMockRecorder {
 	return m.recorder
 }
 
+// BalanceAt mocks base method
+func (m *MockStorage) BalanceAt(arg0 context.Context, arg1 entities.AccountID, arg2 int64) (decimal.Decimal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BalanceAt", arg0, arg1, arg2)
+	ret0, _ := ret[0].(decimal.Decimal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BalanceAt indicates an expected call of BalanceAt
+func (mr *MockStorageMockRecorder) BalanceAt(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BalanceAt", reflect.TypeOf((*MockStorage)(nil).BalanceAt), arg0, arg1, arg2)
+}
+
+// CaptureHold mocks base method
+func (m *MockStorage) CaptureHold(arg0 context.Context, arg1 uuid.UUID, arg2 *decimal.Decimal) (*entities.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureHold", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*entities.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureHold indicates an expected call of CaptureHold
+func (mr *MockStorageMockRecorder) CaptureHold(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHold", reflect.TypeOf((*MockStorage)(nil).CaptureHold), arg0, arg1, arg2)
+}
+
 // CreateAccount mocks base method
-func (m *MockStorage) CreateAccount(arg0 context.Context, arg1 entities.Account) error {
+func (m *MockStorage) CreateAccount(arg0 context.Context, arg1 entities.Account) (*entities.Account, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateAccount", arg0, arg1)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(*entities.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // CreateAccount indicates an expected call of CreateAccount
@@ -48,12 +81,28 @@ func (mr *MockStorageMockRecorder) CreateAccount(arg0, arg1 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockStorage)(nil).CreateAccount), arg0, arg1)
 }
 
+// CreateHold mocks base method
+func (m *MockStorage) CreateHold(arg0 context.Context, arg1 entities.Hold) (*entities.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHold", arg0, arg1)
+	ret0, _ := ret[0].(*entities.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateHold indicates an expected call of CreateHold
+func (mr *MockStorageMockRecorder) CreateHold(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHold", reflect.TypeOf((*MockStorage)(nil).CreateHold), arg0, arg1)
+}
+
 // CreatePayment mocks base method
-func (m *MockStorage) CreatePayment(arg0 context.Context, arg1 entities.Payment) error {
+func (m *MockStorage) CreatePayment(arg0 context.Context, arg1 entities.Payment) (*entities.Payment, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreatePayment", arg0, arg1)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(*entities.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // CreatePayment indicates an expected call of CreatePayment
@@ -62,6 +111,21 @@ func (mr *MockStorageMockRecorder) CreatePayment(arg0, arg1 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePayment", reflect.TypeOf((*MockStorage)(nil).CreatePayment), arg0, arg1)
 }
 
+// CreateRefund mocks base method
+func (m *MockStorage) CreateRefund(arg0 context.Context, arg1 entities.Payment) (*entities.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRefund", arg0, arg1)
+	ret0, _ := ret[0].(*entities.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRefund indicates an expected call of CreateRefund
+func (mr *MockStorageMockRecorder) CreateRefund(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRefund", reflect.TypeOf((*MockStorage)(nil).CreateRefund), arg0, arg1)
+}
+
 // GetAccount mocks base method
 func (m *MockStorage) GetAccount(arg0 context.Context, arg1 entities.AccountID) (*entities.Account, error) {
 	m.ctrl.T.Helper()
@@ -77,32 +141,121 @@ func (mr *MockStorageMockRecorder) GetAccount(arg0, arg1 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockStorage)(nil).GetAccount), arg0, arg1)
 }
 
+// GetHold mocks base method
+func (m *MockStorage) GetHold(arg0 context.Context, arg1 uuid.UUID) (*entities.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHold", arg0, arg1)
+	ret0, _ := ret[0].(*entities.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHold indicates an expected call of GetHold
+func (mr *MockStorageMockRecorder) GetHold(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHold", reflect.TypeOf((*MockStorage)(nil).GetHold), arg0, arg1)
+}
+
+// GetPayment mocks base method
+func (m *MockStorage) GetPayment(arg0 context.Context, arg1 uuid.UUID) (*entities.PaymentRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPayment", arg0, arg1)
+	ret0, _ := ret[0].(*entities.PaymentRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPayment indicates an expected call of GetPayment
+func (mr *MockStorageMockRecorder) GetPayment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPayment", reflect.TypeOf((*MockStorage)(nil).GetPayment), arg0, arg1)
+}
+
+// LedgerEntries mocks base method
+func (m *MockStorage) LedgerEntries(arg0 context.Context, arg1 entities.AccountID) ([]entities.LedgerEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LedgerEntries", arg0, arg1)
+	ret0, _ := ret[0].([]entities.LedgerEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LedgerEntries indicates an expected call of LedgerEntries
+func (mr *MockStorageMockRecorder) LedgerEntries(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LedgerEntries", reflect.TypeOf((*MockStorage)(nil).LedgerEntries), arg0, arg1)
+}
+
 // ListAccounts mocks base method
-func (m *MockStorage) ListAccounts(arg0 context.Context) ([]entities.AccountID, error) {
+func (m *MockStorage) ListAccounts(arg0 context.Context, arg1 entities.AccountsQuery) (entities.AccountsPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListAccounts", arg0)
-	ret0, _ := ret[0].([]entities.AccountID)
+	ret := m.ctrl.Call(m, "ListAccounts", arg0, arg1)
+	ret0, _ := ret[0].(entities.AccountsPage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ListAccounts indicates an expected call of ListAccounts
-func (mr *MockStorageMockRecorder) ListAccounts(arg0 interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) ListAccounts(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccounts", reflect.TypeOf((*MockStorage)(nil).ListAccounts), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccounts", reflect.TypeOf((*MockStorage)(nil).ListAccounts), arg0, arg1)
 }
 
 // PaymentsByAccount mocks base method
-func (m *MockStorage) PaymentsByAccount(arg0 context.Context, arg1 entities.AccountID) ([]entities.Payment, error) {
+func (m *MockStorage) PaymentsByAccount(arg0 context.Context, arg1 entities.AccountID, arg2 entities.PaymentsQuery) (entities.PaymentsPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "PaymentsByAccount", arg0, arg1)
-	ret0, _ := ret[0].([]entities.Payment)
+	ret := m.ctrl.Call(m, "PaymentsByAccount", arg0, arg1, arg2)
+	ret0, _ := ret[0].(entities.PaymentsPage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // PaymentsByAccount indicates an expected call of PaymentsByAccount
-func (mr *MockStorageMockRecorder) PaymentsByAccount(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) PaymentsByAccount(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PaymentsByAccount", reflect.TypeOf((*MockStorage)(nil).PaymentsByAccount), arg0, arg1, arg2)
+}
+
+// Ping mocks base method
+func (m *MockStorage) Ping(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping
+func (mr *MockStorageMockRecorder) Ping(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockStorage)(nil).Ping), arg0)
+}
+
+// ReleaseHold mocks base method
+func (m *MockStorage) ReleaseHold(arg0 context.Context, arg1 uuid.UUID) (*entities.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseHold", arg0, arg1)
+	ret0, _ := ret[0].(*entities.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseHold indicates an expected call of ReleaseHold
+func (mr *MockStorageMockRecorder) ReleaseHold(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseHold", reflect.TypeOf((*MockStorage)(nil).ReleaseHold), arg0, arg1)
+}
+
+// UpdateAccountStatus mocks base method
+func (m *MockStorage) UpdateAccountStatus(arg0 context.Context, arg1 entities.AccountID, arg2 entities.AccountStatus) (*entities.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAccountStatus", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*entities.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAccountStatus indicates an expected call of UpdateAccountStatus
+func (mr *MockStorageMockRecorder) UpdateAccountStatus(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PaymentsByAccount", reflect.TypeOf((*MockStorage)(nil).PaymentsByAccount), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccountStatus", reflect.TypeOf((*MockStorage)(nil).UpdateAccountStatus), arg0, arg1, arg2)
 }
@@ -5,8 +5,10 @@ import (
 	"errors"
 
 	"github.com/go-kit/kit/endpoint"
+	"github.com/google/uuid"
 	"github.com/shirolimit/wallet-service/pkg/entities"
 	"github.com/shirolimit/wallet-service/pkg/service"
+	"github.com/shopspring/decimal"
 )
 
 // CreateAccountRequest is a request struct for CreateAccount method
@@ -32,19 +34,24 @@ func MakeCreateAccountEndpoint(ws service.WalletService) endpoint.Endpoint {
 		if !ok {
 			return nil, errors.New("CreateAccount request type error")
 		}
-		err := ws.CreateAccount(ctx, req.Account)
-		return CreateAccountResponse{Account: req.Account, Error: err}, nil
+		acc, err := ws.CreateAccount(ctx, req.Account)
+		return CreateAccountResponse{Account: acc, Error: err}, nil
 	}
 }
 
 // ListAccountsRequest is a request struct for ListAccounts method
 type ListAccountsRequest struct {
+	Query entities.AccountsQuery
+
+	// Full requests full account records instead of IDs only
+	Full bool
 }
 
 // ListAccountsResponse is a response struct for ListAccounts method
 type ListAccountsResponse struct {
-	Accounts []entities.AccountID
-	Error    error
+	Page  entities.AccountsPage
+	Full  bool
+	Error error
 }
 
 // Failed is a Failer method implementation
@@ -52,11 +59,24 @@ func (r *ListAccountsResponse) Failed() error {
 	return r.Error
 }
 
+// IDs returns IDs of listed accounts
+func (r *ListAccountsResponse) IDs() []entities.AccountID {
+	ids := make([]entities.AccountID, 0, len(r.Page.Accounts))
+	for _, acc := range r.Page.Accounts {
+		ids = append(ids, acc.ID)
+	}
+	return ids
+}
+
 // MakeListAccountsEndpoint constructs ListAccounts endpoint
 func MakeListAccountsEndpoint(ws service.WalletService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		accounts, err := ws.ListAccounts(ctx)
-		return ListAccountsResponse{Accounts: accounts, Error: err}, nil
+		req, ok := request.(ListAccountsRequest)
+		if !ok {
+			return nil, errors.New("ListAccounts request type error")
+		}
+		page, err := ws.ListAccounts(ctx, req.Query)
+		return ListAccountsResponse{Page: page, Full: req.Full, Error: err}, nil
 	}
 }
 
@@ -88,15 +108,45 @@ func MakeGetAccountEndpoint(ws service.WalletService) endpoint.Endpoint {
 	}
 }
 
+// UpdateAccountRequest is a request struct for UpdateAccount method
+type UpdateAccountRequest struct {
+	ID     entities.AccountID
+	Update entities.AccountUpdate
+}
+
+// UpdateAccountResponse is a response struct for UpdateAccount method
+type UpdateAccountResponse struct {
+	Account entities.Account
+	Error   error
+}
+
+// Failed is a Failure method implementation
+func (r *UpdateAccountResponse) Failed() error {
+	return r.Error
+}
+
+// MakeUpdateAccountEndpoint constructs UpdateAccount endpoint
+func MakeUpdateAccountEndpoint(ws service.WalletService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(UpdateAccountRequest)
+		if !ok {
+			return nil, errors.New("UpdateAccount request type error")
+		}
+		acc, err := ws.UpdateAccount(ctx, req.ID, req.Update)
+		return UpdateAccountResponse{Account: acc, Error: err}, nil
+	}
+}
+
 // GetPaymentsRequest is a request struct for GetPayments method
 type GetPaymentsRequest struct {
 	AccountID entities.AccountID
+	Query     entities.PaymentsQuery
 }
 
 // GetPaymentsResponse is a response struct for GetPayments method
 type GetPaymentsResponse struct {
-	Payments []entities.Payment
-	Error    error
+	Page  entities.PaymentsPage
+	Error error
 }
 
 // Failed is a Failure method implementation
@@ -111,8 +161,36 @@ func MakeGetPaymentsEndpoint(ws service.WalletService) endpoint.Endpoint {
 		if !ok {
 			return nil, errors.New("GetPayments request type error")
 		}
-		payments, err := ws.GetPayments(ctx, req.AccountID)
-		return GetPaymentsResponse{Payments: payments, Error: err}, nil
+		page, err := ws.GetPayments(ctx, req.AccountID, req.Query)
+		return GetPaymentsResponse{Page: page, Error: err}, nil
+	}
+}
+
+// GetPaymentRequest is a request struct for GetPayment method
+type GetPaymentRequest struct {
+	ID uuid.UUID
+}
+
+// GetPaymentResponse is a response struct for GetPayment method
+type GetPaymentResponse struct {
+	Payment entities.PaymentRecord
+	Error   error
+}
+
+// Failed is a Failure method implementation
+func (r *GetPaymentResponse) Failed() error {
+	return r.Error
+}
+
+// MakeGetPaymentEndpoint constructs GetPayment endpoint
+func MakeGetPaymentEndpoint(ws service.WalletService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(GetPaymentRequest)
+		if !ok {
+			return nil, errors.New("GetPayment request type error")
+		}
+		payment, err := ws.GetPayment(ctx, req.ID)
+		return GetPaymentResponse{Payment: payment, Error: err}, nil
 	}
 }
 
@@ -125,6 +203,10 @@ type MakePaymentRequest struct {
 type MakePaymentResponse struct {
 	Payment entities.Payment
 	Error   error
+
+	// Replayed is set when the payment with the same ID and data
+	// has been already made and its result is returned again
+	Replayed bool
 }
 
 // Failed is a Failure method implementation
@@ -139,7 +221,155 @@ func MakeMakePaymentsEndpoint(ws service.WalletService) endpoint.Endpoint {
 		if !ok {
 			return nil, errors.New("MakePayment request type error")
 		}
-		err := ws.MakePayment(ctx, req.Payment)
-		return MakePaymentResponse{Payment: req.Payment, Error: err}, nil
+		payment, err := ws.MakePayment(ctx, req.Payment)
+		if err == entities.ErrPaymentDuplicate {
+			return MakePaymentResponse{Payment: payment, Replayed: true}, nil
+		}
+		return MakePaymentResponse{Payment: payment, Error: err}, nil
+	}
+}
+
+// DepositRequest is a request struct for Deposit method
+type DepositRequest struct {
+	Payment entities.Payment
+}
+
+// MakeDepositEndpoint constructs Deposit endpoint, it responds with MakePaymentResponse
+func MakeDepositEndpoint(ws service.WalletService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(DepositRequest)
+		if !ok {
+			return nil, errors.New("Deposit request type error")
+		}
+		payment, err := ws.Deposit(ctx, req.Payment)
+		if err == entities.ErrPaymentDuplicate {
+			return MakePaymentResponse{Payment: payment, Replayed: true}, nil
+		}
+		return MakePaymentResponse{Payment: payment, Error: err}, nil
+	}
+}
+
+// WithdrawRequest is a request struct for Withdraw method
+type WithdrawRequest struct {
+	Payment entities.Payment
+}
+
+// MakeWithdrawEndpoint constructs Withdraw endpoint, it responds with MakePaymentResponse
+func MakeWithdrawEndpoint(ws service.WalletService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(WithdrawRequest)
+		if !ok {
+			return nil, errors.New("Withdraw request type error")
+		}
+		payment, err := ws.Withdraw(ctx, req.Payment)
+		if err == entities.ErrPaymentDuplicate {
+			return MakePaymentResponse{Payment: payment, Replayed: true}, nil
+		}
+		return MakePaymentResponse{Payment: payment, Error: err}, nil
+	}
+}
+
+// CreateHoldRequest is a request struct for CreateHold method
+type CreateHoldRequest struct {
+	Hold entities.Hold
+}
+
+// HoldResponse is a response struct for CreateHold, GetHold and ReleaseHold methods
+type HoldResponse struct {
+	Hold  entities.Hold
+	Error error
+}
+
+// Failed is a Failure method implementation
+func (r *HoldResponse) Failed() error {
+	return r.Error
+}
+
+// MakeCreateHoldEndpoint constructs CreateHold endpoint
+func MakeCreateHoldEndpoint(ws service.WalletService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(CreateHoldRequest)
+		if !ok {
+			return nil, errors.New("CreateHold request type error")
+		}
+		hold, err := ws.CreateHold(ctx, req.Hold)
+		return HoldResponse{Hold: hold, Error: err}, nil
+	}
+}
+
+// GetHoldRequest is a request struct for GetHold method
+type GetHoldRequest struct {
+	ID uuid.UUID
+}
+
+// MakeGetHoldEndpoint constructs GetHold endpoint
+func MakeGetHoldEndpoint(ws service.WalletService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(GetHoldRequest)
+		if !ok {
+			return nil, errors.New("GetHold request type error")
+		}
+		hold, err := ws.GetHold(ctx, req.ID)
+		return HoldResponse{Hold: hold, Error: err}, nil
+	}
+}
+
+// CaptureHoldRequest is a request struct for CaptureHold method
+type CaptureHoldRequest struct {
+	ID uuid.UUID
+
+	// Amount is nil to capture the whole hold
+	Amount *decimal.Decimal
+}
+
+// MakeCaptureHoldEndpoint constructs CaptureHold endpoint, it responds with MakePaymentResponse
+func MakeCaptureHoldEndpoint(ws service.WalletService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(CaptureHoldRequest)
+		if !ok {
+			return nil, errors.New("CaptureHold request type error")
+		}
+		payment, err := ws.CaptureHold(ctx, req.ID, req.Amount)
+		if err == entities.ErrPaymentDuplicate {
+			return MakePaymentResponse{Payment: payment, Replayed: true}, nil
+		}
+		return MakePaymentResponse{Payment: payment, Error: err}, nil
+	}
+}
+
+// ReleaseHoldRequest is a request struct for ReleaseHold method
+type ReleaseHoldRequest struct {
+	ID uuid.UUID
+}
+
+// MakeReleaseHoldEndpoint constructs ReleaseHold endpoint
+func MakeReleaseHoldEndpoint(ws service.WalletService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(ReleaseHoldRequest)
+		if !ok {
+			return nil, errors.New("ReleaseHold request type error")
+		}
+		hold, err := ws.ReleaseHold(ctx, req.ID)
+		return HoldResponse{Hold: hold, Error: err}, nil
+	}
+}
+
+// RefundPaymentRequest is a request struct for RefundPayment method
+type RefundPaymentRequest struct {
+	Refund entities.Payment
+}
+
+// MakeRefundPaymentEndpoint constructs RefundPayment endpoint, it responds with MakePaymentResponse
+func MakeRefundPaymentEndpoint(ws service.WalletService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(RefundPaymentRequest)
+		if !ok {
+			return nil, errors.New("RefundPayment request type error")
+		}
+		payment, err := ws.RefundPayment(ctx, req.Refund)
+		if err == entities.ErrPaymentDuplicate {
+			return MakePaymentResponse{Payment: payment, Replayed: true}, nil
+		}
+		return MakePaymentResponse{Payment: payment, Error: err}, nil
 	}
 }
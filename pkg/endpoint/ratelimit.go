@@ -0,0 +1,191 @@
+package endpoint
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/shirolimit/wallet-service/pkg/entities"
+	"golang.org/x/time/rate"
+)
+
+// maxTrackedAccounts is a number of per-account limiters after which least recently used ones are dropped
+const maxTrackedAccounts = 10000
+
+// RateLimit is a token bucket configuration, zero Rate means unlimited
+type RateLimit struct {
+	// Rate is a number of requests per second allowed on average
+	Rate float64
+
+	// Burst is a number of requests allowed at once, it is at least 1
+	Burst int
+}
+
+// EndpointLimits are rate limits of a single endpoint
+type EndpointLimits struct {
+	// Global limits all requests to endpoint
+	Global RateLimit
+
+	// PerAccount limits requests to endpoint related to the same account
+	PerAccount RateLimit
+}
+
+// Limits are rate limits of all endpoints of Set, zero value means no limits
+type Limits struct {
+	CreateAccount EndpointLimits
+	ListAccounts  EndpointLimits
+	GetAccount    EndpointLimits
+	UpdateAccount EndpointLimits
+	GetPayments   EndpointLimits
+	GetPayment    EndpointLimits
+	MakePayment   EndpointLimits
+	Deposit       EndpointLimits
+	Withdraw      EndpointLimits
+	CreateHold    EndpointLimits
+	GetHold       EndpointLimits
+	CaptureHold   EndpointLimits
+	ReleaseHold   EndpointLimits
+	RefundPayment EndpointLimits
+}
+
+// RateLimitError is returned by rate limiting middlewares for over-limit requests
+type RateLimitError struct {
+	// RetryAfter is a time after which request is likely to be allowed
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return entities.ErrTooManyRequests.Error()
+}
+
+// Unwrap returns ErrTooManyRequests
+func (e *RateLimitError) Unwrap() error {
+	return entities.ErrTooManyRequests
+}
+
+// RetryAfterSeconds returns RetryAfter rounded up to whole seconds, as Retry-After header requires
+func (e *RateLimitError) RetryAfterSeconds() int {
+	return int(math.Max(1, math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// accountRequest is implemented by requests related to a single account
+type accountRequest interface {
+	accountID() entities.AccountID
+}
+
+func (r CreateAccountRequest) accountID() entities.AccountID { return r.Account.ID }
+func (r GetAccountRequest) accountID() entities.AccountID    { return r.ID }
+func (r UpdateAccountRequest) accountID() entities.AccountID { return r.ID }
+func (r GetPaymentsRequest) accountID() entities.AccountID   { return r.AccountID }
+func (r MakePaymentRequest) accountID() entities.AccountID   { return r.Payment.Account }
+func (r DepositRequest) accountID() entities.AccountID       { return r.Payment.Account }
+func (r WithdrawRequest) accountID() entities.AccountID      { return r.Payment.Account }
+func (r CreateHoldRequest) accountID() entities.AccountID    { return r.Hold.Account }
+func (r RefundPaymentRequest) accountID() entities.AccountID { return r.Refund.Account }
+
+// RateLimitingMiddleware returns endpoint middleware which applies global and per-account limits.
+// Per-account limit is keyed on AccountID of the request, requests without account are not limited by it
+func RateLimitingMiddleware(limits EndpointLimits) endpoint.Middleware {
+	global := newLimiter(limits.Global)
+	accounts := newAccountLimiters(limits.PerAccount)
+
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			now := time.Now()
+			var accountReservation *rate.Reservation
+			if req, ok := request.(accountRequest); ok && accounts != nil {
+				r, err := reserve(accounts.get(req.accountID()), now)
+				if err != nil {
+					return nil, err
+				}
+				accountReservation = r
+			}
+			if global != nil {
+				if _, err := reserve(global, now); err != nil {
+					// rejected request must not consume account's token
+					if accountReservation != nil {
+						accountReservation.CancelAt(now)
+					}
+					return nil, err
+				}
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// newLimiter creates token bucket limiter, nil means unlimited
+func newLimiter(limit RateLimit) *rate.Limiter {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	burst := limit.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(limit.Rate), burst)
+}
+
+// reserve takes a token from limiter if it is available at now, the token can be returned
+// by cancelling reservation. Otherwise it returns RateLimitError with the time when the token will be available
+func reserve(limiter *rate.Limiter, now time.Time) (*rate.Reservation, error) {
+	r := limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return nil, &RateLimitError{RetryAfter: time.Second}
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return nil, &RateLimitError{RetryAfter: delay}
+	}
+	return r, nil
+}
+
+// accountLimiters keeps a token bucket for each of maxTrackedAccounts recently used accounts.
+// Dropping the least recently used one is safe as long as its bucket has refilled by the time
+// the account comes back, which is the case for any realistic traffic
+type accountLimiters struct {
+	limit RateLimit
+
+	mutex    sync.Mutex
+	limiters map[entities.AccountID]*list.Element
+	lru      *list.List // of *accountLimiter, most recently used first
+}
+
+type accountLimiter struct {
+	id      entities.AccountID
+	limiter *rate.Limiter
+}
+
+// newAccountLimiters creates per-account limiters, nil means unlimited
+func newAccountLimiters(limit RateLimit) *accountLimiters {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	return &accountLimiters{
+		limit:    limit,
+		limiters: make(map[entities.AccountID]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+func (al *accountLimiters) get(id entities.AccountID) *rate.Limiter {
+	al.mutex.Lock()
+	defer al.mutex.Unlock()
+
+	if element, ok := al.limiters[id]; ok {
+		al.lru.MoveToFront(element)
+		return element.Value.(*accountLimiter).limiter
+	}
+
+	if al.lru.Len() >= maxTrackedAccounts {
+		oldest := al.lru.Back()
+		al.lru.Remove(oldest)
+		delete(al.limiters, oldest.Value.(*accountLimiter).id)
+	}
+	limiter := newLimiter(al.limit)
+	al.limiters[id] = al.lru.PushFront(&accountLimiter{id: id, limiter: limiter})
+	return limiter
+}
@@ -0,0 +1,71 @@
+package endpoint_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shirolimit/wallet-service/pkg/endpoint"
+	"github.com/shirolimit/wallet-service/pkg/entities"
+)
+
+func Test_RateLimitingMiddleware(t *testing.T) {
+	limits := endpoint.EndpointLimits{
+		Global:     endpoint.RateLimit{Rate: 0.01, Burst: 3},
+		PerAccount: endpoint.RateLimit{Rate: 0.01, Burst: 2},
+	}
+	limited := endpoint.RateLimitingMiddleware(limits)(func(ctx context.Context, request interface{}) (interface{}, error) {
+		return request, nil
+	})
+
+	tests := []struct {
+		name    string
+		request interface{}
+		wantErr bool
+	}{
+		{"first_of_alice", endpoint.GetAccountRequest{ID: "alice"}, false},
+		{"second_of_alice", endpoint.GetAccountRequest{ID: "alice"}, false},
+		{"alice_over_account_limit", endpoint.GetAccountRequest{ID: "alice"}, true},
+		{"bob", endpoint.GetAccountRequest{ID: "bob"}, false},
+		{"over_global_limit", endpoint.ListAccountsRequest{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := limited(context.TODO(), tt.request)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RateLimitingMiddleware() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				return
+			}
+
+			limitErr, ok := err.(*endpoint.RateLimitError)
+			if !ok || limitErr.Unwrap() != entities.ErrTooManyRequests || limitErr.RetryAfterSeconds() < 1 {
+				t.Errorf("RateLimitingMiddleware() error = %#v, want RateLimitError", err)
+			}
+		})
+	}
+}
+
+func Test_RateLimitingMiddleware_GlobalRejectKeepsAccountToken(t *testing.T) {
+	limits := endpoint.EndpointLimits{
+		Global:     endpoint.RateLimit{Rate: 20, Burst: 1},
+		PerAccount: endpoint.RateLimit{Rate: 0.01, Burst: 1},
+	}
+	limited := endpoint.RateLimitingMiddleware(limits)(func(ctx context.Context, request interface{}) (interface{}, error) {
+		return request, nil
+	})
+
+	if _, err := limited(context.TODO(), endpoint.GetAccountRequest{ID: "bob"}); err != nil {
+		t.Fatalf("RateLimitingMiddleware() error = %v, want nil", err)
+	}
+	if _, err := limited(context.TODO(), endpoint.GetAccountRequest{ID: "alice"}); err == nil {
+		t.Fatalf("RateLimitingMiddleware() error = nil, want global limit error")
+	}
+
+	// global bucket refills in 50ms, account one would take 100s
+	time.Sleep(100 * time.Millisecond)
+	if _, err := limited(context.TODO(), endpoint.GetAccountRequest{ID: "alice"}); err != nil {
+		t.Errorf("RateLimitingMiddleware() error = %v, want account token returned", err)
+	}
+}
@@ -3,25 +3,52 @@ package endpoint
 import (
 	endpoint "github.com/go-kit/kit/endpoint"
 	"github.com/shirolimit/wallet-service/pkg/service"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Set is a helper struct for endpoints
 type Set struct {
 	CreateAccountEndpoint endpoint.Endpoint
 	GetAccountEndpoint    endpoint.Endpoint
+	UpdateAccountEndpoint endpoint.Endpoint
 	ListAccountsEndpoint  endpoint.Endpoint
 	GetPaymentsEndpoint   endpoint.Endpoint
+	GetPaymentEndpoint    endpoint.Endpoint
 	MakePaymentEndpoint   endpoint.Endpoint
+	DepositEndpoint       endpoint.Endpoint
+	WithdrawEndpoint      endpoint.Endpoint
+	CreateHoldEndpoint    endpoint.Endpoint
+	GetHoldEndpoint       endpoint.Endpoint
+	CaptureHoldEndpoint   endpoint.Endpoint
+	ReleaseHoldEndpoint   endpoint.Endpoint
+	RefundPaymentEndpoint endpoint.Endpoint
 }
 
-// NewEndpointSet creates new endpoint set
-func NewEndpointSet(ws service.WalletService) Set {
+// NewEndpointSet creates new endpoint set, each endpoint is traced with specified tracer
+// and rate limited with its limits. Specified middlewares, i.e. authentication,
+// are applied to all endpoints between tracing and rate limiting
+func NewEndpointSet(ws service.WalletService, tracer trace.Tracer, limits Limits, middlewares ...endpoint.Middleware) Set {
+	wrap := func(e endpoint.Endpoint, name string, limits EndpointLimits) endpoint.Endpoint {
+		chain := append([]endpoint.Middleware{TracingMiddleware(tracer, name)}, middlewares...)
+		chain = append(chain, RateLimitingMiddleware(limits))
+		return endpoint.Chain(chain[0], chain[1:]...)(e)
+	}
+
 	set := Set{
-		CreateAccountEndpoint: MakeCreateAccountEndpoint(ws),
-		ListAccountsEndpoint:  MakeListAccountsEndpoint(ws),
-		GetAccountEndpoint:    MakeGetAccountEndpoint(ws),
-		GetPaymentsEndpoint:   MakeGetPaymentsEndpoint(ws),
-		MakePaymentEndpoint:   MakeMakePaymentsEndpoint(ws),
+		CreateAccountEndpoint: wrap(MakeCreateAccountEndpoint(ws), "CreateAccount", limits.CreateAccount),
+		ListAccountsEndpoint:  wrap(MakeListAccountsEndpoint(ws), "ListAccounts", limits.ListAccounts),
+		GetAccountEndpoint:    wrap(MakeGetAccountEndpoint(ws), "GetAccount", limits.GetAccount),
+		UpdateAccountEndpoint: wrap(MakeUpdateAccountEndpoint(ws), "UpdateAccount", limits.UpdateAccount),
+		GetPaymentsEndpoint:   wrap(MakeGetPaymentsEndpoint(ws), "GetPayments", limits.GetPayments),
+		GetPaymentEndpoint:    wrap(MakeGetPaymentEndpoint(ws), "GetPayment", limits.GetPayment),
+		MakePaymentEndpoint:   wrap(MakeMakePaymentsEndpoint(ws), "MakePayment", limits.MakePayment),
+		DepositEndpoint:       wrap(MakeDepositEndpoint(ws), "Deposit", limits.Deposit),
+		WithdrawEndpoint:      wrap(MakeWithdrawEndpoint(ws), "Withdraw", limits.Withdraw),
+		CreateHoldEndpoint:    wrap(MakeCreateHoldEndpoint(ws), "CreateHold", limits.CreateHold),
+		GetHoldEndpoint:       wrap(MakeGetHoldEndpoint(ws), "GetHold", limits.GetHold),
+		CaptureHoldEndpoint:   wrap(MakeCaptureHoldEndpoint(ws), "CaptureHold", limits.CaptureHold),
+		ReleaseHoldEndpoint:   wrap(MakeReleaseHoldEndpoint(ws), "ReleaseHold", limits.ReleaseHold),
+		RefundPaymentEndpoint: wrap(MakeRefundPaymentEndpoint(ws), "RefundPayment", limits.RefundPayment),
 	}
 	return set
 }
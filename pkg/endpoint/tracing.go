@@ -0,0 +1,27 @@
+package endpoint
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware returns endpoint middleware which starts a span named after the endpoint.
+// Business errors are returned inside of responses, they are recorded by service spans
+func TracingMiddleware(tracer trace.Tracer, name string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			ctx, span := tracer.Start(ctx, "endpoint."+name)
+			defer span.End()
+
+			response, err := next(ctx, request)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			return response, err
+		}
+	}
+}
@@ -2,6 +2,7 @@ package entities
 
 import (
 	"encoding/json"
+	"strings"
 
 	"github.com/shopspring/decimal"
 )
@@ -9,11 +10,38 @@ import (
 // AccountID is an identifier of account
 type AccountID string
 
+// externalAccountPrefix starts IDs of system accounts representing external funding sources
+const externalAccountPrefix = "external:"
+
+// ExternalAccountID returns ID of system account that deposits in specified currency come from
+// and withdrawals go to. Its balance is negated total of money brought into the system
+func ExternalAccountID(currency string) AccountID {
+	return AccountID(externalAccountPrefix + currency)
+}
+
+// IsExternal reports whether ID is reserved for system account of external funding source
+func (id AccountID) IsExternal() bool {
+	return strings.HasPrefix(string(id), externalAccountPrefix)
+}
+
 // Account struct represents an user account in the system
 type Account struct {
 	ID       AccountID       `json:"id"`
 	Currency string          `json:"currency"`
 	Balance  decimal.Decimal `json:"balance"`
+	Status   AccountStatus   `json:"status"`
+
+	// AvailableBalance is the balance minus funds reserved by active holds, it is assigned by server
+	AvailableBalance decimal.Decimal `json:"available_balance"`
+
+	// Owner is a subject of principal allowed to debit account and read its payments,
+	// accounts without owner are available to admins only
+	Owner string `json:"owner,omitempty"`
+}
+
+// AccountUpdate is a partial update of account, nil fields are left unchanged
+type AccountUpdate struct {
+	Status *AccountStatus `json:"status,omitempty"`
 }
 
 // String implements Stringer interface for logging
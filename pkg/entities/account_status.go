@@ -0,0 +1,84 @@
+package entities
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+//go:generate stringer -type AccountStatus -linecomment
+
+// AccountStatus is an enum describing account lifecycle states
+type AccountStatus int
+
+const (
+	// Active account can send and receive payments
+	Active AccountStatus = iota // active
+
+	// Frozen account can receive payments but can't be debited
+	Frozen // frozen
+
+	// Closed account can neither send nor receive payments, it can't be reopened
+	Closed // closed
+)
+
+// ParseAccountStatus converts string representation into AccountStatus
+func ParseAccountStatus(str string) (AccountStatus, error) {
+	switch str {
+	case "active":
+		return Active, nil
+
+	case "frozen":
+		return Frozen, nil
+
+	case "closed":
+		return Closed, nil
+
+	default:
+		return Active, ErrInvalidAccountStatus
+	}
+}
+
+// MarshalJSON is used for JSON marshaling
+func (as AccountStatus) MarshalJSON() ([]byte, error) {
+	buffer := bytes.NewBufferString(`"`)
+	buffer.WriteString(as.String())
+	buffer.WriteString(`"`)
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalJSON is used for JSON unmarshaling
+func (as *AccountStatus) UnmarshalJSON(data []byte) error {
+	var str string
+	err := json.Unmarshal(data, &str)
+	if err != nil {
+		return err
+	}
+
+	*as, err = ParseAccountStatus(str)
+	return err
+}
+
+// CheckStatusChange returns an error if account can't be moved into specified status.
+// Closed accounts can't be changed and only accounts with zero balance can be closed
+func (a Account) CheckStatusChange(status AccountStatus) error {
+	switch {
+	case a.Status == status:
+		return nil
+	case a.Status == Closed:
+		return ErrAccountClosed
+	case status == Closed && !a.Balance.IsZero():
+		return ErrAccountNotEmpty
+	}
+	return nil
+}
+
+// CheckPaymentAccounts returns an error if statuses of accounts don't allow to move money between them
+func CheckPaymentAccounts(source, destination Account) error {
+	switch {
+	case source.Status == Closed || destination.Status == Closed:
+		return ErrAccountClosed
+	case source.Status == Frozen:
+		return ErrAccountFrozen
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package entities
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// AccountsSort is an order of accounts list
+type AccountsSort string
+
+const (
+	// SortByID orders accounts by ID ascending, it is the default order
+	SortByID AccountsSort = "id"
+
+	// SortByIDDesc orders accounts by ID descending
+	SortByIDDesc AccountsSort = "-id"
+
+	// SortByBalance orders accounts by balance ascending, accounts with equal balances are ordered by ID
+	SortByBalance AccountsSort = "balance"
+
+	// SortByBalanceDesc orders accounts by balance descending, accounts with equal balances are ordered by ID descending
+	SortByBalanceDesc AccountsSort = "-balance"
+)
+
+// ParseAccountsSort converts string representation into AccountsSort, empty string means default order
+func ParseAccountsSort(str string) (AccountsSort, error) {
+	switch AccountsSort(str) {
+	case "":
+		return SortByID, nil
+
+	case SortByID, SortByIDDesc, SortByBalance, SortByBalanceDesc:
+		return AccountsSort(str), nil
+
+	default:
+		return SortByID, ErrInvalidAccountsQuery
+	}
+}
+
+// AccountsQuery describes search and keyset pagination of accounts list.
+// Empty and nil filters are not applied
+type AccountsQuery struct {
+	// Limit is a maximum number of accounts in a page, zero means no limit
+	Limit int
+
+	// Cursor is a NextCursor of the previous page requested with the same Sort
+	Cursor string
+
+	// IDPrefix selects accounts which IDs start with specified string
+	IDPrefix string
+
+	// Owner selects accounts of specified principal
+	Owner string
+
+	Currency   string
+	MinBalance *decimal.Decimal
+	MaxBalance *decimal.Decimal
+
+	Sort AccountsSort
+}
+
+// AccountsPage is a single page of accounts list
+type AccountsPage struct {
+	Accounts []Account `json:"accounts"`
+
+	// NextCursor is used to request the next page, it is empty for the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// AccountCursor is a decoded position in accounts list
+type AccountCursor struct {
+	Balance decimal.Decimal
+	ID      AccountID
+}
+
+// NewAccountCursor creates cursor pointing right after specified account
+func NewAccountCursor(acc Account) AccountCursor {
+	return AccountCursor{Balance: acc.Balance, ID: acc.ID}
+}
+
+// Encode converts cursor into opaque string
+func (c AccountCursor) Encode() string {
+	raw := c.Balance.String() + "|" + string(c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// After reports whether account goes after cursor position in specified order
+func (c AccountCursor) After(acc Account, sort AccountsSort) bool {
+	switch sort {
+	case SortByIDDesc:
+		return acc.ID < c.ID
+
+	case SortByBalance:
+		cmp := acc.Balance.Cmp(c.Balance)
+		return cmp > 0 || (cmp == 0 && acc.ID > c.ID)
+
+	case SortByBalanceDesc:
+		cmp := acc.Balance.Cmp(c.Balance)
+		return cmp < 0 || (cmp == 0 && acc.ID < c.ID)
+
+	default:
+		return acc.ID > c.ID
+	}
+}
+
+// DecodeAccountCursor parses cursor created by AccountCursor.Encode
+func DecodeAccountCursor(cursor string) (AccountCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return AccountCursor{}, ErrInvalidCursor
+	}
+
+	// account ID goes last because it may contain separator
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return AccountCursor{}, ErrInvalidCursor
+	}
+
+	balance, err := decimal.NewFromString(parts[0])
+	if err != nil {
+		return AccountCursor{}, ErrInvalidCursor
+	}
+	return AccountCursor{Balance: balance, ID: AccountID(parts[1])}, nil
+}
+
+// Match reports whether account satisfies query filters, cursor and limit are not checked
+func (q AccountsQuery) Match(acc Account) bool {
+	if !strings.HasPrefix(string(acc.ID), q.IDPrefix) {
+		return false
+	}
+
+	if q.Owner != "" && acc.Owner != q.Owner {
+		return false
+	}
+
+	if q.Currency != "" && acc.Currency != q.Currency {
+		return false
+	}
+
+	if q.MinBalance != nil && acc.Balance.LessThan(*q.MinBalance) {
+		return false
+	}
+
+	if q.MaxBalance != nil && acc.Balance.GreaterThan(*q.MaxBalance) {
+		return false
+	}
+	return true
+}
+
+// Less reports whether account a goes before account b in specified order
+func (s AccountsSort) Less(a, b Account) bool {
+	return NewAccountCursor(a).After(b, s)
+}
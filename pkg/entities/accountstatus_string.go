@@ -0,0 +1,26 @@
+// Code generated by "stringer -type AccountStatus -linecomment"; DO NOT EDIT.
+
+package entities
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[Active-0]
+	_ = x[Frozen-1]
+	_ = x[Closed-2]
+}
+
+const _AccountStatus_name = "activefrozenclosed"
+
+var _AccountStatus_index = [...]uint8{0, 6, 12, 18}
+
+func (i AccountStatus) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_AccountStatus_index)-1 {
+		return "AccountStatus(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _AccountStatus_name[_AccountStatus_index[idx]:_AccountStatus_index[idx+1]]
+}
@@ -0,0 +1,121 @@
+package entities
+
+import (
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// Currency describes a currency known to the service
+type Currency struct {
+	// Code is an ISO 4217 alphabetic code, i.e. "USD"
+	Code string `json:"code"`
+
+	// MinorUnits is the number of digits after the decimal point allowed in amounts
+	MinorUnits int32 `json:"minor_units"`
+
+	// Enabled currencies can be used for new accounts and payments
+	Enabled bool `json:"enabled"`
+}
+
+// ValidAmount reports whether amount is not finer than the minor unit of currency
+func (c Currency) ValidAmount(amount decimal.Decimal) bool {
+	return amount.Equal(amount.Truncate(c.MinorUnits))
+}
+
+// Round rounds amount to the minor unit of currency
+func (c Currency) Round(amount decimal.Decimal) decimal.Decimal {
+	return amount.Round(c.MinorUnits)
+}
+
+// CurrencyRegistry is a set of currencies known to the service, it is safe for concurrent use
+// as it can't be modified after creation
+type CurrencyRegistry struct {
+	currencies map[string]Currency
+}
+
+// NewCurrencyRegistry creates registry of specified currencies
+func NewCurrencyRegistry(currencies ...Currency) *CurrencyRegistry {
+	registry := &CurrencyRegistry{
+		currencies: make(map[string]Currency, len(currencies)),
+	}
+	for _, c := range currencies {
+		registry.currencies[c.Code] = c
+	}
+	return registry
+}
+
+// DefaultCurrencyRegistry contains all ISO 4217 currencies, all of them are enabled
+var DefaultCurrencyRegistry = NewCurrencyRegistry(ISO4217Currencies()...)
+
+// Lookup returns enabled currency with specified code.
+// Codes are case sensitive, so "usd" is not the same as "USD"
+func (r *CurrencyRegistry) Lookup(code string) (Currency, error) {
+	c, ok := r.currencies[code]
+	if !ok {
+		return Currency{}, ErrUnknownCurrency
+	}
+	if !c.Enabled {
+		return Currency{}, ErrCurrencyDisabled
+	}
+	return c, nil
+}
+
+// EnabledOnly returns a copy of registry where only currencies with specified codes are enabled
+func (r *CurrencyRegistry) EnabledOnly(codes ...string) (*CurrencyRegistry, error) {
+	enabled := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		if _, ok := r.currencies[code]; !ok {
+			return nil, ErrUnknownCurrency
+		}
+		enabled[code] = true
+	}
+
+	currencies := r.Currencies()
+	for i := range currencies {
+		currencies[i].Enabled = enabled[currencies[i].Code]
+	}
+	return NewCurrencyRegistry(currencies...), nil
+}
+
+// Currencies returns all currencies of the registry ordered by code
+func (r *CurrencyRegistry) Currencies() []Currency {
+	currencies := make([]Currency, 0, len(r.currencies))
+	for _, c := range r.currencies {
+		currencies = append(currencies, c)
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Code < currencies[j].Code })
+	return currencies
+}
+
+// ISO4217Currencies returns enabled Currency for each active ISO 4217 code except precious metals,
+// testing and special codes
+func ISO4217Currencies() []Currency {
+	currencies := make([]Currency, 0, len(iso4217MinorUnits))
+	for code, minorUnits := range iso4217MinorUnits {
+		currencies = append(currencies, Currency{Code: code, MinorUnits: minorUnits, Enabled: true})
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Code < currencies[j].Code })
+	return currencies
+}
+
+// iso4217MinorUnits maps ISO 4217 codes to the number of their minor units
+var iso4217MinorUnits = map[string]int32{
+	"AED": 2, "AFN": 2, "ALL": 2, "AMD": 2, "ANG": 2, "AOA": 2, "ARS": 2, "AUD": 2, "AWG": 2, "AZN": 2,
+	"BAM": 2, "BBD": 2, "BDT": 2, "BGN": 2, "BHD": 3, "BIF": 0, "BMD": 2, "BND": 2, "BOB": 2, "BOV": 2,
+	"BRL": 2, "BSD": 2, "BTN": 2, "BWP": 2, "BYN": 2, "BZD": 2, "CAD": 2, "CDF": 2, "CHE": 2, "CHF": 2,
+	"CHW": 2, "CLF": 4, "CLP": 0, "CNY": 2, "COP": 2, "COU": 2, "CRC": 2, "CUP": 2, "CVE": 2, "CZK": 2,
+	"DJF": 0, "DKK": 2, "DOP": 2, "DZD": 2, "EGP": 2, "ERN": 2, "ETB": 2, "EUR": 2, "FJD": 2, "FKP": 2,
+	"GBP": 2, "GEL": 2, "GHS": 2, "GIP": 2, "GMD": 2, "GNF": 0, "GTQ": 2, "GYD": 2, "HKD": 2, "HNL": 2,
+	"HTG": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "IQD": 3, "IRR": 2, "ISK": 0, "JMD": 2, "JOD": 3,
+	"JPY": 0, "KES": 2, "KGS": 2, "KHR": 2, "KMF": 0, "KPW": 2, "KRW": 0, "KWD": 3, "KYD": 2, "KZT": 2,
+	"LAK": 2, "LBP": 2, "LKR": 2, "LRD": 2, "LSL": 2, "LYD": 3, "MAD": 2, "MDL": 2, "MGA": 2, "MKD": 2,
+	"MMK": 2, "MNT": 2, "MOP": 2, "MRU": 2, "MUR": 2, "MVR": 2, "MWK": 2, "MXN": 2, "MXV": 2, "MYR": 2,
+	"MZN": 2, "NAD": 2, "NGN": 2, "NIO": 2, "NOK": 2, "NPR": 2, "NZD": 2, "OMR": 3, "PAB": 2, "PEN": 2,
+	"PGK": 2, "PHP": 2, "PKR": 2, "PLN": 2, "PYG": 0, "QAR": 2, "RON": 2, "RSD": 2, "RUB": 2, "RWF": 0,
+	"SAR": 2, "SBD": 2, "SCR": 2, "SDG": 2, "SEK": 2, "SGD": 2, "SHP": 2, "SLE": 2, "SOS": 2, "SRD": 2,
+	"SSP": 2, "STN": 2, "SVC": 2, "SYP": 2, "SZL": 2, "THB": 2, "TJS": 2, "TMT": 2, "TND": 3, "TOP": 2,
+	"TRY": 2, "TTD": 2, "TWD": 2, "TZS": 2, "UAH": 2, "UGX": 0, "USD": 2, "USN": 2, "UYI": 0, "UYU": 2,
+	"UYW": 4, "UZS": 2, "VED": 2, "VES": 2, "VND": 0, "VUV": 0, "WST": 2, "XAF": 0, "XCD": 2, "XCG": 2,
+	"XOF": 0, "XPF": 0, "YER": 2, "ZAR": 2, "ZMW": 2, "ZWG": 2,
+}
@@ -7,8 +7,9 @@ var (
 	ErrInsufficientFunds          = errors.New("Insufficient funds to make a payment")
 	ErrAccountNotFound            = errors.New("Account not found")
 	ErrRecipientNotFound          = errors.New("Recipient account not found")
-	ErrDifferentCurrencies        = errors.New("Payments with currency exchange are not supported")
+	ErrDifferentCurrencies        = errors.New("Currency exchange is not configured or doesn't match the accounts")
 	ErrPaymentAlreadyDone         = errors.New("Specified payment has already been completed")
+	ErrPaymentDuplicate           = errors.New("Payment with the same ID and data has already been completed")
 	ErrDatabaseConnection         = errors.New("Database connection error")
 	ErrIncomingPaymentsNotAllowed = errors.New("Incoming payments are not allowed")
 	ErrWrongPaymentAmount         = errors.New("Wrong payment amount")
@@ -21,4 +22,30 @@ var (
 	ErrPaymentSourceNotFound      = errors.New("Payment source account does not exist")
 	ErrPaymentDestinationNotFound = errors.New("Payment destination account does not exist")
 	ErrPaymentSameAccount         = errors.New("Payment source and destination accounts cannot be identical")
+	ErrInvalidCursor              = errors.New("Invalid pagination cursor")
+	ErrInvalidLimit               = errors.New("Page limit must be a positive number")
+	ErrInvalidPaymentsQuery       = errors.New("Invalid payments query")
+	ErrInvalidAccountsQuery       = errors.New("Invalid accounts query")
+	ErrExchangeRateNotFound       = errors.New("Exchange rate for the currency pair is not available")
+	ErrUnknownCurrency            = errors.New("Unknown currency, use ISO 4217 currency code")
+	ErrCurrencyDisabled           = errors.New("Currency is not enabled")
+	ErrAmountPrecision            = errors.New("Amount is finer than the minor unit of currency")
+	ErrTooManyRequests            = errors.New("Too many requests, retry later")
+	ErrUnauthorized               = errors.New("Missing or invalid API key or bearer token")
+	ErrForbidden                  = errors.New("Operation on the account is not permitted")
+	ErrInvalidAccountStatus       = errors.New("Account status must be one of active, frozen or closed")
+	ErrAccountFrozen              = errors.New("Account is frozen and can't be debited")
+	ErrAccountClosed              = errors.New("Account is closed")
+	ErrAccountNotEmpty            = errors.New("Only accounts with zero balance can be closed")
+	ErrReservedAccountID          = errors.New("Account ID is reserved for system accounts")
+	ErrInitialBalanceNotAllowed   = errors.New("Initial balance must be zero, use deposits to fund accounts")
+	ErrEmptyHoldID                = errors.New("Hold ID cannot be empty, use a unique GUID here")
+	ErrHoldAlreadyExists          = errors.New("Hold with the same ID already exists")
+	ErrHoldNotFound               = errors.New("Hold not found")
+	ErrHoldNotActive              = errors.New("Hold has already been captured, released or has expired")
+	ErrInvalidHoldExpiry          = errors.New("Hold expiration time must be in the future and within the maximum hold duration")
+	ErrWrongCaptureAmount         = errors.New("Capture amount must be positive and can't exceed held amount")
+	ErrPaymentNotFound            = errors.New("Payment not found")
+	ErrPaymentNotRefundable       = errors.New("Only regular payments between internal accounts with the same currency can be refunded")
+	ErrRefundExceedsPayment       = errors.New("Total amount of refunds can't exceed the amount of refunded payment")
 )
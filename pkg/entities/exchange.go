@@ -0,0 +1,39 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ExchangeRate is a price of one unit of From currency in To currency
+type ExchangeRate struct {
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Rate      decimal.Decimal `json:"rate"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// Exchange describes currency conversion of cross-currency payment
+type Exchange struct {
+	SourceCurrency      string          `json:"source_currency"`
+	SourceAmount        decimal.Decimal `json:"source_amount"`
+	DestinationCurrency string          `json:"destination_currency"`
+	DestinationAmount   decimal.Decimal `json:"destination_amount"`
+
+	// Rate is a price of one unit of source currency in destination currency
+	Rate          decimal.Decimal `json:"rate"`
+	RateTimestamp time.Time       `json:"rate_timestamp"`
+}
+
+// NewExchange converts amount of payment using specified rate
+func NewExchange(amount decimal.Decimal, rate ExchangeRate) *Exchange {
+	return &Exchange{
+		SourceCurrency:      rate.From,
+		SourceAmount:        amount,
+		DestinationCurrency: rate.To,
+		DestinationAmount:   amount.Mul(rate.Rate),
+		Rate:                rate.Rate,
+		RateTimestamp:       rate.Timestamp.UTC(),
+	}
+}
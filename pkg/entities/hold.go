@@ -0,0 +1,103 @@
+package entities
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+//go:generate stringer -type HoldStatus -linecomment
+
+// HoldStatus is an enum describing hold states
+type HoldStatus int
+
+const (
+	// HoldActive hold reserves funds of account until it is captured, released or expires
+	HoldActive HoldStatus = iota // active
+
+	// HoldCaptured hold has been turned into a payment, the rest of held amount is released
+	HoldCaptured // captured
+
+	// HoldReleased hold has been cancelled without payment
+	HoldReleased // released
+
+	// HoldExpired hold has not been captured or released before its expiration time
+	HoldExpired // expired
+)
+
+// ParseHoldStatus converts string representation into HoldStatus
+func ParseHoldStatus(str string) (HoldStatus, error) {
+	switch str {
+	case "active":
+		return HoldActive, nil
+
+	case "captured":
+		return HoldCaptured, nil
+
+	case "released":
+		return HoldReleased, nil
+
+	case "expired":
+		return HoldExpired, nil
+
+	default:
+		return HoldActive, errors.New("Unable to parse Hold status")
+	}
+}
+
+// MarshalJSON is used for JSON marshaling
+func (hs HoldStatus) MarshalJSON() ([]byte, error) {
+	buffer := bytes.NewBufferString(`"`)
+	buffer.WriteString(hs.String())
+	buffer.WriteString(`"`)
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalJSON is used for JSON unmarshaling
+func (hs *HoldStatus) UnmarshalJSON(data []byte) error {
+	var str string
+	err := json.Unmarshal(data, &str)
+	if err != nil {
+		return err
+	}
+
+	*hs, err = ParseHoldStatus(str)
+	return err
+}
+
+// Hold reserves funds of account for a future payment to destination account.
+// Held funds are excluded from available balance of account until the hold
+// is captured, released or expires
+type Hold struct {
+	// ID is an unique identifier for the hold, the payment made by capture has the same ID
+	ID uuid.UUID `json:"id"`
+
+	// Account is an ID of account whose funds are held
+	Account   AccountID       `json:"account"`
+	ToAccount AccountID       `json:"to_account"`
+	Amount    decimal.Decimal `json:"amount"`
+
+	// Currency is a currency of both accounts, it is assigned by server
+	Currency string `json:"currency"`
+
+	// CapturedAmount is set for captured holds, the rest of Amount is released
+	CapturedAmount *decimal.Decimal `json:"captured_amount,omitempty"`
+
+	Status HoldStatus `json:"status"`
+
+	// CreatedAt is a time when hold was stored, it is assigned by server in UTC
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// String implements Stringer interface for logging
+func (h Hold) String() string {
+	if data, err := json.Marshal(h); err == nil {
+		return string(data)
+	}
+	return "hold"
+}
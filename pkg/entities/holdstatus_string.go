@@ -0,0 +1,27 @@
+// Code generated by "stringer -type HoldStatus -linecomment"; DO NOT EDIT.
+
+package entities
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[HoldActive-0]
+	_ = x[HoldCaptured-1]
+	_ = x[HoldReleased-2]
+	_ = x[HoldExpired-3]
+}
+
+const _HoldStatus_name = "activecapturedreleasedexpired"
+
+var _HoldStatus_index = [...]uint8{0, 6, 14, 22, 29}
+
+func (i HoldStatus) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_HoldStatus_index)-1 {
+		return "HoldStatus(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _HoldStatus_name[_HoldStatus_index[idx]:_HoldStatus_index[idx+1]]
+}
@@ -0,0 +1,24 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// LedgerEntry is a single change of account balance. Each payment has
+// a debit entry of source account and a credit entry of destination account
+type LedgerEntry struct {
+	ID        int64     `json:"id"`
+	PaymentID uuid.UUID `json:"payment_id"`
+	Account   AccountID `json:"account"`
+
+	// Amount is positive for credits and negative for debits
+	Amount decimal.Decimal `json:"amount"`
+
+	// Balance is the balance of account after the entry
+	Balance decimal.Decimal `json:"balance"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -2,6 +2,7 @@ package entities
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
@@ -18,11 +19,28 @@ type Payment struct {
 	Amount    decimal.Decimal  `json:"amount"`
 	Direction PaymentDirection `json:"direction"`
 
+	// Currency is a currency of Amount, that is the currency of source account.
+	// It is assigned by server
+	Currency string `json:"currency"`
+
 	// ToAccount is a destination account ID for Outgoing payments
 	ToAccount *AccountID `json:"to_account,omitempty"`
 
 	// FromAccount is a source account ID for Incoming payments
 	FromAccount *AccountID `json:"from_account,omitempty"`
+
+	// CreatedAt is a time when payment was stored, it is assigned by server in UTC
+	CreatedAt time.Time     `json:"created_at"`
+	Status    PaymentStatus `json:"status"`
+
+	// Exchange is set for payments between accounts with different currencies, it is assigned by server
+	Exchange *Exchange `json:"exchange,omitempty"`
+
+	// Type is assigned by server, refunds are created only by refunding payments
+	Type PaymentType `json:"type"`
+
+	// RefundOf is an ID of refunded payment, it is set for refunds only
+	RefundOf *uuid.UUID `json:"refund_of,omitempty"`
 }
 
 // String implements Stringer interface for logging
@@ -32,3 +50,34 @@ func (p Payment) String() string {
 	}
 	return "payment"
 }
+
+// PaymentRecord is a direction-neutral view of payment as it is seen by the system
+// rather than by one of its accounts
+type PaymentRecord struct {
+	ID          uuid.UUID       `json:"id"`
+	Source      AccountID       `json:"source"`
+	Destination AccountID       `json:"destination"`
+	Amount      decimal.Decimal `json:"amount"`
+
+	// Currency is a currency of Amount, that is the currency of source account
+	Currency string `json:"currency"`
+
+	// CreatedAt is a time when payment was stored in UTC
+	CreatedAt time.Time     `json:"created_at"`
+	Status    PaymentStatus `json:"status"`
+
+	// Exchange is set for payments between accounts with different currencies
+	Exchange *Exchange   `json:"exchange,omitempty"`
+	Type     PaymentType `json:"type"`
+
+	// RefundOf is an ID of refunded payment, it is set for refunds only
+	RefundOf *uuid.UUID `json:"refund_of,omitempty"`
+}
+
+// String implements Stringer interface for logging
+func (r PaymentRecord) String() string {
+	if data, err := json.Marshal(r); err == nil {
+		return string(data)
+	}
+	return "payment record"
+}
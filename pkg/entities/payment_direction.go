@@ -35,16 +35,20 @@ func (pd *PaymentDirection) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
+	*pd, err = ParsePaymentDirection(str)
+	return err
+}
+
+// ParsePaymentDirection converts string representation into PaymentDirection
+func ParsePaymentDirection(str string) (PaymentDirection, error) {
 	switch str {
 	case "outgoing":
-		*pd = Outgoing
-		return nil
+		return Outgoing, nil
 
 	case "incoming":
-		*pd = Incoming
-		return nil
+		return Incoming, nil
 
 	default:
-		return errors.New("Unable to deserialize Payment direction")
+		return Incoming, errors.New("Unable to deserialize Payment direction")
 	}
 }
@@ -0,0 +1,48 @@
+package entities
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+//go:generate stringer -type PaymentStatus -linecomment
+
+// PaymentStatus is an enum describing payment states
+type PaymentStatus int
+
+const (
+	// Completed payment has moved money between accounts
+	Completed PaymentStatus = iota // completed
+)
+
+// ParsePaymentStatus converts string representation into PaymentStatus
+func ParsePaymentStatus(str string) (PaymentStatus, error) {
+	switch str {
+	case "completed":
+		return Completed, nil
+
+	default:
+		return Completed, errors.New("Unable to parse Payment status")
+	}
+}
+
+// MarshalJSON is used for JSON marshaling
+func (ps PaymentStatus) MarshalJSON() ([]byte, error) {
+	buffer := bytes.NewBufferString(`"`)
+	buffer.WriteString(ps.String())
+	buffer.WriteString(`"`)
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalJSON is used for JSON unmarshaling
+func (ps *PaymentStatus) UnmarshalJSON(data []byte) error {
+	var str string
+	err := json.Unmarshal(data, &str)
+	if err != nil {
+		return err
+	}
+
+	*ps, err = ParsePaymentStatus(str)
+	return err
+}
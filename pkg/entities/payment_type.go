@@ -0,0 +1,54 @@
+package entities
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+//go:generate stringer -type PaymentType -linecomment
+
+// PaymentType is an enum describing kinds of payments
+type PaymentType int
+
+const (
+	// RegularPayment is a transfer requested by source account
+	RegularPayment PaymentType = iota // payment
+
+	// Refund returns funds of earlier payment from its destination back to its source
+	Refund // refund
+)
+
+// ParsePaymentType converts string representation into PaymentType
+func ParsePaymentType(str string) (PaymentType, error) {
+	switch str {
+	case "payment":
+		return RegularPayment, nil
+
+	case "refund":
+		return Refund, nil
+
+	default:
+		return RegularPayment, errors.New("Unable to parse Payment type")
+	}
+}
+
+// MarshalJSON is used for JSON marshaling
+func (pt PaymentType) MarshalJSON() ([]byte, error) {
+	buffer := bytes.NewBufferString(`"`)
+	buffer.WriteString(pt.String())
+	buffer.WriteString(`"`)
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalJSON is used for JSON unmarshaling
+func (pt *PaymentType) UnmarshalJSON(data []byte) error {
+	var str string
+	err := json.Unmarshal(data, &str)
+	if err != nil {
+		return err
+	}
+
+	*pt, err = ParsePaymentType(str)
+	return err
+}
@@ -0,0 +1,123 @@
+package entities
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// PaymentsQuery describes filtering and keyset pagination of account payments.
+// Nil filters are not applied
+type PaymentsQuery struct {
+	// Limit is a maximum number of payments in a page, zero means no limit
+	Limit int
+
+	// Cursor is a NextCursor of the previous page, empty cursor means the first page
+	Cursor string
+
+	Direction    *PaymentDirection
+	Counterparty *AccountID
+	MinAmount    *decimal.Decimal
+	MaxAmount    *decimal.Decimal
+
+	// From is an inclusive lower bound of payment creation time
+	From *time.Time
+
+	// To is an exclusive upper bound of payment creation time
+	To *time.Time
+}
+
+// PaymentsPage is a single page of account payments ordered by creation time
+type PaymentsPage struct {
+	Payments []Payment `json:"payments"`
+
+	// NextCursor is used to request the next page, it is empty for the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// PaymentCursor is a decoded position in payments list ordered by creation time and ID
+type PaymentCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// NewPaymentCursor creates cursor pointing right after specified payment
+func NewPaymentCursor(p Payment) PaymentCursor {
+	return PaymentCursor{CreatedAt: p.CreatedAt, ID: p.ID}
+}
+
+// Encode converts cursor into opaque string
+func (c PaymentCursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// After reports whether payment goes after cursor position
+func (c PaymentCursor) After(p Payment) bool {
+	if !p.CreatedAt.Equal(c.CreatedAt) {
+		return p.CreatedAt.After(c.CreatedAt)
+	}
+	return bytes.Compare(p.ID[:], c.ID[:]) > 0
+}
+
+// DecodePaymentCursor parses cursor created by PaymentCursor.Encode
+func DecodePaymentCursor(cursor string) (PaymentCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return PaymentCursor{}, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return PaymentCursor{}, ErrInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return PaymentCursor{}, ErrInvalidCursor
+	}
+
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return PaymentCursor{}, ErrInvalidCursor
+	}
+	return PaymentCursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// Match reports whether payment satisfies query filters, cursor and limit are not checked
+func (q PaymentsQuery) Match(p Payment) bool {
+	if q.Direction != nil && p.Direction != *q.Direction {
+		return false
+	}
+
+	if q.Counterparty != nil {
+		counterparty := p.ToAccount
+		if p.Direction == Incoming {
+			counterparty = p.FromAccount
+		}
+		if counterparty == nil || *counterparty != *q.Counterparty {
+			return false
+		}
+	}
+
+	if q.MinAmount != nil && p.Amount.LessThan(*q.MinAmount) {
+		return false
+	}
+
+	if q.MaxAmount != nil && p.Amount.GreaterThan(*q.MaxAmount) {
+		return false
+	}
+
+	if q.From != nil && p.CreatedAt.Before(*q.From) {
+		return false
+	}
+
+	if q.To != nil && !p.CreatedAt.Before(*q.To) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,24 @@
+// Code generated by "stringer -type PaymentStatus -linecomment"; DO NOT EDIT.
+
+package entities
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[Completed-0]
+}
+
+const _PaymentStatus_name = "completed"
+
+var _PaymentStatus_index = [...]uint8{0, 9}
+
+func (i PaymentStatus) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_PaymentStatus_index)-1 {
+		return "PaymentStatus(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _PaymentStatus_name[_PaymentStatus_index[idx]:_PaymentStatus_index[idx+1]]
+}
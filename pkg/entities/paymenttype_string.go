@@ -0,0 +1,25 @@
+// Code generated by "stringer -type PaymentType -linecomment"; DO NOT EDIT.
+
+package entities
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[RegularPayment-0]
+	_ = x[Refund-1]
+}
+
+const _PaymentType_name = "paymentrefund"
+
+var _PaymentType_index = [...]uint8{0, 7, 13}
+
+func (i PaymentType) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_PaymentType_index)-1 {
+		return "PaymentType(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _PaymentType_name[_PaymentType_index[idx]:_PaymentType_index[idx+1]]
+}
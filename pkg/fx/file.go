@@ -0,0 +1,96 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/shirolimit/wallet-service/pkg/entities"
+)
+
+// ratesFile is a format of file read by FileProvider:
+//
+//	{"timestamp": "2019-03-01T12:00:00Z", "rates": {"USD/EUR": 0.88, "EUR/USD": 1.13}}
+//
+// Modification time of the file is used if timestamp is omitted
+type ratesFile struct {
+	Timestamp *time.Time                 `json:"timestamp"`
+	Rates     map[string]decimal.Decimal `json:"rates"`
+}
+
+// FileProvider returns exchange rates from JSON file.
+// The file is read again after each modification, so rates can be updated without restart
+type FileProvider struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	rates   *StaticProvider
+}
+
+// NewFileProvider creates provider reading rates from specified file
+func NewFileProvider(path string) (*FileProvider, error) {
+	p := &FileProvider{path: path}
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Rate returns exchange rate of specified currency pair or ErrExchangeRateNotFound
+func (p *FileProvider) Rate(ctx context.Context, from, to string) (entities.ExchangeRate, error) {
+	p.mu.Lock()
+	err := p.reload()
+	rates := p.rates
+	p.mu.Unlock()
+
+	if err != nil {
+		return entities.ExchangeRate{}, err
+	}
+	return rates.Rate(ctx, from, to)
+}
+
+// reload reads rates file if it has been modified since the last read
+func (p *FileProvider) reload() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+	if p.rates != nil && info.ModTime().Equal(p.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return err
+	}
+
+	var file ratesFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("malformed rates file %s: %v", p.path, err)
+	}
+
+	for pair, rate := range file.Rates {
+		if currencies := strings.Split(pair, "/"); len(currencies) != 2 || currencies[0] == "" || currencies[1] == "" {
+			return fmt.Errorf("malformed currency pair %q in rates file %s", pair, p.path)
+		}
+		if !rate.IsPositive() {
+			return fmt.Errorf("non-positive rate of %s in rates file %s", pair, p.path)
+		}
+	}
+
+	timestamp := info.ModTime()
+	if file.Timestamp != nil {
+		timestamp = *file.Timestamp
+	}
+
+	p.rates = newStaticProvider(file.Rates, timestamp)
+	p.modTime = info.ModTime()
+	return nil
+}
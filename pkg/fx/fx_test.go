@@ -0,0 +1,88 @@
+package fx_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/shirolimit/wallet-service/pkg/entities"
+	"github.com/shirolimit/wallet-service/pkg/fx"
+)
+
+func Test_StaticProvider_Rate(t *testing.T) {
+	provider := fx.NewStaticProvider(map[string]decimal.Decimal{"usd/eur": decimal.New(9, -1)})
+
+	rate, err := provider.Rate(context.TODO(), "USD", "EUR")
+	if err != nil {
+		t.Fatalf("Error while getting rate: %v", err)
+	}
+	if !rate.Rate.Equal(decimal.New(9, -1)) || rate.From != "USD" || rate.To != "EUR" || rate.Timestamp.IsZero() {
+		t.Errorf("Expectation failed. Actual rate = %v", rate)
+	}
+
+	if _, err = provider.Rate(context.TODO(), "EUR", "USD"); err != entities.ErrExchangeRateNotFound {
+		t.Errorf("Expectation failed. Expected error = %v, actual = %v", entities.ErrExchangeRateNotFound, err)
+	}
+}
+
+func Test_FileProvider_Rate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	writeRates := func(data string, modTime time.Time) {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("Error while writing rates file: %v", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Error while changing rates file time: %v", err)
+		}
+	}
+
+	modTime := time.Date(2019, time.March, 1, 12, 0, 0, 0, time.UTC)
+	writeRates(`{"rates": {"USD/EUR": 0.9}}`, modTime)
+	provider, err := fx.NewFileProvider(path)
+	if err != nil {
+		t.Fatalf("Error while reading rates file: %v", err)
+	}
+
+	rate, err := provider.Rate(context.TODO(), "USD", "EUR")
+	if err != nil || !rate.Rate.Equal(decimal.New(9, -1)) || !rate.Timestamp.Equal(modTime) {
+		t.Errorf("Expectation failed. Actual rate = %v, error = %v", rate, err)
+	}
+
+	// modified file is read again
+	writeRates(`{"timestamp": "2019-03-02T00:00:00Z", "rates": {"USD/EUR": "0.95"}}`, modTime.Add(time.Hour))
+	rate, err = provider.Rate(context.TODO(), "USD", "EUR")
+	if err != nil || !rate.Rate.Equal(decimal.New(95, -2)) || !rate.Timestamp.Equal(modTime.Add(12*time.Hour)) {
+		t.Errorf("Expectation failed. Actual rate = %v, error = %v", rate, err)
+	}
+
+	writeRates(`{"rates": {"USD": 0.9}}`, modTime)
+	if _, err = fx.NewFileProvider(path); err == nil {
+		t.Errorf("Error expectation failed")
+	}
+}
+
+func Test_HTTPProvider_Rate(t *testing.T) {
+	stub := fx.NewStaticProvider(map[string]decimal.Decimal{"USD/EUR": decimal.New(9, -1)})
+	server := httptest.NewServer(fx.NewStubHandler(stub))
+	defer server.Close()
+
+	provider := fx.NewHTTPProvider(server.URL, nil)
+
+	rate, err := provider.Rate(context.TODO(), "USD", "EUR")
+	if err != nil {
+		t.Fatalf("Error while getting rate: %v", err)
+	}
+	expected, _ := stub.Rate(context.TODO(), "USD", "EUR")
+	if !rate.Rate.Equal(expected.Rate) || !rate.Timestamp.Equal(expected.Timestamp) {
+		t.Errorf("Expectation failed. Expected rate = %v, actual = %v", expected, rate)
+	}
+
+	if _, err = provider.Rate(context.TODO(), "EUR", "USD"); err != entities.ErrExchangeRateNotFound {
+		t.Errorf("Expectation failed. Expected error = %v, actual = %v", entities.ErrExchangeRateNotFound, err)
+	}
+}
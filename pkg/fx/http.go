@@ -0,0 +1,97 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shirolimit/wallet-service/pkg/entities"
+)
+
+// httpTimeout limits the time of a single rate request
+const httpTimeout = 5 * time.Second
+
+// HTTPProvider requests exchange rates from HTTP service.
+// The service must respond to GET /rates?from=USD&to=EUR with ExchangeRate JSON object
+// and 404 status code if the pair is unknown, see NewStubHandler
+type HTTPProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPProvider creates provider using rates service at specified URL.
+// Client with default timeout is used if client is nil
+func NewHTTPProvider(baseURL string, client *http.Client) *HTTPProvider {
+	if client == nil {
+		client = &http.Client{Timeout: httpTimeout}
+	}
+	return &HTTPProvider{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  client,
+	}
+}
+
+// Rate returns exchange rate of specified currency pair or ErrExchangeRateNotFound
+func (p *HTTPProvider) Rate(ctx context.Context, from, to string) (entities.ExchangeRate, error) {
+	query := url.Values{"from": {from}, "to": {to}}
+	req, err := http.NewRequest(http.MethodGet, p.baseURL+"/rates?"+query.Encode(), nil)
+	if err != nil {
+		return entities.ExchangeRate{}, err
+	}
+
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return entities.ExchangeRate{}, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return entities.ExchangeRate{}, entities.ErrExchangeRateNotFound
+	default:
+		return entities.ExchangeRate{}, fmt.Errorf("rates service responded with status %d", resp.StatusCode)
+	}
+
+	var rate entities.ExchangeRate
+	if err = json.NewDecoder(resp.Body).Decode(&rate); err != nil {
+		return entities.ExchangeRate{}, fmt.Errorf("malformed rates service response: %v", err)
+	}
+
+	if !strings.EqualFold(rate.From, from) || !strings.EqualFold(rate.To, to) || !rate.Rate.IsPositive() {
+		return entities.ExchangeRate{}, fmt.Errorf("rates service responded with wrong rate %s/%s %s", rate.From, rate.To, rate.Rate)
+	}
+	rate.From, rate.To = from, to
+	return rate, nil
+}
+
+// NewStubHandler creates HTTP handler that serves rates of specified provider
+// in the format expected by HTTPProvider. It allows to run rates service locally
+func NewStubHandler(provider rateProvider) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rates", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		rate, err := provider.Rate(r.Context(), query.Get("from"), query.Get("to"))
+		if err == entities.ErrExchangeRateNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(rate)
+	})
+	return mux
+}
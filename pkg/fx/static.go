@@ -0,0 +1,58 @@
+package fx
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/shirolimit/wallet-service/pkg/entities"
+)
+
+// rateProvider is implemented by all providers of this package
+type rateProvider interface {
+	Rate(ctx context.Context, from, to string) (entities.ExchangeRate, error)
+}
+
+// StaticProvider returns exchange rates from a fixed table
+type StaticProvider struct {
+	rates     map[string]decimal.Decimal
+	timestamp time.Time
+}
+
+// NewStaticProvider creates provider with rates keyed by currency pair, i.e. "USD/EUR".
+// Rates are stamped with the time of provider creation
+func NewStaticProvider(rates map[string]decimal.Decimal) *StaticProvider {
+	return newStaticProvider(rates, time.Now())
+}
+
+func newStaticProvider(rates map[string]decimal.Decimal, timestamp time.Time) *StaticProvider {
+	table := make(map[string]decimal.Decimal, len(rates))
+	for pair, rate := range rates {
+		table[strings.ToUpper(pair)] = rate
+	}
+	return &StaticProvider{
+		rates:     table,
+		timestamp: timestamp.UTC(),
+	}
+}
+
+// Rate returns exchange rate of specified currency pair or ErrExchangeRateNotFound
+func (p *StaticProvider) Rate(ctx context.Context, from, to string) (entities.ExchangeRate, error) {
+	rate, ok := p.rates[pairKey(from, to)]
+	if !ok {
+		return entities.ExchangeRate{}, entities.ErrExchangeRateNotFound
+	}
+
+	return entities.ExchangeRate{
+		From:      from,
+		To:        to,
+		Rate:      rate,
+		Timestamp: p.timestamp,
+	}, nil
+}
+
+func pairKey(from, to string) string {
+	return strings.ToUpper(from) + "/" + strings.ToUpper(to)
+}
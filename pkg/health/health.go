@@ -0,0 +1,123 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkTimeout limits the duration of all readiness checks
+const checkTimeout = 3 * time.Second
+
+// errShuttingDown is reported by readiness check during graceful shutdown
+var errShuttingDown = errors.New("service is shutting down")
+
+// Check is a readiness check of a single dependency, nil error means ready
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker serves liveness and readiness probes.
+// Service is ready when all registered checks pass and shutdown has not started
+type Checker struct {
+	shuttingDown int32
+
+	mutex  sync.RWMutex
+	checks []namedCheck
+}
+
+// NewChecker creates Checker without checks
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+// Add registers readiness check with specified name
+func (c *Checker) Add(name string, check Check) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Shutdown makes readiness checks fail, so that no new traffic is sent while connections are drained
+func (c *Checker) Shutdown() {
+	atomic.StoreInt32(&c.shuttingDown, 1)
+}
+
+// status is a JSON response of health endpoints
+type status struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// LivenessHandler reports that process is alive and able to serve HTTP requests
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, http.StatusOK, status{Status: "ok"})
+	})
+}
+
+// ReadinessHandler runs all checks and responds with 503 status code if any of them fails
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		defer cancel()
+
+		result, ready := c.ready(ctx)
+		code := http.StatusOK
+		if !ready {
+			code = http.StatusServiceUnavailable
+		}
+		writeStatus(w, code, result)
+	})
+}
+
+// ready runs checks concurrently and collects their results
+func (c *Checker) ready(ctx context.Context) (status, bool) {
+	c.mutex.RLock()
+	checks := c.checks
+	c.mutex.RUnlock()
+
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, nc := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			errs[i] = check(ctx)
+		}(i, nc.check)
+	}
+	wg.Wait()
+
+	result := status{Status: "ok", Checks: make(map[string]string, len(checks)+1)}
+	ready := true
+	if atomic.LoadInt32(&c.shuttingDown) == 1 {
+		result.Checks["shutdown"] = errShuttingDown.Error()
+		ready = false
+	}
+	for i, nc := range checks {
+		if errs[i] != nil {
+			result.Checks[nc.name] = errs[i].Error()
+			ready = false
+		} else {
+			result.Checks[nc.name] = "ok"
+		}
+	}
+	if !ready {
+		result.Status = "failing"
+	}
+	return result, ready
+}
+
+func writeStatus(w http.ResponseWriter, code int, s interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(s)
+}
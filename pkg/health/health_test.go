@@ -0,0 +1,62 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shirolimit/wallet-service/pkg/health"
+)
+
+func Test_Checker_Readiness(t *testing.T) {
+	var storageErr error
+	checker := health.NewChecker()
+	checker.Add("storage", func(ctx context.Context) error { return storageErr })
+
+	tests := []struct {
+		name       string
+		prepare    func()
+		wantCode   int
+		wantChecks map[string]string
+	}{
+		{"ready", func() {}, http.StatusOK, map[string]string{"storage": "ok"}},
+		{"check_fails", func() { storageErr = errors.New("connection refused") }, http.StatusServiceUnavailable,
+			map[string]string{"storage": "connection refused"}},
+		{"shutting_down", func() { storageErr = nil; checker.Shutdown() }, http.StatusServiceUnavailable,
+			map[string]string{"storage": "ok", "shutdown": "service is shutting down"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.prepare()
+			recorder := httptest.NewRecorder()
+			checker.ReadinessHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+			if recorder.Code != tt.wantCode {
+				t.Errorf("ReadinessHandler() code = %v, want %v", recorder.Code, tt.wantCode)
+			}
+
+			var body struct {
+				Checks map[string]string `json:"checks"`
+			}
+			if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+				t.Fatalf("Error while decoding response: %v", err)
+			}
+			if len(body.Checks) != len(tt.wantChecks) {
+				t.Errorf("ReadinessHandler() checks = %v, want %v", body.Checks, tt.wantChecks)
+			}
+			for name, want := range tt.wantChecks {
+				if body.Checks[name] != want {
+					t.Errorf("ReadinessHandler() checks = %v, want %v", body.Checks, tt.wantChecks)
+				}
+			}
+		})
+	}
+
+	recorder := httptest.NewRecorder()
+	checker.LivenessHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/healthz", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("LivenessHandler() code = %v, want %v", recorder.Code, http.StatusOK)
+	}
+}
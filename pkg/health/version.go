@@ -0,0 +1,39 @@
+package health
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit,omitempty"`
+	CommitTime string `json:"commit_time,omitempty"`
+	GoVersion  string `json:"go_version"`
+}
+
+// NewBuildInfo creates BuildInfo with specified version, usually set with -ldflags.
+// Commit and its time are taken from VCS information embedded by go build
+func NewBuildInfo(version string) BuildInfo {
+	info := BuildInfo{Version: version, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Commit = setting.Value
+			case "vcs.time":
+				info.CommitTime = setting.Value
+			}
+		}
+	}
+	return info
+}
+
+// VersionHandler responds with build information
+func VersionHandler(info BuildInfo) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, http.StatusOK, info)
+	})
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/shirolimit/wallet-service/pkg/auth"
+	"github.com/shirolimit/wallet-service/pkg/entities"
+	"github.com/shopspring/decimal"
+)
+
+type authorizationMiddleware struct {
+	next WalletService
+}
+
+// AuthorizationMiddleware produces a service Middleware which lets only account owners and admins
+// debit accounts and read their payments. Principal is taken from context, calls without it are forbidden
+func AuthorizationMiddleware() Middleware {
+	return func(next WalletService) WalletService {
+		return &authorizationMiddleware{next: next}
+	}
+}
+
+// CreateAccount makes authenticated principal the owner of account,
+// only admins can create accounts owned by others
+func (amw authorizationMiddleware) CreateAccount(ctx context.Context, acc entities.Account) (entities.Account, error) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return entities.Account{}, entities.ErrForbidden
+	}
+
+	if !principal.HasRole(auth.RoleAdmin) {
+		if acc.Owner != "" && acc.Owner != principal.Subject {
+			return entities.Account{}, entities.ErrForbidden
+		}
+		acc.Owner = principal.Subject
+	}
+	return amw.next.CreateAccount(ctx, acc)
+}
+
+// ListAccounts lists only own accounts of principal, admins see all accounts
+func (amw authorizationMiddleware) ListAccounts(ctx context.Context, query entities.AccountsQuery) (entities.AccountsPage, error) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return entities.AccountsPage{}, entities.ErrForbidden
+	}
+
+	if !principal.HasRole(auth.RoleAdmin) {
+		query.Owner = principal.Subject
+	}
+	return amw.next.ListAccounts(ctx, query)
+}
+
+// GetAccount is allowed to the owner of account
+func (amw authorizationMiddleware) GetAccount(ctx context.Context, id entities.AccountID) (entities.Account, error) {
+	if err := amw.authorize(ctx, id); err != nil {
+		return entities.Account{}, err
+	}
+	return amw.next.GetAccount(ctx, id)
+}
+
+// UpdateAccount is allowed to the owner of account, but only admins can make account active again
+func (amw authorizationMiddleware) UpdateAccount(ctx context.Context, id entities.AccountID,
+	update entities.AccountUpdate) (entities.Account, error) {
+
+	if update.Status != nil && *update.Status == entities.Active {
+		if principal, ok := auth.FromContext(ctx); !ok || !principal.HasRole(auth.RoleAdmin) {
+			return entities.Account{}, entities.ErrForbidden
+		}
+	}
+
+	if err := amw.authorize(ctx, id); err != nil {
+		return entities.Account{}, err
+	}
+	return amw.next.UpdateAccount(ctx, id, update)
+}
+
+// GetPayments is allowed to the owner of account
+func (amw authorizationMiddleware) GetPayments(ctx context.Context, id entities.AccountID,
+	query entities.PaymentsQuery) (entities.PaymentsPage, error) {
+
+	if err := amw.authorize(ctx, id); err != nil {
+		return entities.PaymentsPage{}, err
+	}
+	return amw.next.GetPayments(ctx, id, query)
+}
+
+// GetPayment is allowed to the owners of both payment accounts
+func (amw authorizationMiddleware) GetPayment(ctx context.Context, id uuid.UUID) (entities.PaymentRecord, error) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return entities.PaymentRecord{}, entities.ErrForbidden
+	}
+
+	record, err := amw.next.GetPayment(ctx, id)
+	if err != nil || principal.HasRole(auth.RoleAdmin) {
+		return record, err
+	}
+
+	if err = amw.authorize(ctx, record.Source); err == entities.ErrForbidden {
+		err = amw.authorize(ctx, record.Destination)
+	}
+	if err != nil {
+		return entities.PaymentRecord{}, err
+	}
+	return record, nil
+}
+
+// MakePayment is allowed to the owner of source account
+func (amw authorizationMiddleware) MakePayment(ctx context.Context, payment entities.Payment) (entities.Payment, error) {
+	if err := amw.authorize(ctx, payment.Account); err != nil {
+		return entities.Payment{}, err
+	}
+	return amw.next.MakePayment(ctx, payment)
+}
+
+// Deposit brings money into the system, so it is allowed to admins only
+func (amw authorizationMiddleware) Deposit(ctx context.Context, payment entities.Payment) (entities.Payment, error) {
+	if principal, ok := auth.FromContext(ctx); !ok || !principal.HasRole(auth.RoleAdmin) {
+		return entities.Payment{}, entities.ErrForbidden
+	}
+	return amw.next.Deposit(ctx, payment)
+}
+
+// Withdraw is allowed to the owner of account
+func (amw authorizationMiddleware) Withdraw(ctx context.Context, payment entities.Payment) (entities.Payment, error) {
+	if err := amw.authorize(ctx, payment.Account); err != nil {
+		return entities.Payment{}, err
+	}
+	return amw.next.Withdraw(ctx, payment)
+}
+
+// CreateHold is allowed to the owner of account whose funds are held
+func (amw authorizationMiddleware) CreateHold(ctx context.Context, hold entities.Hold) (entities.Hold, error) {
+	if err := amw.authorize(ctx, hold.Account); err != nil {
+		return entities.Hold{}, err
+	}
+	return amw.next.CreateHold(ctx, hold)
+}
+
+// GetHold is allowed to the owners of both hold accounts
+func (amw authorizationMiddleware) GetHold(ctx context.Context, id uuid.UUID) (entities.Hold, error) {
+	if err := amw.authorizeHold(ctx, id, true); err != nil {
+		return entities.Hold{}, err
+	}
+	return amw.next.GetHold(ctx, id)
+}
+
+// CaptureHold is allowed to the owner of destination account only: the payer can't
+// cancel reservation the payee relies on, even by capturing a tiny part of it
+func (amw authorizationMiddleware) CaptureHold(ctx context.Context, id uuid.UUID,
+	amount *decimal.Decimal) (entities.Payment, error) {
+
+	if err := amw.authorizeHold(ctx, id, false); err != nil {
+		return entities.Payment{}, err
+	}
+	return amw.next.CaptureHold(ctx, id, amount)
+}
+
+// ReleaseHold is allowed to the owner of destination account only, see CaptureHold
+func (amw authorizationMiddleware) ReleaseHold(ctx context.Context, id uuid.UUID) (entities.Hold, error) {
+	if err := amw.authorizeHold(ctx, id, false); err != nil {
+		return entities.Hold{}, err
+	}
+	return amw.next.ReleaseHold(ctx, id)
+}
+
+// RefundPayment is allowed to the owner of refund account, storage checks that it is
+// the destination of refunded payment
+func (amw authorizationMiddleware) RefundPayment(ctx context.Context, refund entities.Payment) (entities.Payment, error) {
+	if err := amw.authorize(ctx, refund.Account); err != nil {
+		return entities.Payment{}, err
+	}
+	return amw.next.RefundPayment(ctx, refund)
+}
+
+// authorizeHold checks that principal of context owns destination account of specified hold,
+// or its source account if payer is set, or is an admin.
+// Missing holds are left to the wrapped service, so that it reports them as usual
+func (amw authorizationMiddleware) authorizeHold(ctx context.Context, id uuid.UUID, payer bool) error {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return entities.ErrForbidden
+	}
+	if principal.HasRole(auth.RoleAdmin) {
+		return nil
+	}
+
+	hold, err := amw.next.GetHold(ctx, id)
+	switch {
+	case err == entities.ErrHoldNotFound:
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if payer {
+		if err = amw.authorize(ctx, hold.Account); err != entities.ErrForbidden {
+			return err
+		}
+	}
+	return amw.authorize(ctx, hold.ToAccount)
+}
+
+// authorize checks that principal of context owns specified account or is an admin.
+// Missing accounts are left to the wrapped service, so that it reports them as usual
+func (amw authorizationMiddleware) authorize(ctx context.Context, id entities.AccountID) error {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return entities.ErrForbidden
+	}
+	if principal.HasRole(auth.RoleAdmin) {
+		return nil
+	}
+
+	acc, err := amw.next.GetAccount(ctx, id)
+	switch {
+	case err == entities.ErrAccountNotFound:
+		return nil
+	case err != nil:
+		return err
+	case acc.Owner == "" || acc.Owner != principal.Subject:
+		return entities.ErrForbidden
+	}
+	return nil
+}
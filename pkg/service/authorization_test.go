@@ -0,0 +1,442 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shirolimit/wallet-service/pkg/auth"
+	"github.com/shirolimit/wallet-service/pkg/db"
+	"github.com/shirolimit/wallet-service/pkg/entities"
+	"github.com/shirolimit/wallet-service/pkg/service"
+)
+
+func Test_AuthorizationMiddleware_MakePayment(t *testing.T) {
+	alice := auth.Principal{Subject: "alice"}
+	admin := auth.Principal{Subject: "backoffice", Roles: []string{auth.RoleAdmin}}
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		source    entities.AccountID
+		wantErr   error
+	}{
+		{"owner", &alice, "alice_usd", nil},
+		{"not_owner", &alice, "bob_usd", entities.ErrForbidden},
+		{"account_without_owner", &alice, "legacy", entities.ErrForbidden},
+		{"admin", &admin, "bob_usd", nil},
+		{"unauthenticated", nil, "alice_usd", entities.ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := db.NewMockStorage(ctrl)
+			expectAccounts(mockStorage,
+				entities.Account{ID: "alice_usd", Balance: decimal.New(100, 0), Currency: "USD", Owner: "alice"},
+				entities.Account{ID: "bob_usd", Balance: decimal.New(100, 0), Currency: "USD", Owner: "bob"},
+				entities.Account{ID: "legacy", Balance: decimal.New(100, 0), Currency: "USD"},
+				entities.Account{ID: "carol_usd", Balance: decimal.New(100, 0), Currency: "USD", Owner: "carol"},
+			)
+			if tt.wantErr == nil {
+				mockStorage.EXPECT().CreatePayment(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, payment entities.Payment) (*entities.Payment, error) {
+						return &payment, nil
+					})
+			}
+
+			ctx := context.TODO()
+			if tt.principal != nil {
+				ctx = auth.NewContext(ctx, *tt.principal)
+			}
+			svc := service.AuthorizationMiddleware()(service.NewWalletService(mockStorage))
+
+			to := entities.AccountID("carol_usd")
+			_, err := svc.MakePayment(ctx, entities.Payment{
+				ID:        uuid.New(),
+				Account:   tt.source,
+				ToAccount: &to,
+				Amount:    decimal.New(10, 0),
+				Direction: entities.Outgoing,
+			})
+			if err != tt.wantErr {
+				t.Errorf("authorizationMiddleware.MakePayment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_AuthorizationMiddleware_Deposit(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal auth.Principal
+		wantErr   error
+	}{
+		{"owner", auth.Principal{Subject: "alice"}, entities.ErrForbidden},
+		{"not_owner", auth.Principal{Subject: "mallory"}, entities.ErrForbidden},
+		{"admin", auth.Principal{Subject: "backoffice", Roles: []string{auth.RoleAdmin}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := db.NewMockStorage(ctrl)
+			expectAccounts(mockStorage, entities.Account{ID: "alice_usd", Currency: "USD", Owner: "alice"})
+			if tt.wantErr == nil {
+				mockStorage.EXPECT().CreatePayment(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, payment entities.Payment) (*entities.Payment, error) {
+						return &payment, nil
+					})
+			}
+
+			svc := service.AuthorizationMiddleware()(service.NewWalletService(mockStorage))
+			_, err := svc.Deposit(auth.NewContext(context.TODO(), tt.principal), entities.Payment{
+				ID:      uuid.New(),
+				Account: "alice_usd",
+				Amount:  decimal.New(10, 0),
+			})
+			if err != tt.wantErr {
+				t.Errorf("authorizationMiddleware.Deposit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_AuthorizationMiddleware_Withdraw(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal auth.Principal
+		wantErr   error
+	}{
+		{"owner", auth.Principal{Subject: "alice"}, nil},
+		{"not_owner", auth.Principal{Subject: "mallory"}, entities.ErrForbidden},
+		{"admin", auth.Principal{Subject: "backoffice", Roles: []string{auth.RoleAdmin}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := db.NewMockStorage(ctrl)
+			expectAccounts(mockStorage, entities.Account{ID: "alice_usd", Balance: decimal.New(100, 0), Currency: "USD", Owner: "alice"})
+			if tt.wantErr == nil {
+				mockStorage.EXPECT().CreatePayment(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, payment entities.Payment) (*entities.Payment, error) {
+						return &payment, nil
+					})
+			}
+
+			svc := service.AuthorizationMiddleware()(service.NewWalletService(mockStorage))
+			_, err := svc.Withdraw(auth.NewContext(context.TODO(), tt.principal), entities.Payment{
+				ID:      uuid.New(),
+				Account: "alice_usd",
+				Amount:  decimal.New(10, 0),
+			})
+			if err != tt.wantErr {
+				t.Errorf("authorizationMiddleware.Withdraw() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_AuthorizationMiddleware_CreateAccount(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal auth.Principal
+		owner     string
+		wantOwner string
+		wantErr   error
+	}{
+		{"owner_is_assigned", auth.Principal{Subject: "alice"}, "", "alice", nil},
+		{"own_account", auth.Principal{Subject: "alice"}, "alice", "alice", nil},
+		{"account_of_other", auth.Principal{Subject: "alice"}, "bob", "", entities.ErrForbidden},
+		{"admin_for_other", auth.Principal{Subject: "backoffice", Roles: []string{auth.RoleAdmin}}, "bob", "bob", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			acc := entities.Account{ID: "account", Currency: "USD", Balance: decimal.New(100, 0), Owner: tt.owner}
+			mockStorage := db.NewMockStorage(ctrl)
+			if tt.wantErr == nil {
+				stored := acc
+				stored.Owner = tt.wantOwner
+				mockStorage.EXPECT().CreateAccount(gomock.Any(), stored).Return(&stored, nil)
+			}
+
+			svc := service.AuthorizationMiddleware()(service.NewWalletService(mockStorage))
+			created, err := svc.CreateAccount(auth.NewContext(context.TODO(), tt.principal), acc)
+			if err != tt.wantErr {
+				t.Errorf("authorizationMiddleware.CreateAccount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if created.Owner != tt.wantOwner {
+				t.Errorf("authorizationMiddleware.CreateAccount() owner = %v, want %v", created.Owner, tt.wantOwner)
+			}
+		})
+	}
+}
+
+func Test_AuthorizationMiddleware_GetAccount(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal auth.Principal
+		wantErr   error
+	}{
+		{"owner", auth.Principal{Subject: "alice"}, nil},
+		{"not_owner", auth.Principal{Subject: "mallory"}, entities.ErrForbidden},
+		{"admin", auth.Principal{Subject: "backoffice", Roles: []string{auth.RoleAdmin}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := db.NewMockStorage(ctrl)
+			expectAccounts(mockStorage, entities.Account{ID: "alice_usd", Currency: "USD", Owner: "alice"})
+
+			svc := service.AuthorizationMiddleware()(service.NewWalletService(mockStorage))
+			_, err := svc.GetAccount(auth.NewContext(context.TODO(), tt.principal), "alice_usd")
+			if err != tt.wantErr {
+				t.Errorf("authorizationMiddleware.GetAccount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_AuthorizationMiddleware_ListAccounts(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal auth.Principal
+		owner     string
+		wantOwner string
+	}{
+		{"own_accounts", auth.Principal{Subject: "alice"}, "", "alice"},
+		{"accounts_of_other", auth.Principal{Subject: "alice"}, "bob", "alice"},
+		{"admin_all", auth.Principal{Subject: "backoffice", Roles: []string{auth.RoleAdmin}}, "", ""},
+		{"admin_of_other", auth.Principal{Subject: "backoffice", Roles: []string{auth.RoleAdmin}}, "bob", "bob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := db.NewMockStorage(ctrl)
+			mockStorage.EXPECT().ListAccounts(gomock.Any(), entities.AccountsQuery{Owner: tt.wantOwner, Sort: entities.SortByID}).
+				Return(entities.AccountsPage{}, nil)
+
+			svc := service.AuthorizationMiddleware()(service.NewWalletService(mockStorage))
+			query := entities.AccountsQuery{Owner: tt.owner}
+			if _, err := svc.ListAccounts(auth.NewContext(context.TODO(), tt.principal), query); err != nil {
+				t.Errorf("authorizationMiddleware.ListAccounts() error = %v", err)
+			}
+		})
+	}
+}
+
+func Test_AuthorizationMiddleware_UpdateAccount(t *testing.T) {
+	alice := auth.Principal{Subject: "alice"}
+	admin := auth.Principal{Subject: "backoffice", Roles: []string{auth.RoleAdmin}}
+
+	tests := []struct {
+		name      string
+		principal auth.Principal
+		id        entities.AccountID
+		status    entities.AccountStatus
+		wantErr   error
+	}{
+		{"owner_freezes", alice, "alice_usd", entities.Frozen, nil},
+		{"owner_closes", alice, "alice_usd", entities.Closed, nil},
+		{"owner_unfreezes", alice, "alice_usd", entities.Active, entities.ErrForbidden},
+		{"not_owner_freezes", alice, "bob_usd", entities.Frozen, entities.ErrForbidden},
+		{"admin_unfreezes", admin, "bob_usd", entities.Active, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := db.NewMockStorage(ctrl)
+			expectAccounts(mockStorage,
+				entities.Account{ID: "alice_usd", Currency: "USD", Owner: "alice"},
+				entities.Account{ID: "bob_usd", Currency: "USD", Owner: "bob", Status: entities.Frozen},
+			)
+			if tt.wantErr == nil {
+				mockStorage.EXPECT().UpdateAccountStatus(gomock.Any(), tt.id, tt.status).
+					Return(&entities.Account{ID: tt.id, Status: tt.status}, nil)
+			}
+
+			svc := service.AuthorizationMiddleware()(service.NewWalletService(mockStorage))
+			status := tt.status
+			_, err := svc.UpdateAccount(auth.NewContext(context.TODO(), tt.principal), tt.id, entities.AccountUpdate{Status: &status})
+			if err != tt.wantErr {
+				t.Errorf("authorizationMiddleware.UpdateAccount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_AuthorizationMiddleware_CaptureHold(t *testing.T) {
+	alice := auth.Principal{Subject: "alice"}
+	bob := auth.Principal{Subject: "bob"}
+	mallory := auth.Principal{Subject: "mallory"}
+	admin := auth.Principal{Subject: "backoffice", Roles: []string{auth.RoleAdmin}}
+
+	tests := []struct {
+		name      string
+		principal auth.Principal
+		wantErr   error
+	}{
+		{"payer", alice, entities.ErrForbidden},
+		{"payee", bob, nil},
+		{"not_owner", mallory, entities.ErrForbidden},
+		{"admin", admin, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			hold := entities.Hold{ID: uuid.New(), Account: "alice_usd", ToAccount: "bob_usd", Amount: decimal.New(10, 0)}
+			mockStorage := db.NewMockStorage(ctrl)
+			mockStorage.EXPECT().GetHold(gomock.Any(), hold.ID).Return(&hold, nil).AnyTimes()
+			expectAccounts(mockStorage,
+				entities.Account{ID: "alice_usd", Currency: "USD", Owner: "alice"},
+				entities.Account{ID: "bob_usd", Currency: "USD", Owner: "bob"},
+			)
+			if tt.wantErr == nil {
+				mockStorage.EXPECT().CaptureHold(gomock.Any(), hold.ID, nil).
+					Return(&entities.Payment{ID: hold.ID, Account: hold.Account, Amount: hold.Amount}, nil)
+			}
+
+			svc := service.AuthorizationMiddleware()(service.NewWalletService(mockStorage))
+			_, err := svc.CaptureHold(auth.NewContext(context.TODO(), tt.principal), hold.ID, nil)
+			if err != tt.wantErr {
+				t.Errorf("authorizationMiddleware.CaptureHold() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_AuthorizationMiddleware_ReleaseHold(t *testing.T) {
+	alice := auth.Principal{Subject: "alice"}
+	bob := auth.Principal{Subject: "bob"}
+	mallory := auth.Principal{Subject: "mallory"}
+	admin := auth.Principal{Subject: "backoffice", Roles: []string{auth.RoleAdmin}}
+
+	tests := []struct {
+		name      string
+		principal auth.Principal
+		wantErr   error
+	}{
+		{"payer", alice, entities.ErrForbidden},
+		{"payee", bob, nil},
+		{"not_owner", mallory, entities.ErrForbidden},
+		{"admin", admin, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			hold := entities.Hold{ID: uuid.New(), Account: "alice_usd", ToAccount: "bob_usd", Amount: decimal.New(10, 0)}
+			mockStorage := db.NewMockStorage(ctrl)
+			mockStorage.EXPECT().GetHold(gomock.Any(), hold.ID).Return(&hold, nil).AnyTimes()
+			expectAccounts(mockStorage,
+				entities.Account{ID: "alice_usd", Currency: "USD", Owner: "alice"},
+				entities.Account{ID: "bob_usd", Currency: "USD", Owner: "bob"},
+			)
+			if tt.wantErr == nil {
+				released := hold
+				released.Status = entities.HoldReleased
+				mockStorage.EXPECT().ReleaseHold(gomock.Any(), hold.ID).Return(&released, nil)
+			}
+
+			svc := service.AuthorizationMiddleware()(service.NewWalletService(mockStorage))
+			_, err := svc.ReleaseHold(auth.NewContext(context.TODO(), tt.principal), hold.ID)
+			if err != tt.wantErr {
+				t.Errorf("authorizationMiddleware.ReleaseHold() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_AuthorizationMiddleware_GetHold(t *testing.T) {
+	alice := auth.Principal{Subject: "alice"}
+	mallory := auth.Principal{Subject: "mallory"}
+
+	tests := []struct {
+		name      string
+		principal auth.Principal
+		wantErr   error
+	}{
+		{"payer", alice, nil},
+		{"not_owner", mallory, entities.ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			hold := entities.Hold{ID: uuid.New(), Account: "alice_usd", ToAccount: "bob_usd", Amount: decimal.New(10, 0)}
+			mockStorage := db.NewMockStorage(ctrl)
+			mockStorage.EXPECT().GetHold(gomock.Any(), hold.ID).Return(&hold, nil).AnyTimes()
+			expectAccounts(mockStorage,
+				entities.Account{ID: "alice_usd", Currency: "USD", Owner: "alice"},
+				entities.Account{ID: "bob_usd", Currency: "USD", Owner: "bob"},
+			)
+
+			svc := service.AuthorizationMiddleware()(service.NewWalletService(mockStorage))
+			_, err := svc.GetHold(auth.NewContext(context.TODO(), tt.principal), hold.ID)
+			if err != tt.wantErr {
+				t.Errorf("authorizationMiddleware.GetHold() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_AuthorizationMiddleware_GetPayment(t *testing.T) {
+	alice := auth.Principal{Subject: "alice"}
+	bob := auth.Principal{Subject: "bob"}
+	mallory := auth.Principal{Subject: "mallory"}
+	admin := auth.Principal{Subject: "backoffice", Roles: []string{auth.RoleAdmin}}
+
+	tests := []struct {
+		name      string
+		principal auth.Principal
+		wantErr   error
+	}{
+		{"payer", alice, nil},
+		{"payee", bob, nil},
+		{"not_owner", mallory, entities.ErrForbidden},
+		{"admin", admin, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			record := entities.PaymentRecord{ID: uuid.New(), Source: "alice_usd", Destination: "bob_usd", Amount: decimal.New(10, 0)}
+			mockStorage := db.NewMockStorage(ctrl)
+			mockStorage.EXPECT().GetPayment(gomock.Any(), record.ID).Return(&record, nil)
+			expectAccounts(mockStorage,
+				entities.Account{ID: "alice_usd", Currency: "USD", Owner: "alice"},
+				entities.Account{ID: "bob_usd", Currency: "USD", Owner: "bob"},
+			)
+
+			svc := service.AuthorizationMiddleware()(service.NewWalletService(mockStorage))
+			got, err := svc.GetPayment(auth.NewContext(context.TODO(), tt.principal), record.ID)
+			if err != tt.wantErr {
+				t.Errorf("authorizationMiddleware.GetPayment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && got.ID == record.ID {
+				t.Errorf("Expectation failed. Payment is disclosed to %v", tt.principal.Subject)
+			}
+		})
+	}
+}
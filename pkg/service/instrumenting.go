@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/google/uuid"
+	"github.com/shirolimit/wallet-service/pkg/entities"
+	"github.com/shopspring/decimal"
+)
+
+// Metrics is a set of metrics recorded by InstrumentingMiddleware
+type Metrics struct {
+	// RequestCount counts calls of service methods, labeled by "method"
+	RequestCount metrics.Counter
+
+	// ErrorCount counts failed calls of service methods, labeled by "method" and "error"
+	ErrorCount metrics.Counter
+
+	// RequestLatency observes duration of service method calls in seconds, labeled by "method"
+	RequestLatency metrics.Histogram
+
+	// PaymentVolume sums amounts of completed payments, deposits, withdrawals, captures and refunds,
+	// labeled by "type" and "currency"
+	PaymentVolume metrics.Counter
+
+	// FailedPayments counts rejected payments of all types, labeled by "type" and "reason"
+	FailedPayments metrics.Counter
+}
+
+type instrumentingMiddleware struct {
+	metrics Metrics
+	next    WalletService
+}
+
+// InstrumentingMiddleware is a function that takes metrics and produces
+// a service Middleware used for recording them
+func InstrumentingMiddleware(m Metrics) Middleware {
+	return func(next WalletService) WalletService {
+		return &instrumentingMiddleware{
+			metrics: m,
+			next:    next,
+		}
+	}
+}
+
+// CreateAccount is a middleware function that records metrics
+// Named return parameter is used for defer
+func (imw instrumentingMiddleware) CreateAccount(ctx context.Context, acc entities.Account) (created entities.Account, err error) {
+	defer imw.observe("CreateAccount", time.Now(), &err)
+	return imw.next.CreateAccount(ctx, acc)
+}
+
+// ListAccounts is a middleware function that records metrics
+// Named return parameters are used for defer
+func (imw instrumentingMiddleware) ListAccounts(ctx context.Context, query entities.AccountsQuery) (page entities.AccountsPage, err error) {
+	defer imw.observe("ListAccounts", time.Now(), &err)
+	return imw.next.ListAccounts(ctx, query)
+}
+
+// GetAccount is a middleware function that records metrics
+// Named return parameters are used for defer
+func (imw instrumentingMiddleware) GetAccount(ctx context.Context, id entities.AccountID) (acc entities.Account, err error) {
+	defer imw.observe("GetAccount", time.Now(), &err)
+	return imw.next.GetAccount(ctx, id)
+}
+
+// UpdateAccount is a middleware function that records metrics
+// Named return parameters are used for defer
+func (imw instrumentingMiddleware) UpdateAccount(ctx context.Context, id entities.AccountID,
+	update entities.AccountUpdate) (acc entities.Account, err error) {
+
+	defer imw.observe("UpdateAccount", time.Now(), &err)
+	return imw.next.UpdateAccount(ctx, id, update)
+}
+
+// GetPayments is a middleware function that records metrics
+// Named return parameters are used for defer
+func (imw instrumentingMiddleware) GetPayments(ctx context.Context, id entities.AccountID,
+	query entities.PaymentsQuery) (page entities.PaymentsPage, err error) {
+
+	defer imw.observe("GetPayments", time.Now(), &err)
+	return imw.next.GetPayments(ctx, id, query)
+}
+
+// GetPayment is a middleware function that records metrics
+// Named return parameters are used for defer
+func (imw instrumentingMiddleware) GetPayment(ctx context.Context, id uuid.UUID) (record entities.PaymentRecord, err error) {
+	defer imw.observe("GetPayment", time.Now(), &err)
+	return imw.next.GetPayment(ctx, id)
+}
+
+// MakePayment is a middleware function that records metrics, including business ones
+// Named return parameters are used for defer
+func (imw instrumentingMiddleware) MakePayment(ctx context.Context, payment entities.Payment) (stored entities.Payment, err error) {
+	defer imw.observe("MakePayment", time.Now(), &err)
+
+	stored, err = imw.next.MakePayment(ctx, payment)
+	imw.observePayment("payment", stored, err)
+	return stored, err
+}
+
+// Deposit is a middleware function that records metrics, including business ones
+// Named return parameters are used for defer
+func (imw instrumentingMiddleware) Deposit(ctx context.Context, payment entities.Payment) (stored entities.Payment, err error) {
+	defer imw.observe("Deposit", time.Now(), &err)
+
+	stored, err = imw.next.Deposit(ctx, payment)
+	imw.observePayment("deposit", stored, err)
+	return stored, err
+}
+
+// Withdraw is a middleware function that records metrics, including business ones
+// Named return parameters are used for defer
+func (imw instrumentingMiddleware) Withdraw(ctx context.Context, payment entities.Payment) (stored entities.Payment, err error) {
+	defer imw.observe("Withdraw", time.Now(), &err)
+
+	stored, err = imw.next.Withdraw(ctx, payment)
+	imw.observePayment("withdrawal", stored, err)
+	return stored, err
+}
+
+// CreateHold is a middleware function that records metrics
+// Named return parameters are used for defer
+func (imw instrumentingMiddleware) CreateHold(ctx context.Context, hold entities.Hold) (stored entities.Hold, err error) {
+	defer imw.observe("CreateHold", time.Now(), &err)
+	return imw.next.CreateHold(ctx, hold)
+}
+
+// GetHold is a middleware function that records metrics
+// Named return parameters are used for defer
+func (imw instrumentingMiddleware) GetHold(ctx context.Context, id uuid.UUID) (hold entities.Hold, err error) {
+	defer imw.observe("GetHold", time.Now(), &err)
+	return imw.next.GetHold(ctx, id)
+}
+
+// CaptureHold is a middleware function that records metrics, including business ones
+// Named return parameters are used for defer
+func (imw instrumentingMiddleware) CaptureHold(ctx context.Context, id uuid.UUID,
+	amount *decimal.Decimal) (payment entities.Payment, err error) {
+
+	defer imw.observe("CaptureHold", time.Now(), &err)
+
+	payment, err = imw.next.CaptureHold(ctx, id, amount)
+	imw.observePayment("capture", payment, err)
+	return payment, err
+}
+
+// ReleaseHold is a middleware function that records metrics
+// Named return parameters are used for defer
+func (imw instrumentingMiddleware) ReleaseHold(ctx context.Context, id uuid.UUID) (hold entities.Hold, err error) {
+	defer imw.observe("ReleaseHold", time.Now(), &err)
+	return imw.next.ReleaseHold(ctx, id)
+}
+
+// RefundPayment is a middleware function that records metrics, including business ones
+// Named return parameters are used for defer
+func (imw instrumentingMiddleware) RefundPayment(ctx context.Context, refund entities.Payment) (stored entities.Payment, err error) {
+	defer imw.observe("RefundPayment", time.Now(), &err)
+
+	stored, err = imw.next.RefundPayment(ctx, refund)
+	imw.observePayment("refund", stored, err)
+	return stored, err
+}
+
+// observe records metrics of method call started at specified time.
+// ErrPaymentDuplicate is a successful replay and is not counted as error
+func (imw instrumentingMiddleware) observe(method string, start time.Time, err *error) {
+	imw.metrics.RequestCount.With("method", method).Add(1)
+	imw.metrics.RequestLatency.With("method", method).Observe(time.Since(start).Seconds())
+	if *err != nil && *err != entities.ErrPaymentDuplicate {
+		imw.metrics.ErrorCount.With("method", method, "error", errorLabel(*err)).Add(1)
+	}
+}
+
+// observePayment records business metrics of a call moving money: amount of completed payment
+// of specified type or reason of its rejection
+func (imw instrumentingMiddleware) observePayment(paymentType string, stored entities.Payment, err error) {
+	switch err {
+	case nil:
+		amount, _ := stored.Amount.Float64()
+		imw.metrics.PaymentVolume.With("type", paymentType, "currency", stored.Currency).Add(amount)
+	case entities.ErrPaymentDuplicate:
+		// replayed payment doesn't move money again
+	default:
+		imw.metrics.FailedPayments.With("type", paymentType, "reason", errorLabel(err)).Add(1)
+	}
+}
+
+// errorLabels are metric label values of sentinel errors
+var errorLabels = map[error]string{
+	entities.ErrAccountAlreadyExists:       "account_already_exists",
+	entities.ErrInsufficientFunds:          "insufficient_funds",
+	entities.ErrAccountNotFound:            "account_not_found",
+	entities.ErrRecipientNotFound:          "recipient_not_found",
+	entities.ErrDifferentCurrencies:        "different_currencies",
+	entities.ErrPaymentAlreadyDone:         "payment_already_done",
+	entities.ErrDatabaseConnection:         "database_connection",
+	entities.ErrIncomingPaymentsNotAllowed: "incoming_payments_not_allowed",
+	entities.ErrWrongPaymentAmount:         "wrong_payment_amount",
+	entities.ErrEmptyAccountID:             "empty_account_id",
+	entities.ErrEmptyAccountCurrency:       "empty_account_currency",
+	entities.ErrNegativeBalance:            "negative_balance",
+	entities.ErrEmptyPaymentSource:         "empty_payment_source",
+	entities.ErrEmptyPaymentDestination:    "empty_payment_destination",
+	entities.ErrEmptyPaymentID:             "empty_payment_id",
+	entities.ErrPaymentSourceNotFound:      "payment_source_not_found",
+	entities.ErrPaymentDestinationNotFound: "payment_destination_not_found",
+	entities.ErrPaymentSameAccount:         "payment_same_account",
+	entities.ErrInvalidCursor:              "invalid_cursor",
+	entities.ErrInvalidLimit:               "invalid_limit",
+	entities.ErrInvalidPaymentsQuery:       "invalid_payments_query",
+	entities.ErrInvalidAccountsQuery:       "invalid_accounts_query",
+	entities.ErrExchangeRateNotFound:       "exchange_rate_not_found",
+	entities.ErrUnknownCurrency:            "unknown_currency",
+	entities.ErrCurrencyDisabled:           "currency_disabled",
+	entities.ErrAmountPrecision:            "amount_precision",
+	entities.ErrForbidden:                  "forbidden",
+	entities.ErrInvalidAccountStatus:       "invalid_account_status",
+	entities.ErrAccountFrozen:              "account_frozen",
+	entities.ErrAccountClosed:              "account_closed",
+	entities.ErrAccountNotEmpty:            "account_not_empty",
+	entities.ErrReservedAccountID:          "reserved_account_id",
+	entities.ErrInitialBalanceNotAllowed:   "initial_balance_not_allowed",
+	entities.ErrEmptyHoldID:                "empty_hold_id",
+	entities.ErrHoldAlreadyExists:          "hold_already_exists",
+	entities.ErrHoldNotFound:               "hold_not_found",
+	entities.ErrHoldNotActive:              "hold_not_active",
+	entities.ErrInvalidHoldExpiry:          "invalid_hold_expiry",
+	entities.ErrWrongCaptureAmount:         "wrong_capture_amount",
+	entities.ErrPaymentNotFound:            "payment_not_found",
+	entities.ErrPaymentNotRefundable:       "payment_not_refundable",
+	entities.ErrRefundExceedsPayment:       "refund_exceeds_payment",
+}
+
+// errorLabel returns metric label value of error, errors other than sentinel ones
+// are labeled "internal" to keep the number of label values bounded
+func errorLabel(err error) string {
+	if label, ok := errorLabels[err]; ok {
+		return label
+	}
+	return "internal"
+}
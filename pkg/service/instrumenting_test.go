@@ -0,0 +1,158 @@
+package service_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shirolimit/wallet-service/pkg/db"
+	"github.com/shirolimit/wallet-service/pkg/entities"
+	"github.com/shirolimit/wallet-service/pkg/service"
+)
+
+// recorder is a metric that sums observed values by label values
+type recorder struct {
+	values map[string]float64
+	labels []string
+}
+
+func newRecorder() *recorder {
+	return &recorder{values: make(map[string]float64)}
+}
+
+func (r *recorder) With(labelValues ...string) metrics.Counter {
+	return &recorder{values: r.values, labels: append(append([]string{}, r.labels...), labelValues...)}
+}
+
+func (r *recorder) Add(delta float64) {
+	r.values[strings.Join(r.labels, ",")] += delta
+}
+
+func (r *recorder) Observe(value float64) {
+	r.Add(1)
+}
+
+// histogram adapts recorder to metrics.Histogram interface
+type histogram struct {
+	*recorder
+}
+
+func (h histogram) With(labelValues ...string) metrics.Histogram {
+	return histogram{h.recorder.With(labelValues...).(*recorder)}
+}
+
+func Test_InstrumentingMiddleware_MakePayment(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := db.NewMockStorage(ctrl)
+	expectAccounts(mockStorage,
+		entities.Account{ID: "alice", Balance: decimal.New(100, 0), Currency: "USD"},
+		entities.Account{ID: "bob", Balance: decimal.New(100, 0), Currency: "USD"},
+	)
+
+	m := service.Metrics{
+		RequestCount:   newRecorder(),
+		ErrorCount:     newRecorder(),
+		RequestLatency: histogram{newRecorder()},
+		PaymentVolume:  newRecorder(),
+		FailedPayments: newRecorder(),
+	}
+	svc := service.InstrumentingMiddleware(m)(service.NewWalletService(mockStorage))
+
+	bob := entities.AccountID("bob")
+	payment := entities.Payment{
+		ID:        uuid.New(),
+		Account:   "alice",
+		ToAccount: &bob,
+		Amount:    decimal.New(30, 0),
+		Direction: entities.Outgoing,
+	}
+	stored := payment
+	stored.Currency = "USD"
+
+	gomock.InOrder(
+		mockStorage.EXPECT().CreatePayment(context.TODO(), gomock.Any()).Return(&stored, nil),
+		mockStorage.EXPECT().CreatePayment(context.TODO(), gomock.Any()).Return(&stored, entities.ErrPaymentDuplicate),
+		mockStorage.EXPECT().CreatePayment(context.TODO(), gomock.Any()).Return(nil, entities.ErrInsufficientFunds),
+	)
+	for i := 0; i < 3; i++ {
+		svc.MakePayment(context.TODO(), payment)
+	}
+
+	tests := []struct {
+		name   string
+		metric metrics.Counter
+		key    string
+		want   float64
+	}{
+		{"requests", m.RequestCount, "method,MakePayment", 3},
+		{"errors", m.ErrorCount, "method,MakePayment,error,insufficient_funds", 1},
+		{"latency", m.RequestLatency.(histogram).recorder, "method,MakePayment", 3},
+		{"volume_excludes_replay", m.PaymentVolume, "type,payment,currency,USD", 30},
+		{"failed_payments", m.FailedPayments, "type,payment,reason,insufficient_funds", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := tt.metric.(*recorder).values
+			if got := values[tt.key]; got != tt.want || len(values) != 1 {
+				t.Errorf("%s = %v, want %s = %v", tt.name, values, tt.key, tt.want)
+			}
+		})
+	}
+}
+
+func Test_InstrumentingMiddleware_PaymentVolume(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := db.NewMockStorage(ctrl)
+	expectAccounts(mockStorage, entities.Account{ID: "alice", Balance: decimal.New(100, 0), Currency: "USD"})
+	mockStorage.EXPECT().CreatePayment(context.TODO(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, payment entities.Payment) (*entities.Payment, error) {
+			payment.Currency = "USD"
+			return &payment, nil
+		}).Times(2)
+	mockStorage.EXPECT().CaptureHold(context.TODO(), gomock.Any(), nil).
+		Return(&entities.Payment{Amount: decimal.New(5, 0), Currency: "USD"}, nil)
+	mockStorage.EXPECT().CreateRefund(context.TODO(), gomock.Any()).Return(nil, entities.ErrRefundExceedsPayment)
+
+	m := service.Metrics{
+		RequestCount:   newRecorder(),
+		ErrorCount:     newRecorder(),
+		RequestLatency: histogram{newRecorder()},
+		PaymentVolume:  newRecorder(),
+		FailedPayments: newRecorder(),
+	}
+	svc := service.InstrumentingMiddleware(m)(service.NewWalletService(mockStorage))
+
+	payment := func(amount int64) entities.Payment {
+		return entities.Payment{ID: uuid.New(), Account: "alice", Amount: decimal.New(amount, 0)}
+	}
+	svc.Deposit(context.TODO(), payment(50))
+	svc.Withdraw(context.TODO(), payment(20))
+	svc.CaptureHold(context.TODO(), uuid.New(), nil)
+	refundOf := uuid.New()
+	refund := payment(10)
+	refund.RefundOf = &refundOf
+	svc.RefundPayment(context.TODO(), refund)
+
+	want := map[string]float64{
+		"type,deposit,currency,USD":    50,
+		"type,withdrawal,currency,USD": 20,
+		"type,capture,currency,USD":    5,
+	}
+	if got := m.PaymentVolume.(*recorder).values; !reflect.DeepEqual(got, want) {
+		t.Errorf("PaymentVolume = %v, want %v", got, want)
+	}
+	wantFailed := map[string]float64{"type,refund,reason,refund_exceeds_payment": 1}
+	if got := m.FailedPayments.(*recorder).values; !reflect.DeepEqual(got, wantFailed) {
+		t.Errorf("FailedPayments = %v, want %v", got, wantFailed)
+	}
+}
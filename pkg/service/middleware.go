@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"github.com/go-kit/kit/log"
+	"github.com/google/uuid"
 	"github.com/shirolimit/wallet-service/pkg/entities"
+	"github.com/shopspring/decimal"
 )
 
 // Middleware is a service middleware type
@@ -30,7 +32,7 @@ func LoggingMiddleware(logger log.Logger) Middleware {
 
 // CreateAccount is a middleware function that prints information to log
 // Named return parameter is used for defer
-func (lmw loggingMiddleware) CreateAccount(ctx context.Context, acc entities.Account) (err error) {
+func (lmw loggingMiddleware) CreateAccount(ctx context.Context, acc entities.Account) (created entities.Account, err error) {
 	defer func(start time.Time) {
 		lmw.logger.Log(
 			"method", "CreateAccount",
@@ -44,17 +46,20 @@ func (lmw loggingMiddleware) CreateAccount(ctx context.Context, acc entities.Acc
 
 // ListAccounts is a middleware function that prints information to log
 // Named return parameters are used for defer
-func (lmw loggingMiddleware) ListAccounts(ctx context.Context) (accs []entities.AccountID, err error) {
+func (lmw loggingMiddleware) ListAccounts(ctx context.Context, query entities.AccountsQuery) (page entities.AccountsPage, err error) {
 	defer func(start time.Time) {
 		lmw.logger.Log(
 			"method", "ListAccounts",
-			"accounts", fmt.Sprintf("%v", accs),
+			"limit", query.Limit,
+			"cursor", query.Cursor,
+			"accounts", len(page.Accounts),
+			"next_cursor", page.NextCursor,
 			"error", err,
 			"duration", time.Since(start),
 		)
 	}(time.Now())
 
-	return lmw.next.ListAccounts(ctx)
+	return lmw.next.ListAccounts(ctx, query)
 }
 
 // GetAccount is a middleware function that prints information to log
@@ -73,29 +78,69 @@ func (lmw loggingMiddleware) GetAccount(ctx context.Context, id entities.Account
 	return lmw.next.GetAccount(ctx, id)
 }
 
+// UpdateAccount is a middleware function that prints information to log
+// Named return parameters are used for defer
+func (lmw loggingMiddleware) UpdateAccount(ctx context.Context, id entities.AccountID,
+	update entities.AccountUpdate) (acc entities.Account, err error) {
+
+	defer func(start time.Time) {
+		lmw.logger.Log(
+			"method", "UpdateAccount",
+			"id", id,
+			"account", acc,
+			"error", err,
+			"duration", time.Since(start),
+		)
+	}(time.Now())
+
+	return lmw.next.UpdateAccount(ctx, id, update)
+}
+
 // GetPayments is a middleware function that prints information to log
 // Named return parameters are used for defer
-func (lmw loggingMiddleware) GetPayments(ctx context.Context, id entities.AccountID) (payments []entities.Payment, err error) {
+func (lmw loggingMiddleware) GetPayments(ctx context.Context, id entities.AccountID,
+	query entities.PaymentsQuery) (page entities.PaymentsPage, err error) {
+
 	defer func(start time.Time) {
 		lmw.logger.Log(
 			"method", "GetPayments",
 			"id", id,
-			"payments", fmt.Sprintf("%v", payments),
+			"limit", query.Limit,
+			"cursor", query.Cursor,
+			"payments", fmt.Sprintf("%v", page.Payments),
+			"next_cursor", page.NextCursor,
 			"error", err,
 			"duration", time.Since(start),
 		)
 	}(time.Now())
 
-	return lmw.next.GetPayments(ctx, id)
+	return lmw.next.GetPayments(ctx, id, query)
+}
+
+// GetPayment is a middleware function that prints information to log
+// Named return parameters are used for defer
+func (lmw loggingMiddleware) GetPayment(ctx context.Context, id uuid.UUID) (record entities.PaymentRecord, err error) {
+	defer func(start time.Time) {
+		lmw.logger.Log(
+			"method", "GetPayment",
+			"id", id,
+			"payment", record,
+			"error", err,
+			"duration", time.Since(start),
+		)
+	}(time.Now())
+
+	return lmw.next.GetPayment(ctx, id)
 }
 
 // MakePayment is a middleware function that prints information to log
-// Named return parameter ise used for defer
-func (lmw loggingMiddleware) MakePayment(ctx context.Context, payment entities.Payment) (err error) {
+// Named return parameters are used for defer
+func (lmw loggingMiddleware) MakePayment(ctx context.Context, payment entities.Payment) (stored entities.Payment, err error) {
 	defer func(start time.Time) {
 		lmw.logger.Log(
 			"method", "MakePayment",
 			"payment", payment,
+			"stored", stored,
 			"error", err,
 			"duration", time.Since(start),
 		)
@@ -103,3 +148,118 @@ func (lmw loggingMiddleware) MakePayment(ctx context.Context, payment entities.P
 
 	return lmw.next.MakePayment(ctx, payment)
 }
+
+// Deposit is a middleware function that prints information to log
+// Named return parameters are used for defer
+func (lmw loggingMiddleware) Deposit(ctx context.Context, payment entities.Payment) (stored entities.Payment, err error) {
+	defer func(start time.Time) {
+		lmw.logger.Log(
+			"method", "Deposit",
+			"payment", payment,
+			"stored", stored,
+			"error", err,
+			"duration", time.Since(start),
+		)
+	}(time.Now())
+
+	return lmw.next.Deposit(ctx, payment)
+}
+
+// Withdraw is a middleware function that prints information to log
+// Named return parameters are used for defer
+func (lmw loggingMiddleware) Withdraw(ctx context.Context, payment entities.Payment) (stored entities.Payment, err error) {
+	defer func(start time.Time) {
+		lmw.logger.Log(
+			"method", "Withdraw",
+			"payment", payment,
+			"stored", stored,
+			"error", err,
+			"duration", time.Since(start),
+		)
+	}(time.Now())
+
+	return lmw.next.Withdraw(ctx, payment)
+}
+
+// CreateHold is a middleware function that prints information to log
+// Named return parameters are used for defer
+func (lmw loggingMiddleware) CreateHold(ctx context.Context, hold entities.Hold) (stored entities.Hold, err error) {
+	defer func(start time.Time) {
+		lmw.logger.Log(
+			"method", "CreateHold",
+			"hold", hold,
+			"stored", stored,
+			"error", err,
+			"duration", time.Since(start),
+		)
+	}(time.Now())
+
+	return lmw.next.CreateHold(ctx, hold)
+}
+
+// GetHold is a middleware function that prints information to log
+// Named return parameters are used for defer
+func (lmw loggingMiddleware) GetHold(ctx context.Context, id uuid.UUID) (hold entities.Hold, err error) {
+	defer func(start time.Time) {
+		lmw.logger.Log(
+			"method", "GetHold",
+			"id", id,
+			"hold", hold,
+			"error", err,
+			"duration", time.Since(start),
+		)
+	}(time.Now())
+
+	return lmw.next.GetHold(ctx, id)
+}
+
+// CaptureHold is a middleware function that prints information to log
+// Named return parameters are used for defer
+func (lmw loggingMiddleware) CaptureHold(ctx context.Context, id uuid.UUID,
+	amount *decimal.Decimal) (payment entities.Payment, err error) {
+
+	defer func(start time.Time) {
+		lmw.logger.Log(
+			"method", "CaptureHold",
+			"id", id,
+			"amount", amount,
+			"payment", payment,
+			"error", err,
+			"duration", time.Since(start),
+		)
+	}(time.Now())
+
+	return lmw.next.CaptureHold(ctx, id, amount)
+}
+
+// ReleaseHold is a middleware function that prints information to log
+// Named return parameters are used for defer
+func (lmw loggingMiddleware) ReleaseHold(ctx context.Context, id uuid.UUID) (hold entities.Hold, err error) {
+	defer func(start time.Time) {
+		lmw.logger.Log(
+			"method", "ReleaseHold",
+			"id", id,
+			"hold", hold,
+			"error", err,
+			"duration", time.Since(start),
+		)
+	}(time.Now())
+
+	return lmw.next.ReleaseHold(ctx, id)
+}
+
+// RefundPayment is a middleware function that prints information to log
+// Named return parameters are used for defer
+func (lmw loggingMiddleware) RefundPayment(ctx context.Context, refund entities.Payment) (stored entities.Payment, err error) {
+	defer func(start time.Time) {
+		lmw.logger.Log(
+			"method", "RefundPayment",
+			"refund", refund,
+			"stored", stored,
+			"error", err,
+			"duration", time.Since(start),
+		)
+	}(time.Now())
+
+	return lmw.next.RefundPayment(ctx, refund)
+}
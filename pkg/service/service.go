@@ -2,59 +2,187 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
 	"github.com/shirolimit/wallet-service/pkg/db"
 	"github.com/shirolimit/wallet-service/pkg/entities"
 )
 
 // WalletService is the main service interface
 type WalletService interface {
-	CreateAccount(ctx context.Context, account entities.Account) error
-	ListAccounts(ctx context.Context) ([]entities.AccountID, error)
+	CreateAccount(ctx context.Context, account entities.Account) (entities.Account, error)
+	ListAccounts(ctx context.Context, query entities.AccountsQuery) (entities.AccountsPage, error)
 	GetAccount(ctx context.Context, id entities.AccountID) (entities.Account, error)
+	UpdateAccount(ctx context.Context, id entities.AccountID, update entities.AccountUpdate) (entities.Account, error)
+
+	GetPayments(ctx context.Context, id entities.AccountID, query entities.PaymentsQuery) (entities.PaymentsPage, error)
+
+	// GetPayment returns direction-neutral record of payment
+	GetPayment(ctx context.Context, id uuid.UUID) (entities.PaymentRecord, error)
+	MakePayment(ctx context.Context, payment entities.Payment) (entities.Payment, error)
+
+	// Deposit credits account with money coming from external funding source
+	Deposit(ctx context.Context, payment entities.Payment) (entities.Payment, error)
+
+	// Withdraw debits account with money going to external funding source
+	Withdraw(ctx context.Context, payment entities.Payment) (entities.Payment, error)
+
+	// CreateHold reserves funds of account for a future payment to destination account
+	CreateHold(ctx context.Context, hold entities.Hold) (entities.Hold, error)
+	GetHold(ctx context.Context, id uuid.UUID) (entities.Hold, error)
+
+	// CaptureHold pays specified amount of active hold to its destination account,
+	// nil amount captures the whole hold. Retried capture returns the original payment and ErrPaymentDuplicate
+	CaptureHold(ctx context.Context, id uuid.UUID, amount *decimal.Decimal) (entities.Payment, error)
 
-	GetPayments(ctx context.Context, id entities.AccountID) ([]entities.Payment, error)
-	MakePayment(ctx context.Context, payment entities.Payment) error
+	// ReleaseHold cancels active hold without payment
+	ReleaseHold(ctx context.Context, id uuid.UUID) (entities.Hold, error)
+
+	// RefundPayment returns funds of the payment referenced by RefundOf from refund account,
+	// which is the destination of refunded payment, back to its source. Zero amount refunds
+	// the whole amount that hasn't been refunded yet
+	RefundPayment(ctx context.Context, refund entities.Payment) (entities.Payment, error)
+}
+
+// FXRateProvider provides exchange rates for payments between accounts with different currencies
+type FXRateProvider interface {
+	// Rate returns the price of one unit of from currency in to currency
+	// or ErrExchangeRateNotFound if the pair is not supported
+	Rate(ctx context.Context, from, to string) (entities.ExchangeRate, error)
+}
+
+// Option is an optional setting of WalletService
+type Option func(*walletService)
+
+// WithFXRateProvider allows payments between accounts with different currencies,
+// amounts are converted with the rates of specified provider
+func WithFXRateProvider(rates FXRateProvider) Option {
+	return func(ws *walletService) {
+		ws.rates = rates
+	}
+}
+
+// WithCurrencyRegistry replaces the default registry of all ISO 4217 currencies
+func WithCurrencyRegistry(currencies *entities.CurrencyRegistry) Option {
+	return func(ws *walletService) {
+		ws.currencies = currencies
+	}
+}
+
+// WithoutInitialBalance makes CreateAccount reject accounts with non-zero balance,
+// so that money can get into the system through deposits only
+func WithoutInitialBalance() Option {
+	return func(ws *walletService) {
+		ws.forbidInitialBalance = true
+	}
 }
 
 type walletService struct {
-	storage db.Storage
+	storage    db.Storage
+	rates      FXRateProvider
+	currencies *entities.CurrencyRegistry
+
+	forbidInitialBalance bool
 }
 
 var (
 	nullUUID = uuid.UUID{}
 )
 
+const (
+	// defaultPageLimit is used when page limit is not specified
+	defaultPageLimit = 50
+
+	// maxPageLimit is the largest allowed page size, bigger limits are reduced to it
+	maxPageLimit = 500
+
+	// defaultHoldTTL is used when hold expiration time is not specified
+	defaultHoldTTL = 7 * 24 * time.Hour
+
+	// maxHoldTTL limits how long funds can be held
+	maxHoldTTL = 30 * 24 * time.Hour
+)
+
 // NewWalletService creates new instance of walletService
-func NewWalletService(storage db.Storage) WalletService {
-	return &walletService{
-		storage: storage,
+func NewWalletService(storage db.Storage, options ...Option) WalletService {
+	ws := &walletService{
+		storage:    storage,
+		currencies: entities.DefaultCurrencyRegistry,
+	}
+	for _, option := range options {
+		option(ws)
 	}
+	return ws
 }
 
-func (ws *walletService) CreateAccount(ctx context.Context, acc entities.Account) error {
+func (ws *walletService) CreateAccount(ctx context.Context, acc entities.Account) (entities.Account, error) {
 	if len(acc.ID) == 0 {
-		return entities.ErrEmptyAccountID
+		return entities.Account{}, entities.ErrEmptyAccountID
+	}
+
+	if acc.ID.IsExternal() {
+		return entities.Account{}, entities.ErrReservedAccountID
 	}
 
 	if len(acc.Currency) == 0 {
-		return entities.ErrEmptyAccountCurrency
+		return entities.Account{}, entities.ErrEmptyAccountCurrency
 	}
 
 	if acc.Balance.IsNegative() {
-		return entities.ErrNegativeBalance
+		return entities.Account{}, entities.ErrNegativeBalance
+	}
+
+	if ws.forbidInitialBalance && !acc.Balance.IsZero() {
+		return entities.Account{}, entities.ErrInitialBalanceNotAllowed
+	}
+
+	// accounts are always opened active
+	acc.Status = entities.Active
+
+	currency, err := ws.currencies.Lookup(acc.Currency)
+	if err != nil {
+		return entities.Account{}, err
+	}
+
+	if !currency.ValidAmount(acc.Balance) {
+		return entities.Account{}, entities.ErrAmountPrecision
 	}
 
-	return ws.storage.CreateAccount(ctx, acc)
+	stored, err := ws.storage.CreateAccount(ctx, acc)
+	if err != nil {
+		return entities.Account{}, err
+	}
+	return *stored, nil
 }
 
-func (ws *walletService) ListAccounts(ctx context.Context) ([]entities.AccountID, error) {
-	accounts, err := ws.storage.ListAccounts(ctx)
-	if accounts == nil {
-		accounts = []entities.AccountID{}
+func (ws *walletService) ListAccounts(ctx context.Context, query entities.AccountsQuery) (entities.AccountsPage, error) {
+	// zero limit means all accounts for compatibility with clients that don't paginate
+	if query.Limit < 0 {
+		return entities.AccountsPage{}, entities.ErrInvalidLimit
+	}
+
+	if query.Limit > maxPageLimit {
+		query.Limit = maxPageLimit
+	}
+
+	sort, err := entities.ParseAccountsSort(string(query.Sort))
+	if err != nil {
+		return entities.AccountsPage{}, err
+	}
+	query.Sort = sort
+
+	if query.MinBalance != nil && query.MaxBalance != nil && query.MinBalance.GreaterThan(*query.MaxBalance) {
+		return entities.AccountsPage{}, entities.ErrInvalidAccountsQuery
+	}
+
+	page, err := ws.storage.ListAccounts(ctx, query)
+	if page.Accounts == nil {
+		page.Accounts = []entities.Account{}
 	}
-	return accounts, err
+	return page, err
 }
 
 func (ws *walletService) GetAccount(ctx context.Context, id entities.AccountID) (entities.Account, error) {
@@ -65,38 +193,382 @@ func (ws *walletService) GetAccount(ctx context.Context, id entities.AccountID)
 	return *acc, nil
 }
 
-func (ws *walletService) GetPayments(ctx context.Context, id entities.AccountID) ([]entities.Payment, error) {
-	payments, err := ws.storage.PaymentsByAccount(ctx, id)
-	if payments == nil {
-		payments = []entities.Payment{}
+func (ws *walletService) UpdateAccount(ctx context.Context, id entities.AccountID,
+	update entities.AccountUpdate) (entities.Account, error) {
+
+	if len(id) == 0 {
+		return entities.Account{}, entities.ErrEmptyAccountID
+	}
+
+	if id.IsExternal() {
+		return entities.Account{}, entities.ErrReservedAccountID
 	}
-	return payments, err
+
+	// status is the only field that can be changed for now
+	if update.Status == nil {
+		return ws.GetAccount(ctx, id)
+	}
+
+	acc, err := ws.storage.UpdateAccountStatus(ctx, id, *update.Status)
+	if err != nil {
+		return entities.Account{}, err
+	}
+	return *acc, nil
 }
 
-func (ws *walletService) MakePayment(ctx context.Context, payment entities.Payment) error {
+func (ws *walletService) GetPayments(ctx context.Context, id entities.AccountID,
+	query entities.PaymentsQuery) (entities.PaymentsPage, error) {
+
+	switch {
+	case query.Limit < 0:
+		return entities.PaymentsPage{}, entities.ErrInvalidLimit
+	case query.Limit == 0:
+		query.Limit = defaultPageLimit
+	case query.Limit > maxPageLimit:
+		query.Limit = maxPageLimit
+	}
+
+	if query.MinAmount != nil && query.MaxAmount != nil && query.MinAmount.GreaterThan(*query.MaxAmount) {
+		return entities.PaymentsPage{}, entities.ErrInvalidPaymentsQuery
+	}
+
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return entities.PaymentsPage{}, entities.ErrInvalidPaymentsQuery
+	}
+
+	page, err := ws.storage.PaymentsByAccount(ctx, id, query)
+	if page.Payments == nil {
+		page.Payments = []entities.Payment{}
+	}
+	return page, err
+}
+
+func (ws *walletService) GetPayment(ctx context.Context, id uuid.UUID) (entities.PaymentRecord, error) {
+	record, err := ws.storage.GetPayment(ctx, id)
+	if err != nil {
+		return entities.PaymentRecord{}, err
+	}
+	return *record, nil
+}
+
+func (ws *walletService) MakePayment(ctx context.Context, payment entities.Payment) (entities.Payment, error) {
 	if payment.ID == nullUUID {
-		return entities.ErrEmptyPaymentID
+		return entities.Payment{}, entities.ErrEmptyPaymentID
 	}
 
 	if len(payment.Account) == 0 {
-		return entities.ErrEmptyAccountID
+		return entities.Payment{}, entities.ErrEmptyAccountID
 	}
 
 	if payment.ToAccount == nil || len(*payment.ToAccount) == 0 {
-		return entities.ErrEmptyPaymentDestination
+		return entities.Payment{}, entities.ErrEmptyPaymentDestination
 	}
 
 	if *payment.ToAccount == payment.Account {
-		return entities.ErrPaymentSameAccount
+		return entities.Payment{}, entities.ErrPaymentSameAccount
+	}
+
+	// external accounts are changed by deposits and withdrawals only
+	if payment.Account.IsExternal() || payment.ToAccount.IsExternal() {
+		return entities.Payment{}, entities.ErrReservedAccountID
 	}
 
 	if payment.Amount.IsNegative() || payment.Amount.IsZero() {
-		return entities.ErrWrongPaymentAmount
+		return entities.Payment{}, entities.ErrWrongPaymentAmount
 	}
 
 	if payment.Direction == entities.Incoming {
-		return entities.ErrIncomingPaymentsNotAllowed
+		return entities.Payment{}, entities.ErrIncomingPaymentsNotAllowed
+	}
+
+	// refunds are made by RefundPayment only, which checks the refunded payment
+	payment.Type = entities.RegularPayment
+	payment.RefundOf = nil
+
+	// exchange is always calculated by server
+	exchange, err := ws.convert(ctx, payment)
+	if err != nil {
+		return entities.Payment{}, err
+	}
+	payment.Exchange = exchange
+
+	// ErrPaymentDuplicate comes with the original payment
+	stored, err := ws.storage.CreatePayment(ctx, payment)
+	if stored == nil {
+		return entities.Payment{}, err
+	}
+	return *stored, err
+}
+
+func (ws *walletService) Deposit(ctx context.Context, payment entities.Payment) (entities.Payment, error) {
+	return ws.moveExternal(ctx, payment, entities.Incoming)
+}
+
+func (ws *walletService) Withdraw(ctx context.Context, payment entities.Payment) (entities.Payment, error) {
+	return ws.moveExternal(ctx, payment, entities.Outgoing)
+}
+
+// moveExternal makes payment between account and external account of its currency
+// in specified direction. External account is created on the first use
+func (ws *walletService) moveExternal(ctx context.Context, payment entities.Payment,
+	direction entities.PaymentDirection) (entities.Payment, error) {
+
+	if payment.ID == nullUUID {
+		return entities.Payment{}, entities.ErrEmptyPaymentID
+	}
+
+	if len(payment.Account) == 0 {
+		return entities.Payment{}, entities.ErrEmptyAccountID
+	}
+
+	if payment.Account.IsExternal() {
+		return entities.Payment{}, entities.ErrReservedAccountID
+	}
+
+	if payment.Amount.IsNegative() || payment.Amount.IsZero() {
+		return entities.Payment{}, entities.ErrWrongPaymentAmount
+	}
+
+	acc, err := ws.storage.GetAccount(ctx, payment.Account)
+	if err != nil {
+		return entities.Payment{}, err
+	}
+
+	currency, err := ws.currencies.Lookup(acc.Currency)
+	if err != nil {
+		return entities.Payment{}, err
+	}
+
+	if !currency.ValidAmount(payment.Amount) {
+		return entities.Payment{}, entities.ErrAmountPrecision
+	}
+
+	external := entities.ExternalAccountID(acc.Currency)
+	payment.Direction = direction
+	payment.Exchange = nil
+	payment.Type = entities.RegularPayment
+	payment.RefundOf = nil
+	missingExternal := entities.ErrPaymentSourceNotFound
+	if direction == entities.Incoming {
+		payment.FromAccount, payment.ToAccount = &external, nil
+	} else {
+		payment.FromAccount, payment.ToAccount = nil, &external
+		missingExternal = entities.ErrPaymentDestinationNotFound
+	}
+
+	stored, err := ws.storage.CreatePayment(ctx, payment)
+	if err == missingExternal {
+		_, err = ws.storage.CreateAccount(ctx, entities.Account{ID: external, Currency: acc.Currency})
+		if err != nil && err != entities.ErrAccountAlreadyExists {
+			return entities.Payment{}, err
+		}
+		stored, err = ws.storage.CreatePayment(ctx, payment)
+	}
+
+	// ErrPaymentDuplicate comes with the original payment
+	if stored == nil {
+		return entities.Payment{}, err
+	}
+	return *stored, err
+}
+
+func (ws *walletService) CreateHold(ctx context.Context, hold entities.Hold) (entities.Hold, error) {
+	if hold.ID == nullUUID {
+		return entities.Hold{}, entities.ErrEmptyHoldID
+	}
+
+	if len(hold.Account) == 0 {
+		return entities.Hold{}, entities.ErrEmptyAccountID
+	}
+
+	if len(hold.ToAccount) == 0 {
+		return entities.Hold{}, entities.ErrEmptyPaymentDestination
+	}
+
+	if hold.ToAccount == hold.Account {
+		return entities.Hold{}, entities.ErrPaymentSameAccount
+	}
+
+	if hold.Account.IsExternal() || hold.ToAccount.IsExternal() {
+		return entities.Hold{}, entities.ErrReservedAccountID
+	}
+
+	if hold.Amount.IsNegative() || hold.Amount.IsZero() {
+		return entities.Hold{}, entities.ErrWrongPaymentAmount
+	}
+
+	now := time.Now()
+	if hold.ExpiresAt.IsZero() {
+		hold.ExpiresAt = now.Add(defaultHoldTTL)
+	}
+	if !hold.ExpiresAt.After(now) || hold.ExpiresAt.After(now.Add(maxHoldTTL)) {
+		return entities.Hold{}, entities.ErrInvalidHoldExpiry
+	}
+
+	source, err := ws.storage.GetAccount(ctx, hold.Account)
+	if err == entities.ErrAccountNotFound {
+		return entities.Hold{}, entities.ErrPaymentSourceNotFound
+	}
+	if err != nil {
+		return entities.Hold{}, err
+	}
+
+	if err = ws.checkPrecision(source.Currency, hold.Amount); err != nil {
+		return entities.Hold{}, err
+	}
+
+	stored, err := ws.storage.CreateHold(ctx, hold)
+	if err != nil {
+		return entities.Hold{}, err
+	}
+	return *stored, nil
+}
+
+func (ws *walletService) GetHold(ctx context.Context, id uuid.UUID) (entities.Hold, error) {
+	hold, err := ws.storage.GetHold(ctx, id)
+	if err != nil {
+		return entities.Hold{}, err
+	}
+	return *hold, nil
+}
+
+func (ws *walletService) CaptureHold(ctx context.Context, id uuid.UUID, amount *decimal.Decimal) (entities.Payment, error) {
+	if id == nullUUID {
+		return entities.Payment{}, entities.ErrEmptyHoldID
+	}
+
+	if amount != nil {
+		if !amount.IsPositive() {
+			return entities.Payment{}, entities.ErrWrongCaptureAmount
+		}
+
+		hold, err := ws.storage.GetHold(ctx, id)
+		if err != nil {
+			return entities.Payment{}, err
+		}
+
+		if err = ws.checkPrecision(hold.Currency, *amount); err != nil {
+			return entities.Payment{}, err
+		}
+	}
+
+	// ErrPaymentDuplicate comes with the payment of the original capture
+	payment, err := ws.storage.CaptureHold(ctx, id, amount)
+	if payment == nil {
+		return entities.Payment{}, err
+	}
+	return *payment, err
+}
+
+func (ws *walletService) ReleaseHold(ctx context.Context, id uuid.UUID) (entities.Hold, error) {
+	if id == nullUUID {
+		return entities.Hold{}, entities.ErrEmptyHoldID
+	}
+
+	hold, err := ws.storage.ReleaseHold(ctx, id)
+	if err != nil {
+		return entities.Hold{}, err
+	}
+	return *hold, nil
+}
+
+func (ws *walletService) RefundPayment(ctx context.Context, refund entities.Payment) (entities.Payment, error) {
+	if refund.ID == nullUUID {
+		return entities.Payment{}, entities.ErrEmptyPaymentID
+	}
+
+	if len(refund.Account) == 0 {
+		return entities.Payment{}, entities.ErrEmptyAccountID
+	}
+
+	if refund.RefundOf == nil || *refund.RefundOf == nullUUID {
+		return entities.Payment{}, entities.ErrPaymentNotFound
+	}
+
+	if refund.Amount.IsNegative() {
+		return entities.Payment{}, entities.ErrWrongPaymentAmount
+	}
+
+	if !refund.Amount.IsZero() {
+		acc, err := ws.storage.GetAccount(ctx, refund.Account)
+		if err != nil {
+			return entities.Payment{}, err
+		}
+
+		if err = ws.checkPrecision(acc.Currency, refund.Amount); err != nil {
+			return entities.Payment{}, err
+		}
+	}
+
+	// ErrPaymentDuplicate comes with the original refund
+	stored, err := ws.storage.CreateRefund(ctx, refund)
+	if stored == nil {
+		return entities.Payment{}, err
+	}
+	return *stored, err
+}
+
+// checkPrecision returns an error if amount is finer than the minor unit of currency
+func (ws *walletService) checkPrecision(code string, amount decimal.Decimal) error {
+	currency, err := ws.currencies.Lookup(code)
+	if err != nil {
+		return err
 	}
 
-	return ws.storage.CreatePayment(ctx, payment)
+	if !currency.ValidAmount(amount) {
+		return entities.ErrAmountPrecision
+	}
+	return nil
+}
+
+// convert checks payment amount against the currencies of source and destination accounts
+// and converts it if currencies differ and exchange is allowed. Nil is returned if there is no need
+// in conversion, storage rejects payments between accounts with different currencies in this case
+func (ws *walletService) convert(ctx context.Context, payment entities.Payment) (*entities.Exchange, error) {
+	source, err := ws.storage.GetAccount(ctx, payment.Account)
+	if err == entities.ErrAccountNotFound {
+		return nil, entities.ErrPaymentSourceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	destination, err := ws.storage.GetAccount(ctx, *payment.ToAccount)
+	if err == entities.ErrAccountNotFound {
+		return nil, entities.ErrPaymentDestinationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sourceCurrency, err := ws.currencies.Lookup(source.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if !sourceCurrency.ValidAmount(payment.Amount) {
+		return nil, entities.ErrAmountPrecision
+	}
+
+	if source.Currency == destination.Currency || ws.rates == nil {
+		return nil, nil
+	}
+
+	destinationCurrency, err := ws.currencies.Lookup(destination.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	rate, err := ws.rates.Rate(ctx, source.Currency, destination.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	exchange := entities.NewExchange(payment.Amount, rate)
+	exchange.DestinationAmount = destinationCurrency.Round(exchange.DestinationAmount)
+	if !exchange.DestinationAmount.IsPositive() {
+		// amount is too small to be converted
+		return nil, entities.ErrWrongPaymentAmount
+	}
+	return exchange, nil
 }
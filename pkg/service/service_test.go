@@ -4,6 +4,7 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/shirolimit/wallet-service/pkg/db"
 	"github.com/shirolimit/wallet-service/pkg/entities"
+	"github.com/shirolimit/wallet-service/pkg/fx"
 )
 
 func Test_walletService_CreateAccount(t *testing.T) {
@@ -33,6 +35,12 @@ func Test_walletService_CreateAccount(t *testing.T) {
 			true,
 			false,
 		},
+		{
+			"error_on_reserved_account_id",
+			args{acc: entities.Account{ID: entities.ExternalAccountID("USD"), Currency: "USD", Balance: decimal.New(100, 0)}},
+			true,
+			false,
+		},
 		{
 			"error_on_empty_currency",
 			args{acc: entities.Account{ID: "alice", Currency: "", Balance: decimal.New(100, 0)}},
@@ -45,6 +53,18 @@ func Test_walletService_CreateAccount(t *testing.T) {
 			true,
 			false,
 		},
+		{
+			"error_on_unknown_currency",
+			args{acc: entities.Account{ID: "alice", Currency: "usd", Balance: decimal.New(100, 0)}},
+			true,
+			false,
+		},
+		{
+			"error_on_balance_precision",
+			args{acc: entities.Account{ID: "alice", Currency: "JPY", Balance: decimal.New(1, -2)}},
+			true,
+			false,
+		},
 		{
 			"calls_storage",
 			args{acc: entities.Account{ID: "alice", Currency: "USD", Balance: decimal.New(100, 0)}},
@@ -69,36 +89,128 @@ func Test_walletService_CreateAccount(t *testing.T) {
 			mockStorage := db.NewMockStorage(ctrl)
 			svc := service.NewWalletService(mockStorage)
 			if tt.wantCall {
-				mockStorage.EXPECT().CreateAccount(context.TODO(), tt.args.acc).Return(tt.args.storageError)
+				mockStorage.EXPECT().CreateAccount(context.TODO(), tt.args.acc).
+					DoAndReturn(func(ctx context.Context, acc entities.Account) (*entities.Account, error) {
+						if tt.args.storageError != nil {
+							return nil, tt.args.storageError
+						}
+						return &acc, nil
+					})
 			}
-			if err := svc.CreateAccount(context.TODO(), tt.args.acc); (err != nil) != tt.wantErr {
+			if _, err := svc.CreateAccount(context.TODO(), tt.args.acc); (err != nil) != tt.wantErr {
 				t.Errorf("walletService.CreateAccount() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
+func Test_walletService_CreateAccountWithoutInitialBalance(t *testing.T) {
+	svc := service.NewWalletService(db.NewMemoryStorage(), service.WithoutInitialBalance())
+
+	funded := entities.Account{ID: "alice", Currency: "USD", Balance: decimal.New(100, 0)}
+	if _, err := svc.CreateAccount(context.TODO(), funded); err != entities.ErrInitialBalanceNotAllowed {
+		t.Errorf("walletService.CreateAccount() error = %v, wantErr %v", err, entities.ErrInitialBalanceNotAllowed)
+	}
+
+	empty := entities.Account{ID: "alice", Currency: "USD"}
+	if _, err := svc.CreateAccount(context.TODO(), empty); err != nil {
+		t.Errorf("walletService.CreateAccount() error = %v", err)
+	}
+}
+
 func Test_walletService_ListAccounts(t *testing.T) {
+	minBalance, maxBalance := decimal.New(100, 0), decimal.New(10, 0)
 	type args struct {
-		storageData  []entities.AccountID
+		query        entities.AccountsQuery
+		storageQuery entities.AccountsQuery
+		storageData  entities.AccountsPage
 		storageError error
 	}
 	tests := []struct {
-		name    string
-		args    args
-		want    []entities.AccountID
-		wantErr bool
+		name     string
+		args     args
+		want     entities.AccountsPage
+		wantErr  bool
+		wantCall bool
 	}{
 		{
 			"calls_storage",
-			args{storageData: []entities.AccountID{"alice", "bob"}, storageError: nil},
-			[]entities.AccountID{"alice", "bob"},
+			args{
+				query:        entities.AccountsQuery{Limit: 2, IDPrefix: "a"},
+				storageQuery: entities.AccountsQuery{Limit: 2, IDPrefix: "a", Sort: entities.SortByID},
+				storageData: entities.AccountsPage{
+					Accounts: []entities.Account{
+						{ID: "alice", Balance: decimal.New(100, 0), Currency: "USD"},
+						{ID: "alex", Balance: decimal.New(200, 0), Currency: "USD"},
+					},
+					NextCursor: "cursor",
+				},
+				storageError: nil,
+			},
+			entities.AccountsPage{
+				Accounts: []entities.Account{
+					{ID: "alice", Balance: decimal.New(100, 0), Currency: "USD"},
+					{ID: "alex", Balance: decimal.New(200, 0), Currency: "USD"},
+				},
+				NextCursor: "cursor",
+			},
+			false,
+			true,
+		},
+		{
+			"unlimited_by_default",
+			args{
+				storageQuery: entities.AccountsQuery{Sort: entities.SortByID},
+			},
+			entities.AccountsPage{Accounts: []entities.Account{}},
+			false,
+			true,
+		},
+		{
+			"max_limit",
+			args{
+				query:        entities.AccountsQuery{Limit: 100000, Sort: entities.SortByBalanceDesc},
+				storageQuery: entities.AccountsQuery{Limit: 500, Sort: entities.SortByBalanceDesc},
+			},
+			entities.AccountsPage{Accounts: []entities.Account{}},
+			false,
+			true,
+		},
+		{
+			"error_on_negative_limit",
+			args{
+				query: entities.AccountsQuery{Limit: -1},
+			},
+			entities.AccountsPage{},
+			true,
+			false,
+		},
+		{
+			"error_on_unknown_sort",
+			args{
+				query: entities.AccountsQuery{Sort: "currency"},
+			},
+			entities.AccountsPage{},
+			true,
+			false,
+		},
+		{
+			"error_on_wrong_balance_range",
+			args{
+				query: entities.AccountsQuery{MinBalance: &minBalance, MaxBalance: &maxBalance},
+			},
+			entities.AccountsPage{},
+			true,
 			false,
 		},
 		{
 			"error_on_storage_error",
-			args{storageData: nil, storageError: entities.ErrDatabaseConnection},
-			[]entities.AccountID{},
+			args{
+				storageQuery: entities.AccountsQuery{Sort: entities.SortByID},
+				storageError: entities.ErrDatabaseConnection,
+			},
+			entities.AccountsPage{Accounts: []entities.Account{}},
+			true,
 			true,
 		},
 	}
@@ -110,8 +222,11 @@ func Test_walletService_ListAccounts(t *testing.T) {
 			mockStorage := db.NewMockStorage(ctrl)
 			svc := service.NewWalletService(mockStorage)
 
-			mockStorage.EXPECT().ListAccounts(context.TODO()).Return(tt.args.storageData, tt.args.storageError)
-			got, err := svc.ListAccounts(context.TODO())
+			if tt.wantCall {
+				mockStorage.EXPECT().ListAccounts(context.TODO(), tt.args.storageQuery).
+					Return(tt.args.storageData, tt.args.storageError)
+			}
+			got, err := svc.ListAccounts(context.TODO(), tt.args.query)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("walletService.ListAccounts() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -183,27 +298,347 @@ func Test_walletService_GetAccount(t *testing.T) {
 	}
 }
 
+func Test_walletService_UpdateAccount(t *testing.T) {
+	frozen := entities.Frozen
+	alice := entities.Account{ID: "alice", Balance: decimal.New(100, 0), Currency: "USD"}
+
+	tests := []struct {
+		name         string
+		id           entities.AccountID
+		update       entities.AccountUpdate
+		storageError error
+		want         entities.Account
+		wantErr      error
+	}{
+		{"error_on_empty_account_id", "", entities.AccountUpdate{Status: &frozen}, nil, entities.Account{}, entities.ErrEmptyAccountID},
+		{"empty_update_returns_account", "alice", entities.AccountUpdate{}, nil, alice, nil},
+		{"updates_status", "alice", entities.AccountUpdate{Status: &frozen}, nil,
+			entities.Account{ID: "alice", Balance: decimal.New(100, 0), Currency: "USD", Status: entities.Frozen}, nil},
+		{"error_on_storage_error", "alice", entities.AccountUpdate{Status: &frozen}, entities.ErrAccountClosed,
+			entities.Account{}, entities.ErrAccountClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := db.NewMockStorage(ctrl)
+			expectAccounts(mockStorage, alice)
+			if tt.update.Status != nil && tt.id != "" {
+				mockStorage.EXPECT().UpdateAccountStatus(gomock.Any(), tt.id, *tt.update.Status).
+					DoAndReturn(func(ctx context.Context, id entities.AccountID, status entities.AccountStatus) (*entities.Account, error) {
+						if tt.storageError != nil {
+							return nil, tt.storageError
+						}
+						updated := alice
+						updated.Status = status
+						return &updated, nil
+					})
+			}
+
+			svc := service.NewWalletService(mockStorage)
+			got, err := svc.UpdateAccount(context.TODO(), tt.id, tt.update)
+			if err != tt.wantErr {
+				t.Errorf("walletService.UpdateAccount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("walletService.UpdateAccount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_walletService_DepositWithdraw(t *testing.T) {
+	svc := service.NewWalletService(db.NewMemoryStorage())
+	for _, acc := range []entities.Account{
+		{ID: "alice", Currency: "USD"},
+		{ID: "bob", Currency: "JPY"},
+	} {
+		if _, err := svc.CreateAccount(context.TODO(), acc); err != nil {
+			t.Fatalf("Error while creating account: %v", err)
+		}
+	}
+
+	payment := func(account string, amount decimal.Decimal) entities.Payment {
+		return entities.Payment{ID: uuid.New(), Account: entities.AccountID(account), Amount: amount}
+	}
+	deposit := payment("alice", decimal.New(100, 0))
+
+	// steps are run in order, each one depends on the state left by previous ones
+	tests := []struct {
+		name     string
+		withdraw bool
+		payment  entities.Payment
+		wantErr  error
+	}{
+		{"error_on_empty_payment_id", false, entities.Payment{Account: "alice", Amount: decimal.New(10, 0)}, entities.ErrEmptyPaymentID},
+		{"error_on_wrong_amount", false, payment("alice", decimal.New(-10, 0)), entities.ErrWrongPaymentAmount},
+		{"error_on_amount_precision", false, payment("bob", decimal.New(1, -1)), entities.ErrAmountPrecision},
+		{"error_on_unknown_account", false, payment("mallory", decimal.New(10, 0)), entities.ErrAccountNotFound},
+		{"error_on_external_account", false, payment(string(entities.ExternalAccountID("USD")), decimal.New(10, 0)), entities.ErrReservedAccountID},
+		{"deposit", false, deposit, nil},
+		{"deposit_replayed", false, deposit, entities.ErrPaymentDuplicate},
+		{"withdraw", true, payment("alice", decimal.New(30, 0)), nil},
+		{"error_on_insufficient_funds", true, payment("alice", decimal.New(71, 0)), entities.ErrInsufficientFunds},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.withdraw {
+				_, err = svc.Withdraw(context.TODO(), tt.payment)
+			} else {
+				_, err = svc.Deposit(context.TODO(), tt.payment)
+			}
+			if err != tt.wantErr {
+				t.Errorf("walletService error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	alice, _ := svc.GetAccount(context.TODO(), "alice")
+	external, _ := svc.GetAccount(context.TODO(), entities.ExternalAccountID("USD"))
+	if !alice.Balance.Equal(decimal.New(70, 0)) || !external.Balance.Equal(decimal.New(-70, 0)) {
+		t.Errorf("Expectation failed. Actual balances: alice = %v, external = %v", alice.Balance, external.Balance)
+	}
+
+	page, err := svc.GetPayments(context.TODO(), "alice", entities.PaymentsQuery{})
+	if err != nil {
+		t.Fatalf("Error while quering payments: %v", err)
+	}
+	if len(page.Payments) != 2 || page.Payments[0].Direction != entities.Incoming || page.Payments[1].Direction != entities.Outgoing {
+		t.Errorf("Expectation failed. Actual payments = %v", page.Payments)
+	}
+}
+
+func Test_walletService_Holds(t *testing.T) {
+	svc := service.NewWalletService(db.NewMemoryStorage())
+	for _, acc := range []entities.Account{
+		{ID: "alice", Currency: "USD", Balance: decimal.New(100, 0)},
+		{ID: "bob", Currency: "USD"},
+	} {
+		if _, err := svc.CreateAccount(context.TODO(), acc); err != nil {
+			t.Fatalf("Error while creating account: %v", err)
+		}
+	}
+
+	hold := func(to string, amount decimal.Decimal, expiresAt time.Time) entities.Hold {
+		return entities.Hold{ID: uuid.New(), Account: "alice", ToAccount: entities.AccountID(to), Amount: amount, ExpiresAt: expiresAt}
+	}
+	ten := decimal.New(10, 0)
+
+	tests := []struct {
+		name    string
+		hold    entities.Hold
+		wantErr error
+	}{
+		{"error_on_empty_hold_id", entities.Hold{Account: "alice", ToAccount: "bob", Amount: ten}, entities.ErrEmptyHoldID},
+		{"error_on_same_account", hold("alice", ten, time.Time{}), entities.ErrPaymentSameAccount},
+		{"error_on_external_account", hold(string(entities.ExternalAccountID("USD")), ten, time.Time{}), entities.ErrReservedAccountID},
+		{"error_on_wrong_amount", hold("bob", decimal.New(-10, 0), time.Time{}), entities.ErrWrongPaymentAmount},
+		{"error_on_amount_precision", hold("bob", decimal.New(1, -3), time.Time{}), entities.ErrAmountPrecision},
+		{"error_on_past_expiry", hold("bob", ten, time.Now().Add(-time.Minute)), entities.ErrInvalidHoldExpiry},
+		{"error_on_distant_expiry", hold("bob", ten, time.Now().AddDate(1, 0, 0)), entities.ErrInvalidHoldExpiry},
+		{"error_on_unknown_destination", hold("mallory", ten, time.Time{}), entities.ErrPaymentDestinationNotFound},
+		{"error_on_insufficient_funds", hold("bob", decimal.New(101, 0), time.Time{}), entities.ErrInsufficientFunds},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.CreateHold(context.TODO(), tt.hold); err != tt.wantErr {
+				t.Errorf("walletService.CreateHold() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	stored, err := svc.CreateHold(context.TODO(), hold("bob", decimal.New(60, 0), time.Time{}))
+	if err != nil {
+		t.Fatalf("Error while creating hold: %v", err)
+	}
+	if stored.Status != entities.HoldActive || stored.Currency != "USD" || stored.ExpiresAt.Before(time.Now().Add(24*time.Hour)) {
+		t.Errorf("Expectation failed. Actual hold = %v", stored)
+	}
+
+	fine := decimal.New(1, -3)
+	if _, err := svc.CaptureHold(context.TODO(), stored.ID, &fine); err != entities.ErrAmountPrecision {
+		t.Errorf("walletService.CaptureHold() error = %v, wantErr %v", err, entities.ErrAmountPrecision)
+	}
+
+	partial := decimal.New(45, 0)
+	payment, err := svc.CaptureHold(context.TODO(), stored.ID, &partial)
+	if err != nil {
+		t.Fatalf("Error while capturing hold: %v", err)
+	}
+	if payment.ID != stored.ID || !payment.Amount.Equal(partial) || *payment.ToAccount != "bob" {
+		t.Errorf("Expectation failed. Actual payment = %v", payment)
+	}
+
+	alice, _ := svc.GetAccount(context.TODO(), "alice")
+	if !alice.Balance.Equal(decimal.New(55, 0)) || !alice.AvailableBalance.Equal(decimal.New(55, 0)) {
+		t.Errorf("Expectation failed. Actual account = %v", alice)
+	}
+}
+
+func Test_walletService_RefundPayment(t *testing.T) {
+	svc := service.NewWalletService(db.NewMemoryStorage())
+	for _, acc := range []entities.Account{
+		{ID: "alice", Currency: "USD", Balance: decimal.New(100, 0)},
+		{ID: "bob", Currency: "USD"},
+	} {
+		if _, err := svc.CreateAccount(context.TODO(), acc); err != nil {
+			t.Fatalf("Error while creating account: %v", err)
+		}
+	}
+
+	payment, err := svc.MakePayment(context.TODO(), entities.Payment{
+		ID:        uuid.New(),
+		Account:   "alice",
+		ToAccount: accountIDRef("bob"),
+		Amount:    decimal.New(60, 0),
+		Direction: entities.Outgoing,
+	})
+	if err != nil {
+		t.Fatalf("Error while making payment: %v", err)
+	}
+
+	refund := func(id uuid.UUID, amount decimal.Decimal) entities.Payment {
+		return entities.Payment{ID: id, Account: "bob", RefundOf: &payment.ID, Amount: amount}
+	}
+	ten := decimal.New(10, 0)
+
+	tests := []struct {
+		name    string
+		refund  entities.Payment
+		wantErr error
+	}{
+		{"error_on_empty_refund_id", refund(uuid.UUID{}, ten), entities.ErrEmptyPaymentID},
+		{"error_on_empty_account", entities.Payment{ID: uuid.New(), RefundOf: &payment.ID, Amount: ten}, entities.ErrEmptyAccountID},
+		{"error_on_missing_payment", entities.Payment{ID: uuid.New(), Account: "bob", Amount: ten}, entities.ErrPaymentNotFound},
+		{"error_on_negative_amount", refund(uuid.New(), decimal.New(-10, 0)), entities.ErrWrongPaymentAmount},
+		{"error_on_amount_precision", refund(uuid.New(), decimal.New(1, -3)), entities.ErrAmountPrecision},
+		{"error_on_exceeding_amount", refund(uuid.New(), decimal.New(61, 0)), entities.ErrRefundExceedsPayment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.RefundPayment(context.TODO(), tt.refund); err != tt.wantErr {
+				t.Errorf("walletService.RefundPayment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	partial := refund(uuid.New(), decimal.New(15, 0))
+	for _, wantErr := range []error{nil, entities.ErrPaymentDuplicate} {
+		stored, err := svc.RefundPayment(context.TODO(), partial)
+		if err != wantErr {
+			t.Errorf("walletService.RefundPayment() error = %v, wantErr %v", err, wantErr)
+		}
+		if stored.Type != entities.Refund || *stored.RefundOf != payment.ID || *stored.ToAccount != "alice" {
+			t.Errorf("Expectation failed. Actual refund = %v", stored)
+		}
+	}
+
+	rest, err := svc.RefundPayment(context.TODO(), refund(uuid.New(), decimal.Zero))
+	if err != nil {
+		t.Fatalf("Error while refunding payment: %v", err)
+	}
+	if !rest.Amount.Equal(decimal.New(45, 0)) {
+		t.Errorf("Expectation failed. Expected refund of the rest of payment, actual = %v", rest)
+	}
+
+	alice, _ := svc.GetAccount(context.TODO(), "alice")
+	if !alice.Balance.Equal(decimal.New(100, 0)) {
+		t.Errorf("Expectation failed. Actual account = %v", alice)
+	}
+}
+
+func Test_walletService_MakePaymentForgedRefund(t *testing.T) {
+	svc := service.NewWalletService(db.NewMemoryStorage())
+	for _, acc := range []entities.Account{
+		{ID: "alice", Currency: "USD", Balance: decimal.New(100, 0)},
+		{ID: "bob", Currency: "USD"},
+		{ID: "mallory", Currency: "USD", Balance: decimal.New(100, 0)},
+	} {
+		if _, err := svc.CreateAccount(context.TODO(), acc); err != nil {
+			t.Fatalf("Error while creating account: %v", err)
+		}
+	}
+
+	payment, err := svc.MakePayment(context.TODO(), entities.Payment{
+		ID:        uuid.New(),
+		Account:   "alice",
+		ToAccount: accountIDRef("bob"),
+		Amount:    decimal.New(60, 0),
+		Direction: entities.Outgoing,
+	})
+	if err != nil {
+		t.Fatalf("Error while making payment: %v", err)
+	}
+
+	// payment claiming to refund someone else's payment is stored as a regular one
+	forged, err := svc.MakePayment(context.TODO(), entities.Payment{
+		ID:        uuid.New(),
+		Account:   "mallory",
+		ToAccount: accountIDRef("alice"),
+		Amount:    decimal.New(50, 0),
+		Direction: entities.Outgoing,
+		Type:      entities.Refund,
+		RefundOf:  &payment.ID,
+	})
+	if err != nil {
+		t.Fatalf("Error while making payment: %v", err)
+	}
+	if forged.Type != entities.RegularPayment || forged.RefundOf != nil {
+		t.Errorf("Expectation failed. Expected regular payment, actual = %v", forged)
+	}
+
+	refund, err := svc.RefundPayment(context.TODO(), entities.Payment{ID: uuid.New(), Account: "bob", RefundOf: &payment.ID})
+	if err != nil {
+		t.Fatalf("Error while refunding payment: %v", err)
+	}
+	if !refund.Amount.Equal(payment.Amount) {
+		t.Errorf("Expectation failed. Expected refund of the whole payment, actual = %v", refund)
+	}
+}
+
 func accountIDRef(id string) *entities.AccountID {
 	accId := entities.AccountID(id)
 	return &accId
 }
 func Test_walletService_GetPayments(t *testing.T) {
+	minAmount, maxAmount := decimal.New(100, 0), decimal.New(10, 0)
 	type args struct {
 		id           entities.AccountID
-		storageData  []entities.Payment
+		query        entities.PaymentsQuery
+		storageQuery entities.PaymentsQuery
+		storageData  entities.PaymentsPage
 		storageError error
 	}
 	tests := []struct {
-		name    string
-		args    args
-		want    []entities.Payment
-		wantErr bool
+		name     string
+		args     args
+		want     entities.PaymentsPage
+		wantErr  bool
+		wantCall bool
 	}{
 		{
 			"calls_storage",
 			args{
-				id: "alice",
-				storageData: []entities.Payment{
+				id:           "alice",
+				query:        entities.PaymentsQuery{Limit: 1},
+				storageQuery: entities.PaymentsQuery{Limit: 1},
+				storageData: entities.PaymentsPage{
+					Payments: []entities.Payment{
+						{
+							Account:   "alice",
+							Amount:    decimal.New(100, 0),
+							Direction: entities.Outgoing,
+							ToAccount: accountIDRef("bob"),
+						},
+					},
+					NextCursor: "cursor",
+				},
+				storageError: nil,
+			},
+			entities.PaymentsPage{
+				Payments: []entities.Payment{
 					{
 						Account:   "alice",
 						Amount:    decimal.New(100, 0),
@@ -211,26 +646,62 @@ func Test_walletService_GetPayments(t *testing.T) {
 						ToAccount: accountIDRef("bob"),
 					},
 				},
-				storageError: nil,
+				NextCursor: "cursor",
 			},
-			[]entities.Payment{
-				{
-					Account:   "alice",
-					Amount:    decimal.New(100, 0),
-					Direction: entities.Outgoing,
-					ToAccount: accountIDRef("bob"),
-				},
+			false,
+			true,
+		},
+		{
+			"default_limit",
+			args{
+				id:           "alice",
+				storageQuery: entities.PaymentsQuery{Limit: 50},
 			},
+			entities.PaymentsPage{Payments: []entities.Payment{}},
+			false,
+			true,
+		},
+		{
+			"max_limit",
+			args{
+				id:           "alice",
+				query:        entities.PaymentsQuery{Limit: 100000},
+				storageQuery: entities.PaymentsQuery{Limit: 500},
+			},
+			entities.PaymentsPage{Payments: []entities.Payment{}},
+			false,
+			true,
+		},
+		{
+			"error_on_negative_limit",
+			args{
+				id:    "alice",
+				query: entities.PaymentsQuery{Limit: -1},
+			},
+			entities.PaymentsPage{},
+			true,
+			false,
+		},
+		{
+			"error_on_wrong_amount_range",
+			args{
+				id:    "alice",
+				query: entities.PaymentsQuery{MinAmount: &minAmount, MaxAmount: &maxAmount},
+			},
+			entities.PaymentsPage{},
+			true,
 			false,
 		},
 		{
 			"error_on_storage_error",
 			args{
 				id:           "alice",
-				storageData:  []entities.Payment{},
+				storageQuery: entities.PaymentsQuery{Limit: 50},
+				storageData:  entities.PaymentsPage{Payments: []entities.Payment{}},
 				storageError: entities.ErrDatabaseConnection,
 			},
-			[]entities.Payment{},
+			entities.PaymentsPage{Payments: []entities.Payment{}},
+			true,
 			true,
 		},
 	}
@@ -242,8 +713,11 @@ func Test_walletService_GetPayments(t *testing.T) {
 			mockStorage := db.NewMockStorage(ctrl)
 			svc := service.NewWalletService(mockStorage)
 
-			mockStorage.EXPECT().PaymentsByAccount(context.TODO(), tt.args.id).Return(tt.args.storageData, tt.args.storageError)
-			got, err := svc.GetPayments(context.TODO(), tt.args.id)
+			if tt.wantCall {
+				mockStorage.EXPECT().PaymentsByAccount(context.TODO(), tt.args.id, tt.args.storageQuery).
+					Return(tt.args.storageData, tt.args.storageError)
+			}
+			got, err := svc.GetPayments(context.TODO(), tt.args.id, tt.args.query)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("walletService.GetPayments() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -377,6 +851,20 @@ func Test_walletService_MakePayment(t *testing.T) {
 			true,
 			false,
 		},
+		{
+			"error_on_amount_precision",
+			args{
+				payment: entities.Payment{
+					ID:        uuid.New(),
+					Account:   "alice",
+					ToAccount: accountIDRef("bob"),
+					Amount:    decimal.New(100001, -3),
+					Direction: entities.Outgoing,
+				},
+			},
+			true,
+			false,
+		},
 		{
 			"storage_called",
 			args{
@@ -406,6 +894,21 @@ func Test_walletService_MakePayment(t *testing.T) {
 			true,
 			true,
 		},
+		{
+			"error_on_insufficient_funds",
+			args{
+				payment: entities.Payment{
+					ID:        uuid.New(),
+					Account:   "alice",
+					ToAccount: accountIDRef("bob"),
+					Amount:    decimal.New(100, 0),
+					Direction: entities.Outgoing,
+				},
+				storageError: entities.ErrInsufficientFunds,
+			},
+			true,
+			true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -414,14 +917,120 @@ func Test_walletService_MakePayment(t *testing.T) {
 
 			mockStorage := db.NewMockStorage(ctrl)
 			svc := service.NewWalletService(mockStorage)
+			expectAccounts(mockStorage,
+				entities.Account{ID: "alice", Balance: decimal.New(100, 0), Currency: "USD"},
+				entities.Account{ID: "bob", Balance: decimal.New(100, 0), Currency: "USD"},
+			)
 
 			if tt.wantCall {
+				var stored *entities.Payment
+				if tt.args.storageError == nil {
+					stored = &tt.args.payment
+				}
 				mockStorage.EXPECT().CreatePayment(context.TODO(), tt.args.payment).
-					Return(tt.args.storageError)
+					Return(stored, tt.args.storageError)
 			}
-			if err := svc.MakePayment(context.TODO(), tt.args.payment); (err != nil) != tt.wantErr {
+			if _, err := svc.MakePayment(context.TODO(), tt.args.payment); (err != nil) != tt.wantErr {
 				t.Errorf("walletService.MakePayment() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+// expectAccounts allows any number of GetAccount calls returning specified accounts
+func expectAccounts(mockStorage *db.MockStorage, accounts ...entities.Account) {
+	mockStorage.EXPECT().GetAccount(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, id entities.AccountID) (*entities.Account, error) {
+			for _, acc := range accounts {
+				if acc.ID == id {
+					return &acc, nil
+				}
+			}
+			return nil, entities.ErrAccountNotFound
+		}).AnyTimes()
+}
+
+func Test_walletService_MakePaymentExchange(t *testing.T) {
+	rates := fx.NewStaticProvider(map[string]decimal.Decimal{
+		"USD/EUR": decimal.New(9, -1),
+		"USD/JPY": decimal.RequireFromString("109.876"),
+		"USD/GBP": decimal.New(8, -1),
+	})
+	currencies := entities.NewCurrencyRegistry(
+		entities.Currency{Code: "USD", MinorUnits: 2, Enabled: true},
+		entities.Currency{Code: "EUR", MinorUnits: 2, Enabled: true},
+		entities.Currency{Code: "JPY", MinorUnits: 0, Enabled: true},
+		entities.Currency{Code: "GBP", MinorUnits: 2, Enabled: false},
+		entities.Currency{Code: "CHF", MinorUnits: 2, Enabled: true},
+	)
+
+	tests := []struct {
+		name                  string
+		to                    entities.AccountID
+		wantDestinationAmount string
+		wantErr               error
+		wantCall              bool
+	}{
+		{"same_currency", "bob", "", nil, true},
+		{"exchange", "eve", "90", nil, true},
+		{"rounded_to_minor_units", "jack", "10988", nil, true},
+		{"error_on_disabled_currency", "gus", "", entities.ErrCurrencyDisabled, false},
+		{"error_on_unknown_rate", "hans", "", entities.ErrExchangeRateNotFound, false},
+		{"error_on_unknown_destination", "mallory", "", entities.ErrPaymentDestinationNotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := db.NewMockStorage(ctrl)
+			svc := service.NewWalletService(mockStorage,
+				service.WithFXRateProvider(rates),
+				service.WithCurrencyRegistry(currencies),
+			)
+			expectAccounts(mockStorage,
+				entities.Account{ID: "alice", Balance: decimal.New(100, 0), Currency: "USD"},
+				entities.Account{ID: "bob", Balance: decimal.New(100, 0), Currency: "USD"},
+				entities.Account{ID: "eve", Balance: decimal.New(100, 0), Currency: "EUR"},
+				entities.Account{ID: "jack", Balance: decimal.New(100, 0), Currency: "JPY"},
+				entities.Account{ID: "gus", Balance: decimal.New(100, 0), Currency: "GBP"},
+				entities.Account{ID: "hans", Balance: decimal.New(100, 0), Currency: "CHF"},
+			)
+
+			if tt.wantCall {
+				mockStorage.EXPECT().CreatePayment(context.TODO(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, payment entities.Payment) (*entities.Payment, error) {
+						return &payment, nil
+					})
+			}
+
+			// exchange sent by client must be ignored
+			payment := entities.Payment{
+				ID:        uuid.New(),
+				Account:   "alice",
+				ToAccount: &tt.to,
+				Amount:    decimal.New(100, 0),
+				Direction: entities.Outgoing,
+				Exchange:  &entities.Exchange{DestinationAmount: decimal.New(1000, 0)},
+			}
+			got, err := svc.MakePayment(context.TODO(), payment)
+			if err != tt.wantErr {
+				t.Fatalf("walletService.MakePayment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if tt.wantDestinationAmount == "" {
+				if got.Exchange != nil {
+					t.Errorf("walletService.MakePayment() exchange = %v, want nil", got.Exchange)
+				}
+				return
+			}
+			want := decimal.RequireFromString(tt.wantDestinationAmount)
+			if got.Exchange == nil || !got.Exchange.DestinationAmount.Equal(want) || got.Exchange.SourceCurrency != "USD" {
+				t.Errorf("walletService.MakePayment() exchange = %v, want destination amount %v", got.Exchange, want)
+			}
+		})
+	}
+}
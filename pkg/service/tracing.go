@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/shirolimit/wallet-service/pkg/entities"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracingMiddleware struct {
+	tracer trace.Tracer
+	next   WalletService
+}
+
+// TracingMiddleware is a function that takes a tracer and produces
+// a service Middleware which starts a span for each method call
+func TracingMiddleware(tracer trace.Tracer) Middleware {
+	return func(next WalletService) WalletService {
+		return &tracingMiddleware{
+			tracer: tracer,
+			next:   next,
+		}
+	}
+}
+
+// CreateAccount is a middleware function that traces the call
+// Named return parameter is used for defer
+func (tmw tracingMiddleware) CreateAccount(ctx context.Context, acc entities.Account) (created entities.Account, err error) {
+	ctx, span := tmw.start(ctx, "CreateAccount",
+		attribute.String("wallet.account_id", string(acc.ID)),
+		attribute.String("wallet.currency", acc.Currency),
+	)
+	defer finish(span, &err)
+	return tmw.next.CreateAccount(ctx, acc)
+}
+
+// ListAccounts is a middleware function that traces the call
+// Named return parameters are used for defer
+func (tmw tracingMiddleware) ListAccounts(ctx context.Context, query entities.AccountsQuery) (page entities.AccountsPage, err error) {
+	ctx, span := tmw.start(ctx, "ListAccounts", attribute.Int("wallet.limit", query.Limit))
+	defer finish(span, &err)
+	return tmw.next.ListAccounts(ctx, query)
+}
+
+// GetAccount is a middleware function that traces the call
+// Named return parameters are used for defer
+func (tmw tracingMiddleware) GetAccount(ctx context.Context, id entities.AccountID) (acc entities.Account, err error) {
+	ctx, span := tmw.start(ctx, "GetAccount", attribute.String("wallet.account_id", string(id)))
+	defer finish(span, &err)
+	return tmw.next.GetAccount(ctx, id)
+}
+
+// UpdateAccount is a middleware function that traces the call
+// Named return parameters are used for defer
+func (tmw tracingMiddleware) UpdateAccount(ctx context.Context, id entities.AccountID,
+	update entities.AccountUpdate) (acc entities.Account, err error) {
+
+	attrs := []attribute.KeyValue{attribute.String("wallet.account_id", string(id))}
+	if update.Status != nil {
+		attrs = append(attrs, attribute.String("wallet.account_status", update.Status.String()))
+	}
+	ctx, span := tmw.start(ctx, "UpdateAccount", attrs...)
+	defer finish(span, &err)
+	return tmw.next.UpdateAccount(ctx, id, update)
+}
+
+// GetPayments is a middleware function that traces the call
+// Named return parameters are used for defer
+func (tmw tracingMiddleware) GetPayments(ctx context.Context, id entities.AccountID,
+	query entities.PaymentsQuery) (page entities.PaymentsPage, err error) {
+
+	ctx, span := tmw.start(ctx, "GetPayments",
+		attribute.String("wallet.account_id", string(id)),
+		attribute.Int("wallet.limit", query.Limit),
+	)
+	defer finish(span, &err)
+	return tmw.next.GetPayments(ctx, id, query)
+}
+
+// GetPayment is a middleware function that traces the call
+// Named return parameters are used for defer
+func (tmw tracingMiddleware) GetPayment(ctx context.Context, id uuid.UUID) (record entities.PaymentRecord, err error) {
+	ctx, span := tmw.start(ctx, "GetPayment", attribute.String("wallet.payment_id", id.String()))
+	defer finish(span, &err)
+	return tmw.next.GetPayment(ctx, id)
+}
+
+// MakePayment is a middleware function that traces the call
+// Named return parameters are used for defer
+func (tmw tracingMiddleware) MakePayment(ctx context.Context, payment entities.Payment) (stored entities.Payment, err error) {
+	attrs := []attribute.KeyValue{
+		attribute.String("wallet.payment_id", payment.ID.String()),
+		attribute.String("wallet.account_id", string(payment.Account)),
+		attribute.String("wallet.amount", payment.Amount.String()),
+	}
+	if payment.ToAccount != nil {
+		attrs = append(attrs, attribute.String("wallet.to_account", string(*payment.ToAccount)))
+	}
+
+	ctx, span := tmw.start(ctx, "MakePayment", attrs...)
+	defer finish(span, &err)
+	return tmw.next.MakePayment(ctx, payment)
+}
+
+// Deposit is a middleware function that traces the call
+// Named return parameters are used for defer
+func (tmw tracingMiddleware) Deposit(ctx context.Context, payment entities.Payment) (stored entities.Payment, err error) {
+	ctx, span := tmw.start(ctx, "Deposit", externalPaymentAttributes(payment)...)
+	defer finish(span, &err)
+	return tmw.next.Deposit(ctx, payment)
+}
+
+// Withdraw is a middleware function that traces the call
+// Named return parameters are used for defer
+func (tmw tracingMiddleware) Withdraw(ctx context.Context, payment entities.Payment) (stored entities.Payment, err error) {
+	ctx, span := tmw.start(ctx, "Withdraw", externalPaymentAttributes(payment)...)
+	defer finish(span, &err)
+	return tmw.next.Withdraw(ctx, payment)
+}
+
+// externalPaymentAttributes are span attributes of deposits and withdrawals
+func externalPaymentAttributes(payment entities.Payment) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("wallet.payment_id", payment.ID.String()),
+		attribute.String("wallet.account_id", string(payment.Account)),
+		attribute.String("wallet.amount", payment.Amount.String()),
+	}
+}
+
+// CreateHold is a middleware function that traces the call
+// Named return parameters are used for defer
+func (tmw tracingMiddleware) CreateHold(ctx context.Context, hold entities.Hold) (stored entities.Hold, err error) {
+	ctx, span := tmw.start(ctx, "CreateHold",
+		attribute.String("wallet.hold_id", hold.ID.String()),
+		attribute.String("wallet.account_id", string(hold.Account)),
+		attribute.String("wallet.to_account_id", string(hold.ToAccount)),
+		attribute.String("wallet.amount", hold.Amount.String()),
+	)
+	defer finish(span, &err)
+	return tmw.next.CreateHold(ctx, hold)
+}
+
+// GetHold is a middleware function that traces the call
+// Named return parameters are used for defer
+func (tmw tracingMiddleware) GetHold(ctx context.Context, id uuid.UUID) (hold entities.Hold, err error) {
+	ctx, span := tmw.start(ctx, "GetHold", attribute.String("wallet.hold_id", id.String()))
+	defer finish(span, &err)
+	return tmw.next.GetHold(ctx, id)
+}
+
+// CaptureHold is a middleware function that traces the call
+// Named return parameters are used for defer
+func (tmw tracingMiddleware) CaptureHold(ctx context.Context, id uuid.UUID,
+	amount *decimal.Decimal) (payment entities.Payment, err error) {
+
+	attrs := []attribute.KeyValue{attribute.String("wallet.hold_id", id.String())}
+	if amount != nil {
+		attrs = append(attrs, attribute.String("wallet.amount", amount.String()))
+	}
+	ctx, span := tmw.start(ctx, "CaptureHold", attrs...)
+	defer finish(span, &err)
+	return tmw.next.CaptureHold(ctx, id, amount)
+}
+
+// ReleaseHold is a middleware function that traces the call
+// Named return parameters are used for defer
+func (tmw tracingMiddleware) ReleaseHold(ctx context.Context, id uuid.UUID) (hold entities.Hold, err error) {
+	ctx, span := tmw.start(ctx, "ReleaseHold", attribute.String("wallet.hold_id", id.String()))
+	defer finish(span, &err)
+	return tmw.next.ReleaseHold(ctx, id)
+}
+
+// RefundPayment is a middleware function that traces the call
+// Named return parameters are used for defer
+func (tmw tracingMiddleware) RefundPayment(ctx context.Context, refund entities.Payment) (stored entities.Payment, err error) {
+	attrs := []attribute.KeyValue{
+		attribute.String("wallet.payment_id", refund.ID.String()),
+		attribute.String("wallet.account_id", string(refund.Account)),
+		attribute.String("wallet.amount", refund.Amount.String()),
+	}
+	if refund.RefundOf != nil {
+		attrs = append(attrs, attribute.String("wallet.refund_of", refund.RefundOf.String()))
+	}
+
+	ctx, span := tmw.start(ctx, "RefundPayment", attrs...)
+	defer finish(span, &err)
+	return tmw.next.RefundPayment(ctx, refund)
+}
+
+func (tmw tracingMiddleware) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tmw.tracer.Start(ctx, "WalletService."+method, trace.WithAttributes(attrs...))
+}
+
+// finish records error of method call in span and ends it.
+// ErrPaymentDuplicate is a successful replay and is only marked with an attribute
+func finish(span trace.Span, err *error) {
+	switch *err {
+	case nil:
+	case entities.ErrPaymentDuplicate:
+		span.SetAttributes(attribute.Bool("wallet.replayed", true))
+	default:
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
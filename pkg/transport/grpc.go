@@ -0,0 +1,866 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/shirolimit/wallet-service/pkg/endpoint"
+	"github.com/shirolimit/wallet-service/pkg/entities"
+	"github.com/shirolimit/wallet-service/pkg/pb"
+)
+
+type grpcServer struct {
+	pb.UnimplementedWalletServer
+
+	createAccount grpctransport.Handler
+	listAccounts  grpctransport.Handler
+	getAccount    grpctransport.Handler
+	updateAccount grpctransport.Handler
+	getPayments   grpctransport.Handler
+	getPayment    grpctransport.Handler
+	makePayment   grpctransport.Handler
+	deposit       grpctransport.Handler
+	withdraw      grpctransport.Handler
+	createHold    grpctransport.Handler
+	getHold       grpctransport.Handler
+	captureHold   grpctransport.Handler
+	releaseHold   grpctransport.Handler
+	refundPayment grpctransport.Handler
+}
+
+// NewGRPCServer creates gRPC server that serves the same endpoints as HTTP handler
+func NewGRPCServer(endpoints endpoint.Set, options ...grpctransport.ServerOption) pb.WalletServer {
+	return &grpcServer{
+		createAccount: grpctransport.NewServer(
+			endpoints.CreateAccountEndpoint,
+			decodeGRPCCreateAccountRequest,
+			encodeGRPCCreateAccountResponse,
+			options...,
+		),
+		listAccounts: grpctransport.NewServer(
+			endpoints.ListAccountsEndpoint,
+			decodeGRPCListAccountsRequest,
+			encodeGRPCListAccountsResponse,
+			options...,
+		),
+		getAccount: grpctransport.NewServer(
+			endpoints.GetAccountEndpoint,
+			decodeGRPCGetAccountRequest,
+			encodeGRPCGetAccountResponse,
+			options...,
+		),
+		updateAccount: grpctransport.NewServer(
+			endpoints.UpdateAccountEndpoint,
+			decodeGRPCUpdateAccountRequest,
+			encodeGRPCUpdateAccountResponse,
+			options...,
+		),
+		getPayments: grpctransport.NewServer(
+			endpoints.GetPaymentsEndpoint,
+			decodeGRPCGetPaymentsRequest,
+			encodeGRPCGetPaymentsResponse,
+			options...,
+		),
+		getPayment: grpctransport.NewServer(
+			endpoints.GetPaymentEndpoint,
+			decodeGRPCGetPaymentRequest,
+			encodeGRPCGetPaymentResponse,
+			options...,
+		),
+		makePayment: grpctransport.NewServer(
+			endpoints.MakePaymentEndpoint,
+			decodeGRPCMakePaymentRequest,
+			encodeGRPCMakePaymentResponse,
+			options...,
+		),
+		deposit: grpctransport.NewServer(
+			endpoints.DepositEndpoint,
+			decodeGRPCDepositRequest,
+			encodeGRPCDepositResponse,
+			options...,
+		),
+		withdraw: grpctransport.NewServer(
+			endpoints.WithdrawEndpoint,
+			decodeGRPCWithdrawRequest,
+			encodeGRPCWithdrawResponse,
+			options...,
+		),
+		createHold: grpctransport.NewServer(
+			endpoints.CreateHoldEndpoint,
+			decodeGRPCCreateHoldRequest,
+			encodeGRPCCreateHoldResponse,
+			options...,
+		),
+		getHold: grpctransport.NewServer(
+			endpoints.GetHoldEndpoint,
+			decodeGRPCGetHoldRequest,
+			encodeGRPCGetHoldResponse,
+			options...,
+		),
+		captureHold: grpctransport.NewServer(
+			endpoints.CaptureHoldEndpoint,
+			decodeGRPCCaptureHoldRequest,
+			encodeGRPCCaptureHoldResponse,
+			options...,
+		),
+		releaseHold: grpctransport.NewServer(
+			endpoints.ReleaseHoldEndpoint,
+			decodeGRPCReleaseHoldRequest,
+			encodeGRPCReleaseHoldResponse,
+			options...,
+		),
+		refundPayment: grpctransport.NewServer(
+			endpoints.RefundPaymentEndpoint,
+			decodeGRPCRefundPaymentRequest,
+			encodeGRPCRefundPaymentResponse,
+			options...,
+		),
+	}
+}
+
+func (s *grpcServer) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.CreateAccountReply, error) {
+	_, resp, err := s.createAccount.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.(*pb.CreateAccountReply), nil
+}
+
+func (s *grpcServer) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsReply, error) {
+	_, resp, err := s.listAccounts.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.(*pb.ListAccountsReply), nil
+}
+
+func (s *grpcServer) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.GetAccountReply, error) {
+	_, resp, err := s.getAccount.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.(*pb.GetAccountReply), nil
+}
+
+func (s *grpcServer) UpdateAccount(ctx context.Context, req *pb.UpdateAccountRequest) (*pb.UpdateAccountReply, error) {
+	_, resp, err := s.updateAccount.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.(*pb.UpdateAccountReply), nil
+}
+
+func (s *grpcServer) GetPayments(ctx context.Context, req *pb.GetPaymentsRequest) (*pb.GetPaymentsReply, error) {
+	_, resp, err := s.getPayments.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.(*pb.GetPaymentsReply), nil
+}
+
+func (s *grpcServer) GetPayment(ctx context.Context, req *pb.GetPaymentRequest) (*pb.GetPaymentReply, error) {
+	_, resp, err := s.getPayment.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.(*pb.GetPaymentReply), nil
+}
+
+func (s *grpcServer) MakePayment(ctx context.Context, req *pb.MakePaymentRequest) (*pb.MakePaymentReply, error) {
+	_, resp, err := s.makePayment.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.(*pb.MakePaymentReply), nil
+}
+
+func (s *grpcServer) Deposit(ctx context.Context, req *pb.DepositRequest) (*pb.DepositReply, error) {
+	_, resp, err := s.deposit.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.(*pb.DepositReply), nil
+}
+
+func (s *grpcServer) Withdraw(ctx context.Context, req *pb.WithdrawRequest) (*pb.WithdrawReply, error) {
+	_, resp, err := s.withdraw.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.(*pb.WithdrawReply), nil
+}
+
+func (s *grpcServer) CreateHold(ctx context.Context, req *pb.CreateHoldRequest) (*pb.CreateHoldReply, error) {
+	_, resp, err := s.createHold.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.(*pb.CreateHoldReply), nil
+}
+
+func (s *grpcServer) GetHold(ctx context.Context, req *pb.GetHoldRequest) (*pb.GetHoldReply, error) {
+	_, resp, err := s.getHold.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.(*pb.GetHoldReply), nil
+}
+
+func (s *grpcServer) CaptureHold(ctx context.Context, req *pb.CaptureHoldRequest) (*pb.CaptureHoldReply, error) {
+	_, resp, err := s.captureHold.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.(*pb.CaptureHoldReply), nil
+}
+
+func (s *grpcServer) ReleaseHold(ctx context.Context, req *pb.ReleaseHoldRequest) (*pb.ReleaseHoldReply, error) {
+	_, resp, err := s.releaseHold.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.(*pb.ReleaseHoldReply), nil
+}
+
+func (s *grpcServer) RefundPayment(ctx context.Context, req *pb.RefundPaymentRequest) (*pb.RefundPaymentReply, error) {
+	_, resp, err := s.refundPayment.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.(*pb.RefundPaymentReply), nil
+}
+
+func decodeGRPCCreateAccountRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.CreateAccountRequest)
+	if req.Account == nil {
+		return nil, status.Error(codes.InvalidArgument, "account is required")
+	}
+
+	balance, err := decodeGRPCDecimal(req.Account.Balance)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "malformed balance")
+	}
+
+	return endpoint.CreateAccountRequest{
+		Account: entities.Account{
+			ID:       entities.AccountID(req.Account.Id),
+			Currency: req.Account.Currency,
+			Balance:  balance,
+			Owner:    req.Account.Owner,
+		},
+	}, nil
+}
+
+func encodeGRPCCreateAccountResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	resp := response.(endpoint.CreateAccountResponse)
+	if err := resp.Failed(); err != nil {
+		return nil, err
+	}
+	return &pb.CreateAccountReply{Account: encodeGRPCAccount(resp.Account)}, nil
+}
+
+func decodeGRPCListAccountsRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.ListAccountsRequest)
+
+	// gRPC clients always get full accounts, so the list is paginated like full view of HTTP API
+	query := entities.AccountsQuery{
+		Limit:    int(req.Limit),
+		Cursor:   req.Cursor,
+		IDPrefix: req.Prefix,
+		Currency: req.Currency,
+		Sort:     entities.AccountsSort(req.Sort),
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultAccountsPageLimit
+	}
+
+	var err error
+	if query.MinBalance, err = decodeGRPCOptionalDecimal(req.MinBalance); err != nil {
+		return nil, entities.ErrInvalidAccountsQuery
+	}
+	if query.MaxBalance, err = decodeGRPCOptionalDecimal(req.MaxBalance); err != nil {
+		return nil, entities.ErrInvalidAccountsQuery
+	}
+	return endpoint.ListAccountsRequest{Query: query, Full: true}, nil
+}
+
+func encodeGRPCListAccountsResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	resp := response.(endpoint.ListAccountsResponse)
+	if err := resp.Failed(); err != nil {
+		return nil, err
+	}
+
+	reply := &pb.ListAccountsReply{
+		Accounts:   make([]*pb.Account, 0, len(resp.Page.Accounts)),
+		NextCursor: resp.Page.NextCursor,
+	}
+	for _, acc := range resp.Page.Accounts {
+		reply.Accounts = append(reply.Accounts, encodeGRPCAccount(acc))
+	}
+	return reply, nil
+}
+
+func decodeGRPCGetAccountRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.GetAccountRequest)
+	return endpoint.GetAccountRequest{ID: entities.AccountID(req.Id)}, nil
+}
+
+func encodeGRPCGetAccountResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	resp := response.(endpoint.GetAccountResponse)
+	if err := resp.Failed(); err != nil {
+		return nil, err
+	}
+	return &pb.GetAccountReply{Account: encodeGRPCAccount(resp.Account)}, nil
+}
+
+func decodeGRPCUpdateAccountRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.UpdateAccountRequest)
+	update := entities.AccountUpdate{}
+
+	var accountStatus entities.AccountStatus
+	switch req.Status {
+	case pb.AccountStatus_ACCOUNT_STATUS_UNSPECIFIED:
+	case pb.AccountStatus_ACCOUNT_STATUS_ACTIVE:
+		accountStatus = entities.Active
+		update.Status = &accountStatus
+	case pb.AccountStatus_ACCOUNT_STATUS_FROZEN:
+		accountStatus = entities.Frozen
+		update.Status = &accountStatus
+	case pb.AccountStatus_ACCOUNT_STATUS_CLOSED:
+		accountStatus = entities.Closed
+		update.Status = &accountStatus
+	default:
+		return nil, entities.ErrInvalidAccountStatus
+	}
+
+	return endpoint.UpdateAccountRequest{ID: entities.AccountID(req.Id), Update: update}, nil
+}
+
+func encodeGRPCUpdateAccountResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	resp := response.(endpoint.UpdateAccountResponse)
+	if err := resp.Failed(); err != nil {
+		return nil, err
+	}
+	return &pb.UpdateAccountReply{Account: encodeGRPCAccount(resp.Account)}, nil
+}
+
+func decodeGRPCGetPaymentsRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.GetPaymentsRequest)
+	query := entities.PaymentsQuery{
+		Limit:  int(req.Limit),
+		Cursor: req.Cursor,
+	}
+
+	switch req.Direction {
+	case pb.PaymentDirection_PAYMENT_DIRECTION_UNSPECIFIED:
+	case pb.PaymentDirection_PAYMENT_DIRECTION_INCOMING:
+		direction := entities.Incoming
+		query.Direction = &direction
+	case pb.PaymentDirection_PAYMENT_DIRECTION_OUTGOING:
+		direction := entities.Outgoing
+		query.Direction = &direction
+	default:
+		return nil, entities.ErrInvalidPaymentsQuery
+	}
+
+	if req.Counterparty != "" {
+		counterparty := entities.AccountID(req.Counterparty)
+		query.Counterparty = &counterparty
+	}
+
+	var err error
+	if query.MinAmount, err = decodeGRPCOptionalDecimal(req.MinAmount); err != nil {
+		return nil, entities.ErrInvalidPaymentsQuery
+	}
+	if query.MaxAmount, err = decodeGRPCOptionalDecimal(req.MaxAmount); err != nil {
+		return nil, entities.ErrInvalidPaymentsQuery
+	}
+	if query.From, err = decodeGRPCOptionalTime(req.From); err != nil {
+		return nil, entities.ErrInvalidPaymentsQuery
+	}
+	if query.To, err = decodeGRPCOptionalTime(req.To); err != nil {
+		return nil, entities.ErrInvalidPaymentsQuery
+	}
+
+	return endpoint.GetPaymentsRequest{
+		AccountID: entities.AccountID(req.AccountId),
+		Query:     query,
+	}, nil
+}
+
+func encodeGRPCGetPaymentsResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	resp := response.(endpoint.GetPaymentsResponse)
+	if err := resp.Failed(); err != nil {
+		return nil, err
+	}
+
+	reply := &pb.GetPaymentsReply{
+		Payments:   make([]*pb.Payment, 0, len(resp.Page.Payments)),
+		NextCursor: resp.Page.NextCursor,
+	}
+	for _, payment := range resp.Page.Payments {
+		reply.Payments = append(reply.Payments, encodeGRPCPayment(payment))
+	}
+	return reply, nil
+}
+
+func decodeGRPCGetPaymentRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.GetPaymentRequest)
+	id, err := decodeGRPCPaymentID(req.Id)
+	return endpoint.GetPaymentRequest{ID: id}, err
+}
+
+func encodeGRPCGetPaymentResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	resp := response.(endpoint.GetPaymentResponse)
+	if err := resp.Failed(); err != nil {
+		return nil, err
+	}
+	return &pb.GetPaymentReply{Payment: encodeGRPCPaymentRecord(resp.Payment)}, nil
+}
+
+func decodeGRPCMakePaymentRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.MakePaymentRequest)
+	id, amount, err := decodeGRPCPaymentIDAndAmount(req.Id, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	toAccount := entities.AccountID(req.ToAccount)
+	return endpoint.MakePaymentRequest{
+		Payment: entities.Payment{
+			ID:        id,
+			Account:   entities.AccountID(req.Account),
+			Amount:    amount,
+			Direction: entities.Outgoing,
+			ToAccount: &toAccount,
+		},
+	}, nil
+}
+
+func encodeGRPCMakePaymentResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	resp := response.(endpoint.MakePaymentResponse)
+	if err := resp.Failed(); err != nil {
+		return nil, err
+	}
+	return &pb.MakePaymentReply{
+		Payment:  encodeGRPCPayment(resp.Payment),
+		Replayed: resp.Replayed,
+	}, nil
+}
+
+func decodeGRPCDepositRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.DepositRequest)
+	id, amount, err := decodeGRPCPaymentIDAndAmount(req.Id, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	return endpoint.DepositRequest{
+		Payment: entities.Payment{ID: id, Account: entities.AccountID(req.Account), Amount: amount},
+	}, nil
+}
+
+func encodeGRPCDepositResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	resp := response.(endpoint.MakePaymentResponse)
+	if err := resp.Failed(); err != nil {
+		return nil, err
+	}
+	return &pb.DepositReply{
+		Payment:  encodeGRPCPayment(resp.Payment),
+		Replayed: resp.Replayed,
+	}, nil
+}
+
+func decodeGRPCWithdrawRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.WithdrawRequest)
+	id, amount, err := decodeGRPCPaymentIDAndAmount(req.Id, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	return endpoint.WithdrawRequest{
+		Payment: entities.Payment{ID: id, Account: entities.AccountID(req.Account), Amount: amount},
+	}, nil
+}
+
+func encodeGRPCWithdrawResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	resp := response.(endpoint.MakePaymentResponse)
+	if err := resp.Failed(); err != nil {
+		return nil, err
+	}
+	return &pb.WithdrawReply{
+		Payment:  encodeGRPCPayment(resp.Payment),
+		Replayed: resp.Replayed,
+	}, nil
+}
+
+// decodeGRPCPaymentIDAndAmount parses idempotency key and amount of payment, empty ID is rejected by service
+func decodeGRPCPaymentIDAndAmount(rawID, rawAmount string) (uuid.UUID, decimal.Decimal, error) {
+	var id uuid.UUID
+	if rawID != "" {
+		var err error
+		if id, err = uuid.Parse(rawID); err != nil {
+			return id, decimal.Decimal{}, status.Error(codes.InvalidArgument, "malformed payment id")
+		}
+	}
+
+	amount, err := decodeGRPCDecimal(rawAmount)
+	if err != nil {
+		return id, decimal.Decimal{}, entities.ErrWrongPaymentAmount
+	}
+	return id, amount, nil
+}
+
+func decodeGRPCCreateHoldRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.CreateHoldRequest)
+	id, amount, err := decodeGRPCPaymentIDAndAmount(req.Id, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	hold := entities.Hold{
+		ID:        id,
+		Account:   entities.AccountID(req.Account),
+		ToAccount: entities.AccountID(req.ToAccount),
+		Amount:    amount,
+	}
+	if req.ExpiresAt != nil {
+		if err = req.ExpiresAt.CheckValid(); err != nil {
+			return nil, entities.ErrInvalidHoldExpiry
+		}
+		hold.ExpiresAt = req.ExpiresAt.AsTime()
+	}
+	return endpoint.CreateHoldRequest{Hold: hold}, nil
+}
+
+func encodeGRPCCreateHoldResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	resp := response.(endpoint.HoldResponse)
+	if err := resp.Failed(); err != nil {
+		return nil, err
+	}
+	return &pb.CreateHoldReply{Hold: encodeGRPCHold(resp.Hold)}, nil
+}
+
+func decodeGRPCGetHoldRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.GetHoldRequest)
+	id, err := decodeGRPCHoldID(req.Id)
+	return endpoint.GetHoldRequest{ID: id}, err
+}
+
+func encodeGRPCGetHoldResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	resp := response.(endpoint.HoldResponse)
+	if err := resp.Failed(); err != nil {
+		return nil, err
+	}
+	return &pb.GetHoldReply{Hold: encodeGRPCHold(resp.Hold)}, nil
+}
+
+func decodeGRPCCaptureHoldRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.CaptureHoldRequest)
+	id, err := decodeGRPCHoldID(req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := decodeGRPCOptionalDecimal(req.Amount)
+	if err != nil {
+		return nil, entities.ErrWrongCaptureAmount
+	}
+	return endpoint.CaptureHoldRequest{ID: id, Amount: amount}, nil
+}
+
+func encodeGRPCCaptureHoldResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	resp := response.(endpoint.MakePaymentResponse)
+	if err := resp.Failed(); err != nil {
+		return nil, err
+	}
+	return &pb.CaptureHoldReply{
+		Payment:  encodeGRPCPayment(resp.Payment),
+		Replayed: resp.Replayed,
+	}, nil
+}
+
+func decodeGRPCReleaseHoldRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.ReleaseHoldRequest)
+	id, err := decodeGRPCHoldID(req.Id)
+	return endpoint.ReleaseHoldRequest{ID: id}, err
+}
+
+func encodeGRPCReleaseHoldResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	resp := response.(endpoint.HoldResponse)
+	if err := resp.Failed(); err != nil {
+		return nil, err
+	}
+	return &pb.ReleaseHoldReply{Hold: encodeGRPCHold(resp.Hold)}, nil
+}
+
+func decodeGRPCRefundPaymentRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.RefundPaymentRequest)
+	id, amount, err := decodeGRPCPaymentIDAndAmount(req.Id, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	refundOf, err := decodeGRPCPaymentID(req.PaymentId)
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoint.RefundPaymentRequest{
+		Refund: entities.Payment{
+			ID:        id,
+			Account:   entities.AccountID(req.Account),
+			Amount:    amount,
+			Direction: entities.Outgoing,
+			RefundOf:  &refundOf,
+		},
+	}, nil
+}
+
+func encodeGRPCRefundPaymentResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	resp := response.(endpoint.MakePaymentResponse)
+	if err := resp.Failed(); err != nil {
+		return nil, err
+	}
+	return &pb.RefundPaymentReply{
+		Payment:  encodeGRPCPayment(resp.Payment),
+		Replayed: resp.Replayed,
+	}, nil
+}
+
+// decodeGRPCPaymentID parses ID of existing payment, malformed IDs can't belong to any payment
+func decodeGRPCPaymentID(rawID string) (uuid.UUID, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return uuid.UUID{}, entities.ErrPaymentNotFound
+	}
+	return id, nil
+}
+
+// decodeGRPCHoldID parses ID of existing hold, malformed IDs can't belong to any hold
+func decodeGRPCHoldID(rawID string) (uuid.UUID, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return uuid.UUID{}, entities.ErrHoldNotFound
+	}
+	return id, nil
+}
+
+func encodeGRPCHold(hold entities.Hold) *pb.Hold {
+	result := &pb.Hold{
+		Id:        hold.ID.String(),
+		Account:   string(hold.Account),
+		ToAccount: string(hold.ToAccount),
+		Amount:    hold.Amount.String(),
+		Currency:  hold.Currency,
+		CreatedAt: timestamppb.New(hold.CreatedAt),
+		ExpiresAt: timestamppb.New(hold.ExpiresAt),
+	}
+	if hold.CapturedAmount != nil {
+		result.CapturedAmount = hold.CapturedAmount.String()
+	}
+	switch hold.Status {
+	case entities.HoldActive:
+		result.Status = pb.HoldStatus_HOLD_STATUS_ACTIVE
+	case entities.HoldCaptured:
+		result.Status = pb.HoldStatus_HOLD_STATUS_CAPTURED
+	case entities.HoldReleased:
+		result.Status = pb.HoldStatus_HOLD_STATUS_RELEASED
+	case entities.HoldExpired:
+		result.Status = pb.HoldStatus_HOLD_STATUS_EXPIRED
+	}
+	return result
+}
+
+func encodeGRPCAccount(acc entities.Account) *pb.Account {
+	result := &pb.Account{
+		Id:               string(acc.ID),
+		Currency:         acc.Currency,
+		Balance:          acc.Balance.String(),
+		Owner:            acc.Owner,
+		AvailableBalance: acc.AvailableBalance.String(),
+	}
+	switch acc.Status {
+	case entities.Active:
+		result.Status = pb.AccountStatus_ACCOUNT_STATUS_ACTIVE
+	case entities.Frozen:
+		result.Status = pb.AccountStatus_ACCOUNT_STATUS_FROZEN
+	case entities.Closed:
+		result.Status = pb.AccountStatus_ACCOUNT_STATUS_CLOSED
+	}
+	return result
+}
+
+func encodeGRPCPayment(payment entities.Payment) *pb.Payment {
+	result := &pb.Payment{
+		Id:        payment.ID.String(),
+		Account:   string(payment.Account),
+		Amount:    payment.Amount.String(),
+		Currency:  payment.Currency,
+		CreatedAt: timestamppb.New(payment.CreatedAt),
+	}
+
+	if payment.Direction == entities.Outgoing {
+		result.Direction = pb.PaymentDirection_PAYMENT_DIRECTION_OUTGOING
+	} else {
+		result.Direction = pb.PaymentDirection_PAYMENT_DIRECTION_INCOMING
+	}
+
+	if payment.ToAccount != nil {
+		result.ToAccount = string(*payment.ToAccount)
+	}
+	if payment.FromAccount != nil {
+		result.FromAccount = string(*payment.FromAccount)
+	}
+
+	switch payment.Status {
+	case entities.Completed:
+		result.Status = pb.PaymentStatus_PAYMENT_STATUS_COMPLETED
+	}
+
+	result.Type = encodeGRPCPaymentType(payment.Type)
+	if payment.RefundOf != nil {
+		result.RefundOf = payment.RefundOf.String()
+	}
+	result.Exchange = encodeGRPCExchange(payment.Exchange)
+	return result
+}
+
+func encodeGRPCPaymentRecord(record entities.PaymentRecord) *pb.PaymentRecord {
+	result := &pb.PaymentRecord{
+		Id:          record.ID.String(),
+		Source:      string(record.Source),
+		Destination: string(record.Destination),
+		Amount:      record.Amount.String(),
+		Currency:    record.Currency,
+		CreatedAt:   timestamppb.New(record.CreatedAt),
+		Type:        encodeGRPCPaymentType(record.Type),
+		Exchange:    encodeGRPCExchange(record.Exchange),
+	}
+
+	switch record.Status {
+	case entities.Completed:
+		result.Status = pb.PaymentStatus_PAYMENT_STATUS_COMPLETED
+	}
+
+	if record.RefundOf != nil {
+		result.RefundOf = record.RefundOf.String()
+	}
+	return result
+}
+
+func encodeGRPCPaymentType(paymentType entities.PaymentType) pb.PaymentType {
+	switch paymentType {
+	case entities.RegularPayment:
+		return pb.PaymentType_PAYMENT_TYPE_PAYMENT
+	case entities.Refund:
+		return pb.PaymentType_PAYMENT_TYPE_REFUND
+	default:
+		return pb.PaymentType_PAYMENT_TYPE_UNSPECIFIED
+	}
+}
+
+// encodeGRPCExchange returns nil for payments without currency exchange
+func encodeGRPCExchange(exchange *entities.Exchange) *pb.Exchange {
+	if exchange == nil {
+		return nil
+	}
+	return &pb.Exchange{
+		SourceCurrency:      exchange.SourceCurrency,
+		SourceAmount:        exchange.SourceAmount.String(),
+		DestinationCurrency: exchange.DestinationCurrency,
+		DestinationAmount:   exchange.DestinationAmount.String(),
+		Rate:                exchange.Rate.String(),
+		RateTimestamp:       timestamppb.New(exchange.RateTimestamp),
+	}
+}
+
+// decodeGRPCDecimal parses decimal string, empty string means zero
+func decodeGRPCDecimal(value string) (decimal.Decimal, error) {
+	if value == "" {
+		return decimal.Decimal{}, nil
+	}
+	return decimal.NewFromString(value)
+}
+
+// decodeGRPCOptionalDecimal parses decimal string, empty string means that value is not set
+func decodeGRPCOptionalDecimal(value string) (*decimal.Decimal, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func decodeGRPCOptionalTime(value *timestamppb.Timestamp) (*time.Time, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if err := value.CheckValid(); err != nil {
+		return nil, err
+	}
+
+	t := value.AsTime()
+	return &t, nil
+}
+
+// grpcError converts error into gRPC status error. Codes mirror HTTP status codes
+// returned by statusCodeFromError, so both transports report errors the same way
+func grpcError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(codeFromError(err), err.Error())
+}
+
+// codeFromError translates error into gRPC status code
+func codeFromError(err error) codes.Code {
+	// conflicts with account, hold or payment state are not about existence of something
+	switch err {
+	case entities.ErrAccountFrozen, entities.ErrAccountClosed, entities.ErrAccountNotEmpty, entities.ErrHoldNotActive,
+		entities.ErrRefundExceedsPayment:
+		return codes.FailedPrecondition
+	}
+
+	switch statusCodeFromError(err) {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+
+	case http.StatusPaymentRequired:
+		return codes.FailedPrecondition
+
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+
+	case http.StatusNotFound:
+		return codes.NotFound
+
+	case http.StatusConflict:
+		return codes.AlreadyExists
+
+	case http.StatusUnprocessableEntity:
+		return codes.FailedPrecondition
+
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,127 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/shirolimit/wallet-service/pkg/db"
+	"github.com/shirolimit/wallet-service/pkg/endpoint"
+	"github.com/shirolimit/wallet-service/pkg/entities"
+	"github.com/shirolimit/wallet-service/pkg/pb"
+	"github.com/shirolimit/wallet-service/pkg/service"
+)
+
+func Test_codeFromError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{"account_frozen", entities.ErrAccountFrozen, codes.FailedPrecondition},
+		{"account_closed", entities.ErrAccountClosed, codes.FailedPrecondition},
+		{"account_not_empty", entities.ErrAccountNotEmpty, codes.FailedPrecondition},
+		{"hold_not_active", entities.ErrHoldNotActive, codes.FailedPrecondition},
+		{"refund_exceeds_payment", entities.ErrRefundExceedsPayment, codes.FailedPrecondition},
+		{"different_currencies", entities.ErrDifferentCurrencies, codes.FailedPrecondition},
+		{"insufficient_funds", entities.ErrInsufficientFunds, codes.FailedPrecondition},
+		{"account_exists", entities.ErrAccountAlreadyExists, codes.AlreadyExists},
+		{"payment_done", entities.ErrPaymentAlreadyDone, codes.AlreadyExists},
+		{"not_found", entities.ErrAccountNotFound, codes.NotFound},
+		{"wrong_amount", entities.ErrWrongPaymentAmount, codes.InvalidArgument},
+		{"unauthorized", entities.ErrUnauthorized, codes.Unauthenticated},
+		{"forbidden", entities.ErrForbidden, codes.PermissionDenied},
+		{"rate_limited", &endpoint.RateLimitError{RetryAfter: time.Second}, codes.ResourceExhausted},
+		{"database", entities.ErrDatabaseConnection, codes.Unavailable},
+		{"unknown", errors.New("unknown"), codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := codeFromError(tt.err); got != tt.want {
+				t.Errorf("codeFromError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newGRPCClient serves endpoints over in-memory connection and returns a client connected to them
+func newGRPCClient(t *testing.T, endpoints endpoint.Set) pb.WalletClient {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	pb.RegisterWalletServer(server, NewGRPCServer(endpoints))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Error while connecting to gRPC server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewWalletClient(conn)
+}
+
+func Test_grpcServer_MakePayment(t *testing.T) {
+	svc := service.NewWalletService(db.NewMemoryStorage())
+	client := newGRPCClient(t, endpoint.NewEndpointSet(svc, noop.NewTracerProvider().Tracer(""), endpoint.Limits{}))
+
+	for _, acc := range []*pb.Account{
+		{Id: "alice", Currency: "USD", Balance: "100"},
+		{Id: "bob", Currency: "USD", Balance: "0"},
+	} {
+		if _, err := client.CreateAccount(context.TODO(), &pb.CreateAccountRequest{Account: acc}); err != nil {
+			t.Fatalf("Error while creating account: %v", err)
+		}
+	}
+
+	id := uuid.New().String()
+	tests := []struct {
+		name         string
+		request      *pb.MakePaymentRequest
+		wantCode     codes.Code
+		wantReplayed bool
+	}{
+		{"payment", &pb.MakePaymentRequest{Id: id, Account: "alice", ToAccount: "bob", Amount: "30"}, codes.OK, false},
+		{"retry", &pb.MakePaymentRequest{Id: id, Account: "alice", ToAccount: "bob", Amount: "30"}, codes.OK, true},
+		{"same_id_other_amount", &pb.MakePaymentRequest{Id: id, Account: "alice", ToAccount: "bob", Amount: "20"}, codes.AlreadyExists, false},
+		{"insufficient_funds", &pb.MakePaymentRequest{Id: uuid.New().String(), Account: "alice", ToAccount: "bob", Amount: "100"}, codes.FailedPrecondition, false},
+		{"unknown_recipient", &pb.MakePaymentRequest{Id: uuid.New().String(), Account: "alice", ToAccount: "carol", Amount: "10"}, codes.NotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply, err := client.MakePayment(context.TODO(), tt.request)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("grpcServer.MakePayment() code = %v, want %v, error = %v", code, tt.wantCode, err)
+			}
+			if err != nil {
+				return
+			}
+			if reply.Replayed != tt.wantReplayed || reply.Payment.Id != id || reply.Payment.Amount != "30" {
+				t.Errorf("grpcServer.MakePayment() = %v, want replayed %v", reply, tt.wantReplayed)
+			}
+		})
+	}
+
+	acc, err := client.GetAccount(context.TODO(), &pb.GetAccountRequest{Id: "alice"})
+	if err != nil {
+		t.Fatalf("Error while getting account: %v", err)
+	}
+	if acc.Account.Balance != "70" {
+		t.Errorf("Expectation failed. Expected balance = 70, actual = %v", acc.Account.Balance)
+	}
+}
@@ -4,22 +4,54 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/google/uuid"
 	mux "github.com/gorilla/mux"
 	"github.com/shirolimit/wallet-service/pkg/endpoint"
 	"github.com/shirolimit/wallet-service/pkg/entities"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// idempotentReplayedHeader marks responses to repeated payment requests
+	idempotentReplayedHeader = "Idempotent-Replayed"
+
+	// nextCursorHeader carries the next page cursor of ID-only accounts list
+	nextCursorHeader = "X-Next-Cursor"
+
+	// retryAfterHeader tells throttled clients how many seconds to wait
+	retryAfterHeader = "Retry-After"
+
+	// defaultAccountsPageLimit is a page size of full accounts list if limit is not specified
+	defaultAccountsPageLimit = 50
 )
 
 // NewHTTPHandler creates new HTTP handler
 func NewHTTPHandler(endpoints endpoint.Set, options []httptransport.ServerOption) http.Handler {
+	// error encoder goes first, so that it can be overridden by specified options
+	options = append([]httptransport.ServerOption{httptransport.ServerErrorEncoder(encodeError)}, options...)
+
 	m := mux.NewRouter()
 	makeCreateAccountHandler(m, endpoints, options)
 	makeListAccountsHandler(m, endpoints, options)
 	makeGetAccountHandler(m, endpoints, options)
+	makeUpdateAccountHandler(m, endpoints, options)
 	makeGetPaymentsHandler(m, endpoints, options)
+	makeGetPaymentHandler(m, endpoints, options)
 	makeMakePaymentHandler(m, endpoints, options)
+	makeDepositHandler(m, endpoints, options)
+	makeWithdrawHandler(m, endpoints, options)
+	makeCreateHoldHandler(m, endpoints, options)
+	makeGetHoldHandler(m, endpoints, options)
+	makeCaptureHoldHandler(m, endpoints, options)
+	makeReleaseHoldHandler(m, endpoints, options)
+	makeRefundPaymentHandler(m, endpoints, options)
 	return m
 }
 
@@ -69,7 +101,42 @@ func makeListAccountsHandler(m *mux.Router, endpoints endpoint.Set, options []ht
 }
 
 func decodeListAccountsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
-	return endpoint.ListAccountsRequest{}, nil
+	values := r.URL.Query()
+	req := endpoint.ListAccountsRequest{
+		Query: entities.AccountsQuery{
+			Cursor:   values.Get("cursor"),
+			IDPrefix: values.Get("prefix"),
+			Currency: values.Get("currency"),
+			Sort:     entities.AccountsSort(values.Get("sort")),
+		},
+	}
+
+	switch values.Get("view") {
+	case "", "ids":
+	case "full":
+		// unlike ID-only view, full one is always paginated
+		req.Full = true
+		req.Query.Limit = defaultAccountsPageLimit
+	default:
+		return nil, entities.ErrInvalidAccountsQuery
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return nil, entities.ErrInvalidLimit
+		}
+		req.Query.Limit = n
+	}
+
+	var err error
+	if req.Query.MinBalance, err = decodeDecimalParam(values, "min_balance"); err != nil {
+		return nil, entities.ErrInvalidAccountsQuery
+	}
+	if req.Query.MaxBalance, err = decodeDecimalParam(values, "max_balance"); err != nil {
+		return nil, entities.ErrInvalidAccountsQuery
+	}
+	return req, nil
 }
 
 func encodeListAccountsResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
@@ -83,8 +150,17 @@ func encodeListAccountsResponse(ctx context.Context, w http.ResponseWriter, resp
 		return nil
 	}
 
+	if resp.Full {
+		w.WriteHeader(http.StatusOK)
+		return json.NewEncoder(w).Encode(resp.Page)
+	}
+
+	// ID-only view keeps plain array response, so the cursor goes to header
+	if resp.Page.NextCursor != "" {
+		w.Header().Set(nextCursorHeader, resp.Page.NextCursor)
+	}
 	w.WriteHeader(http.StatusOK)
-	return json.NewEncoder(w).Encode(resp.Accounts)
+	return json.NewEncoder(w).Encode(resp.IDs())
 }
 
 // makeGetAccountHandler creates HTTP handler for GetAccount endpoint
@@ -122,6 +198,47 @@ func encodeGetAccountResponse(ctx context.Context, w http.ResponseWriter, respon
 	return json.NewEncoder(w).Encode(resp.Account)
 }
 
+// makeUpdateAccountHandler creates HTTP handler for UpdateAccount endpoint
+func makeUpdateAccountHandler(m *mux.Router, endpoints endpoint.Set, options []httptransport.ServerOption) {
+	m.Methods("PATCH").Path("/accounts/{id}").Handler(
+		httptransport.NewServer(
+			endpoints.UpdateAccountEndpoint,
+			decodeUpdateAccountRequest,
+			encodeUpdateAccountResponse,
+			options...,
+		),
+	)
+}
+
+func decodeUpdateAccountRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.UpdateAccountRequest{
+		ID: entities.AccountID(mux.Vars(r)["id"]),
+	}
+	err := json.NewDecoder(r.Body).Decode(&req.Update)
+	if err == entities.ErrInvalidAccountStatus {
+		return req, err
+	}
+	if err != nil {
+		return req, errors.New("Bad request")
+	}
+	return req, nil
+}
+
+func encodeUpdateAccountResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, ok := response.(endpoint.UpdateAccountResponse)
+	if !ok || resp.Failed() != nil {
+		err := resp.Failed()
+		w.WriteHeader(statusCodeFromError(err))
+		writeError(ctx, w, err)
+		return nil
+	}
+
+	w.WriteHeader(http.StatusOK)
+	return json.NewEncoder(w).Encode(resp.Account)
+}
+
 // makeGetPaymentsHandler creates HTTP handler for GetPayments endpoint
 func makeGetPaymentsHandler(m *mux.Router, endpoints endpoint.Set, options []httptransport.ServerOption) {
 	m.Methods("GET").Path("/accounts/{id}/payments").Handler(
@@ -139,9 +256,84 @@ func decodeGetPaymentsRequest(ctx context.Context, r *http.Request) (interface{}
 	req := endpoint.GetPaymentsRequest{
 		AccountID: entities.AccountID(vars["id"]),
 	}
+
+	query, err := decodePaymentsQuery(r.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+	req.Query = query
 	return req, nil
 }
 
+// decodePaymentsQuery parses pagination and filter parameters of GetPayments request
+func decodePaymentsQuery(values url.Values) (entities.PaymentsQuery, error) {
+	query := entities.PaymentsQuery{
+		Cursor: values.Get("cursor"),
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return query, entities.ErrInvalidLimit
+		}
+		query.Limit = n
+	}
+
+	if direction := values.Get("direction"); direction != "" {
+		pd, err := entities.ParsePaymentDirection(direction)
+		if err != nil {
+			return query, entities.ErrInvalidPaymentsQuery
+		}
+		query.Direction = &pd
+	}
+
+	if counterparty := values.Get("counterparty"); counterparty != "" {
+		id := entities.AccountID(counterparty)
+		query.Counterparty = &id
+	}
+
+	var err error
+	if query.MinAmount, err = decodeDecimalParam(values, "min_amount"); err != nil {
+		return query, err
+	}
+	if query.MaxAmount, err = decodeDecimalParam(values, "max_amount"); err != nil {
+		return query, err
+	}
+	if query.From, err = decodeTimeParam(values, "from"); err != nil {
+		return query, err
+	}
+	if query.To, err = decodeTimeParam(values, "to"); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+func decodeDecimalParam(values url.Values, name string) (*decimal.Decimal, error) {
+	value := values.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return nil, entities.ErrInvalidPaymentsQuery
+	}
+	return &d, nil
+}
+
+func decodeTimeParam(values url.Values, name string) (*time.Time, error) {
+	value := values.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, entities.ErrInvalidPaymentsQuery
+	}
+	return &t, nil
+}
+
 func encodeGetPaymentsResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
@@ -154,7 +346,48 @@ func encodeGetPaymentsResponse(ctx context.Context, w http.ResponseWriter, respo
 	}
 
 	w.WriteHeader(http.StatusOK)
-	return json.NewEncoder(w).Encode(resp.Payments)
+	return json.NewEncoder(w).Encode(resp.Page)
+}
+
+// makeGetPaymentHandler creates HTTP handler for GetPayment endpoint
+func makeGetPaymentHandler(m *mux.Router, endpoints endpoint.Set, options []httptransport.ServerOption) {
+	m.Methods("GET").Path("/payments/{paymentId}").Handler(
+		httptransport.NewServer(
+			endpoints.GetPaymentEndpoint,
+			decodeGetPaymentRequest,
+			encodeGetPaymentResponse,
+			options...,
+		),
+	)
+}
+
+func decodeGetPaymentRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := paymentIDFromPath(r)
+	return endpoint.GetPaymentRequest{ID: id}, err
+}
+
+func encodeGetPaymentResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, ok := response.(endpoint.GetPaymentResponse)
+	if !ok || resp.Failed() != nil {
+		err := resp.Failed()
+		w.WriteHeader(statusCodeFromError(err))
+		writeError(ctx, w, err)
+		return nil
+	}
+
+	w.WriteHeader(http.StatusOK)
+	return json.NewEncoder(w).Encode(resp.Payment)
+}
+
+// paymentIDFromPath parses payment ID of request path, malformed IDs can't belong to any payment
+func paymentIDFromPath(r *http.Request) (uuid.UUID, error) {
+	id, err := uuid.Parse(mux.Vars(r)["paymentId"])
+	if err != nil {
+		return uuid.UUID{}, entities.ErrPaymentNotFound
+	}
+	return id, nil
 }
 
 // makeMakePaymentHandler creates HTTP handler for MakePayment endpoint
@@ -170,15 +403,24 @@ func makeMakePaymentHandler(m *mux.Router, endpoints endpoint.Set, options []htt
 }
 
 func decodeMakePaymentRequest(ctx context.Context, r *http.Request) (interface{}, error) {
-	req := endpoint.MakePaymentRequest{}
-	err := json.NewDecoder(r.Body).Decode(&req.Payment)
-	if err != nil {
-		return req, errors.New("Bad request")
+	var body struct {
+		ID        uuid.UUID          `json:"id"`
+		ToAccount entities.AccountID `json:"to_account"`
+		Amount    decimal.Decimal    `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return endpoint.MakePaymentRequest{}, errors.New("Bad request")
 	}
 
-	req.Payment.Direction = entities.Outgoing
-	req.Payment.Account = entities.AccountID(mux.Vars(r)["id"])
-	return req, nil
+	return endpoint.MakePaymentRequest{
+		Payment: entities.Payment{
+			ID:        body.ID,
+			Account:   entities.AccountID(mux.Vars(r)["id"]),
+			ToAccount: &body.ToAccount,
+			Amount:    body.Amount,
+			Direction: entities.Outgoing,
+		},
+	}, nil
 }
 
 func encodeMakePaymentResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
@@ -192,12 +434,243 @@ func encodeMakePaymentResponse(ctx context.Context, w http.ResponseWriter, respo
 		return nil
 	}
 
+	if resp.Replayed {
+		w.Header().Set(idempotentReplayedHeader, "true")
+	}
 	w.WriteHeader(http.StatusOK)
 	return json.NewEncoder(w).Encode(resp.Payment)
 }
 
+// makeDepositHandler creates HTTP handler for Deposit endpoint
+func makeDepositHandler(m *mux.Router, endpoints endpoint.Set, options []httptransport.ServerOption) {
+	m.Methods("POST").Path("/accounts/{id}/deposits").Handler(
+		httptransport.NewServer(
+			endpoints.DepositEndpoint,
+			decodeDepositRequest,
+			encodeMakePaymentResponse,
+			options...,
+		),
+	)
+}
+
+func decodeDepositRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	payment, err := decodeExternalPayment(r)
+	return endpoint.DepositRequest{Payment: payment}, err
+}
+
+// makeWithdrawHandler creates HTTP handler for Withdraw endpoint
+func makeWithdrawHandler(m *mux.Router, endpoints endpoint.Set, options []httptransport.ServerOption) {
+	m.Methods("POST").Path("/accounts/{id}/withdrawals").Handler(
+		httptransport.NewServer(
+			endpoints.WithdrawEndpoint,
+			decodeWithdrawRequest,
+			encodeMakePaymentResponse,
+			options...,
+		),
+	)
+}
+
+func decodeWithdrawRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	payment, err := decodeExternalPayment(r)
+	return endpoint.WithdrawRequest{Payment: payment}, err
+}
+
+// decodeExternalPayment decodes ID and amount of deposit or withdrawal,
+// the counterparty is external account chosen by service
+func decodeExternalPayment(r *http.Request) (entities.Payment, error) {
+	var body struct {
+		ID     uuid.UUID       `json:"id"`
+		Amount decimal.Decimal `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return entities.Payment{}, errors.New("Bad request")
+	}
+
+	return entities.Payment{
+		ID:      body.ID,
+		Account: entities.AccountID(mux.Vars(r)["id"]),
+		Amount:  body.Amount,
+	}, nil
+}
+
+// makeCreateHoldHandler creates HTTP handler for CreateHold endpoint
+func makeCreateHoldHandler(m *mux.Router, endpoints endpoint.Set, options []httptransport.ServerOption) {
+	m.Methods("POST").Path("/accounts/{id}/holds").Handler(
+		httptransport.NewServer(
+			endpoints.CreateHoldEndpoint,
+			decodeCreateHoldRequest,
+			encodeCreateHoldResponse,
+			options...,
+		),
+	)
+}
+
+func decodeCreateHoldRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var body struct {
+		ID        uuid.UUID          `json:"id"`
+		ToAccount entities.AccountID `json:"to_account"`
+		Amount    decimal.Decimal    `json:"amount"`
+		ExpiresAt time.Time          `json:"expires_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errors.New("Bad request")
+	}
+
+	return endpoint.CreateHoldRequest{
+		Hold: entities.Hold{
+			ID:        body.ID,
+			Account:   entities.AccountID(mux.Vars(r)["id"]),
+			ToAccount: body.ToAccount,
+			Amount:    body.Amount,
+			ExpiresAt: body.ExpiresAt,
+		},
+	}, nil
+}
+
+func encodeCreateHoldResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return encodeHoldResponse(ctx, w, response, http.StatusCreated)
+}
+
+// makeGetHoldHandler creates HTTP handler for GetHold endpoint
+func makeGetHoldHandler(m *mux.Router, endpoints endpoint.Set, options []httptransport.ServerOption) {
+	m.Methods("GET").Path("/holds/{id}").Handler(
+		httptransport.NewServer(
+			endpoints.GetHoldEndpoint,
+			decodeGetHoldRequest,
+			encodeGetHoldResponse,
+			options...,
+		),
+	)
+}
+
+func decodeGetHoldRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := holdIDFromPath(r)
+	return endpoint.GetHoldRequest{ID: id}, err
+}
+
+func encodeGetHoldResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return encodeHoldResponse(ctx, w, response, http.StatusOK)
+}
+
+// makeCaptureHoldHandler creates HTTP handler for CaptureHold endpoint
+func makeCaptureHoldHandler(m *mux.Router, endpoints endpoint.Set, options []httptransport.ServerOption) {
+	m.Methods("POST").Path("/holds/{id}/capture").Handler(
+		httptransport.NewServer(
+			endpoints.CaptureHoldEndpoint,
+			decodeCaptureHoldRequest,
+			encodeMakePaymentResponse,
+			options...,
+		),
+	)
+}
+
+// decodeCaptureHoldRequest decodes optional capture amount, empty body captures the whole hold
+func decodeCaptureHoldRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := holdIDFromPath(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Amount *decimal.Decimal `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		return nil, errors.New("Bad request")
+	}
+	return endpoint.CaptureHoldRequest{ID: id, Amount: body.Amount}, nil
+}
+
+// makeReleaseHoldHandler creates HTTP handler for ReleaseHold endpoint
+func makeReleaseHoldHandler(m *mux.Router, endpoints endpoint.Set, options []httptransport.ServerOption) {
+	m.Methods("POST").Path("/holds/{id}/release").Handler(
+		httptransport.NewServer(
+			endpoints.ReleaseHoldEndpoint,
+			decodeReleaseHoldRequest,
+			encodeReleaseHoldResponse,
+			options...,
+		),
+	)
+}
+
+func decodeReleaseHoldRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := holdIDFromPath(r)
+	return endpoint.ReleaseHoldRequest{ID: id}, err
+}
+
+func encodeReleaseHoldResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return encodeHoldResponse(ctx, w, response, http.StatusOK)
+}
+
+// holdIDFromPath parses hold ID of request path, malformed IDs can't belong to any hold
+func holdIDFromPath(r *http.Request) (uuid.UUID, error) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		return uuid.UUID{}, entities.ErrHoldNotFound
+	}
+	return id, nil
+}
+
+// encodeHoldResponse writes hold of successful response with specified status code
+func encodeHoldResponse(ctx context.Context, w http.ResponseWriter, response interface{}, code int) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, ok := response.(endpoint.HoldResponse)
+	if !ok || resp.Failed() != nil {
+		err := resp.Failed()
+		w.WriteHeader(statusCodeFromError(err))
+		writeError(ctx, w, err)
+		return nil
+	}
+
+	w.WriteHeader(code)
+	return json.NewEncoder(w).Encode(resp.Hold)
+}
+
+// makeRefundPaymentHandler creates HTTP handler for RefundPayment endpoint
+func makeRefundPaymentHandler(m *mux.Router, endpoints endpoint.Set, options []httptransport.ServerOption) {
+	m.Methods("POST").Path("/payments/{paymentId}/refunds").Handler(
+		httptransport.NewServer(
+			endpoints.RefundPaymentEndpoint,
+			decodeRefundPaymentRequest,
+			encodeMakePaymentResponse,
+			options...,
+		),
+	)
+}
+
+// decodeRefundPaymentRequest decodes refund of payment, omitted amount refunds the rest of payment
+func decodeRefundPaymentRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	refundOf, err := paymentIDFromPath(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		ID      uuid.UUID          `json:"id"`
+		Account entities.AccountID `json:"account"`
+		Amount  decimal.Decimal    `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errors.New("Bad request")
+	}
+
+	return endpoint.RefundPaymentRequest{
+		Refund: entities.Payment{
+			ID:        body.ID,
+			Account:   body.Account,
+			Amount:    body.Amount,
+			Direction: entities.Outgoing,
+			RefundOf:  &refundOf,
+		},
+	}, nil
+}
+
 // statusCodeFromError translates error into HTTP status code
 func statusCodeFromError(err error) int {
+	if _, ok := err.(*endpoint.RateLimitError); ok {
+		return http.StatusTooManyRequests
+	}
+
 	switch err {
 	case entities.ErrAccountAlreadyExists:
 		return http.StatusConflict
@@ -206,7 +679,10 @@ func statusCodeFromError(err error) int {
 		return http.StatusNotFound
 
 	case entities.ErrDifferentCurrencies:
-		return http.StatusForbidden
+		return http.StatusUnprocessableEntity
+
+	case entities.ErrExchangeRateNotFound:
+		return http.StatusUnprocessableEntity
 
 	case entities.ErrInsufficientFunds:
 		return http.StatusPaymentRequired
@@ -218,7 +694,7 @@ func statusCodeFromError(err error) int {
 		return http.StatusNotFound
 
 	case entities.ErrDatabaseConnection:
-		return http.StatusInternalServerError
+		return http.StatusServiceUnavailable
 
 	case entities.ErrIncomingPaymentsNotAllowed:
 		return http.StatusBadRequest
@@ -235,6 +711,15 @@ func statusCodeFromError(err error) int {
 	case entities.ErrNegativeBalance:
 		return http.StatusBadRequest
 
+	case entities.ErrUnknownCurrency:
+		return http.StatusBadRequest
+
+	case entities.ErrCurrencyDisabled:
+		return http.StatusBadRequest
+
+	case entities.ErrAmountPrecision:
+		return http.StatusBadRequest
+
 	case entities.ErrEmptyPaymentSource:
 		return http.StatusBadRequest
 
@@ -253,6 +738,72 @@ func statusCodeFromError(err error) int {
 	case entities.ErrPaymentSameAccount:
 		return http.StatusBadRequest
 
+	case entities.ErrInvalidCursor:
+		return http.StatusBadRequest
+
+	case entities.ErrInvalidLimit:
+		return http.StatusBadRequest
+
+	case entities.ErrInvalidPaymentsQuery:
+		return http.StatusBadRequest
+
+	case entities.ErrInvalidAccountsQuery:
+		return http.StatusBadRequest
+
+	case entities.ErrTooManyRequests:
+		return http.StatusTooManyRequests
+
+	case entities.ErrUnauthorized:
+		return http.StatusUnauthorized
+
+	case entities.ErrForbidden:
+		return http.StatusForbidden
+
+	case entities.ErrInvalidAccountStatus:
+		return http.StatusBadRequest
+
+	case entities.ErrAccountFrozen:
+		return http.StatusConflict
+
+	case entities.ErrAccountClosed:
+		return http.StatusConflict
+
+	case entities.ErrAccountNotEmpty:
+		return http.StatusConflict
+
+	case entities.ErrReservedAccountID:
+		return http.StatusBadRequest
+
+	case entities.ErrInitialBalanceNotAllowed:
+		return http.StatusBadRequest
+
+	case entities.ErrEmptyHoldID:
+		return http.StatusBadRequest
+
+	case entities.ErrHoldAlreadyExists:
+		return http.StatusConflict
+
+	case entities.ErrHoldNotFound:
+		return http.StatusNotFound
+
+	case entities.ErrHoldNotActive:
+		return http.StatusConflict
+
+	case entities.ErrInvalidHoldExpiry:
+		return http.StatusBadRequest
+
+	case entities.ErrWrongCaptureAmount:
+		return http.StatusBadRequest
+
+	case entities.ErrPaymentNotFound:
+		return http.StatusNotFound
+
+	case entities.ErrPaymentNotRefundable:
+		return http.StatusBadRequest
+
+	case entities.ErrRefundExceedsPayment:
+		return http.StatusConflict
+
 	default:
 		return http.StatusInternalServerError
 	}
@@ -265,3 +816,16 @@ type errorWrapper struct {
 func writeError(ctx context.Context, w http.ResponseWriter, err error) {
 	json.NewEncoder(w).Encode(errorWrapper{Error: err.Error()})
 }
+
+// encodeError is used for errors returned by request decoders and endpoint middlewares
+func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
+	if limitErr, ok := err.(*endpoint.RateLimitError); ok {
+		w.Header().Set(retryAfterHeader, strconv.Itoa(limitErr.RetryAfterSeconds()))
+	}
+	if err == entities.ErrUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCodeFromError(err))
+	writeError(ctx, w, err)
+}
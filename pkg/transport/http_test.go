@@ -0,0 +1,100 @@
+package transport_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/shirolimit/wallet-service/pkg/db"
+	"github.com/shirolimit/wallet-service/pkg/endpoint"
+	"github.com/shirolimit/wallet-service/pkg/entities"
+	"github.com/shirolimit/wallet-service/pkg/service"
+	"github.com/shirolimit/wallet-service/pkg/transport"
+)
+
+// newHTTPHandler creates HTTP handler of wallet service backed by memory storage
+func newHTTPHandler() http.Handler {
+	svc := service.NewWalletService(db.NewMemoryStorage())
+	endpoints := endpoint.NewEndpointSet(svc, noop.NewTracerProvider().Tracer(""), endpoint.Limits{})
+	return transport.NewHTTPHandler(endpoints, nil)
+}
+
+// serveJSON sends request with JSON body to handler and returns recorded response
+func serveJSON(handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func Test_HTTPHandler_CreateAccount(t *testing.T) {
+	handler := newHTTPHandler()
+
+	// server assigned fields of request body are ignored
+	w := serveJSON(handler, "POST", "/accounts",
+		`{"id": "alice", "currency": "USD", "balance": "100", "available_balance": "5", "status": "frozen"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("CreateAccount status = %v, want %v, body = %s", w.Code, http.StatusCreated, w.Body)
+	}
+
+	var acc entities.Account
+	if err := json.NewDecoder(w.Body).Decode(&acc); err != nil {
+		t.Fatalf("Error while decoding account: %v", err)
+	}
+	hundred := decimal.New(100, 0)
+	if acc.ID != "alice" || acc.Status != entities.Active ||
+		!acc.Balance.Equal(hundred) || !acc.AvailableBalance.Equal(hundred) {
+		t.Errorf("Expectation failed. Actual account = %v", acc)
+	}
+}
+
+func Test_HTTPHandler_MakePayment(t *testing.T) {
+	handler := newHTTPHandler()
+	for _, body := range []string{
+		`{"id": "alice", "currency": "USD", "balance": "100"}`,
+		`{"id": "bob", "currency": "USD", "balance": "0"}`,
+	} {
+		if w := serveJSON(handler, "POST", "/accounts", body); w.Code != http.StatusCreated {
+			t.Fatalf("Error while creating account: status = %v, body = %s", w.Code, w.Body)
+		}
+	}
+
+	id := uuid.New().String()
+	tests := []struct {
+		name         string
+		body         string
+		wantCode     int
+		wantReplayed string
+	}{
+		{"payment", `{"id": "` + id + `", "to_account": "bob", "amount": "30"}`, http.StatusOK, ""},
+		{"retry", `{"id": "` + id + `", "to_account": "bob", "amount": "30"}`, http.StatusOK, "true"},
+		{"same_id_other_amount", `{"id": "` + id + `", "to_account": "bob", "amount": "20"}`, http.StatusConflict, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveJSON(handler, "POST", "/accounts/alice/payments", tt.body)
+			if w.Code != tt.wantCode {
+				t.Fatalf("MakePayment status = %v, want %v, body = %s", w.Code, tt.wantCode, w.Body)
+			}
+			if got := w.Header().Get("Idempotent-Replayed"); got != tt.wantReplayed {
+				t.Errorf("MakePayment Idempotent-Replayed = %q, want %q", got, tt.wantReplayed)
+			}
+		})
+	}
+
+	w := serveJSON(handler, "GET", "/accounts/alice", "")
+	var acc entities.Account
+	if err := json.NewDecoder(w.Body).Decode(&acc); err != nil {
+		t.Fatalf("Error while decoding account: %v", err)
+	}
+	if !acc.Balance.Equal(decimal.New(70, 0)) {
+		t.Errorf("Expectation failed. Expected balance = 70, actual = %v", acc.Balance)
+	}
+}
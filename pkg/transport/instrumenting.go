@@ -0,0 +1,43 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	httptransport "github.com/go-kit/kit/transport/http"
+	mux "github.com/gorilla/mux"
+)
+
+// requestStartKey is a context key of HTTP request start time
+type requestStartKey struct{}
+
+// HTTPMetricsOptions returns server options recording HTTP request count and latency in seconds.
+// Both metrics are labeled by "method", "route" and "code"
+func HTTPMetricsOptions(requests metrics.Counter, latency metrics.Histogram) []httptransport.ServerOption {
+	return []httptransport.ServerOption{
+		httptransport.ServerBefore(func(ctx context.Context, r *http.Request) context.Context {
+			return context.WithValue(ctx, requestStartKey{}, time.Now())
+		}),
+		httptransport.ServerFinalizer(func(ctx context.Context, code int, r *http.Request) {
+			labels := []string{"method", r.Method, "route", routeTemplate(r), "code", strconv.Itoa(code)}
+			requests.With(labels...).Add(1)
+			if start, ok := ctx.Value(requestStartKey{}).(time.Time); ok {
+				latency.With(labels...).Observe(time.Since(start).Seconds())
+			}
+		}),
+	}
+}
+
+// routeTemplate returns path template of matched route, so that
+// account IDs don't get into metric labels
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,39 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HTTPTracingOptions returns server options which start a span for each HTTP request.
+// Parent span context is extracted from request headers, i.e. W3C traceparent, with specified propagator
+func HTTPTracingOptions(tracer trace.Tracer, propagator propagation.TextMapPropagator) []httptransport.ServerOption {
+	return []httptransport.ServerOption{
+		httptransport.ServerBefore(func(ctx context.Context, r *http.Request) context.Context {
+			ctx = propagator.Extract(ctx, propagation.HeaderCarrier(r.Header))
+			route := routeTemplate(r)
+			ctx, _ = tracer.Start(ctx, r.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.method", r.Method),
+					attribute.String("http.route", route),
+				),
+			)
+			return ctx
+		}),
+		httptransport.ServerFinalizer(func(ctx context.Context, code int, r *http.Request) {
+			span := trace.SpanFromContext(ctx)
+			span.SetAttributes(attribute.Int("http.status_code", code))
+			if code >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(code))
+			}
+			span.End()
+		}),
+	}
+}